@@ -0,0 +1,52 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/x/term"
+
+	"github.com/AerNos/firefrp-client/internal/api"
+	"github.com/AerNos/firefrp-client/internal/config"
+	"github.com/AerNos/firefrp-client/internal/tunnel"
+)
+
+// auditWait is how long --audit pauses before connecting when stdin is not
+// a terminal to confirm on, leaving time to abort with Ctrl+C.
+const auditWait = 10 * time.Second
+
+// errAuditRejected is returned when the user declines to connect after
+// reviewing the audited config.
+var errAuditRejected = errors.New("connection aborted after --audit review")
+
+// printAudit prints what the server returned and the frpc config derived
+// from it (--audit). Secrets are masked unless --show-token is given.
+func printAudit(cfg *config.Config, prefix string, data *api.ValidateData, tunnelCfg tunnel.TunnelConfig) {
+	fmt.Printf("%sServer response (--audit):\n%s\n", prefix, data.AuditJSON(cfg.ShowToken))
+	fmt.Printf("%sfrpc config:\n%s\n\n", prefix, tunnelCfg.FrpConfigJSON(cfg.ShowToken))
+}
+
+// confirmAudit asks the user to approve the printed config before
+// connecting. Without a terminal on stdin it waits auditWait instead.
+func confirmAudit() error {
+	if !term.IsTerminal(os.Stdin.Fd()) {
+		fmt.Printf("Connecting in %s (press Ctrl+C to abort)...\n", auditWait)
+		time.Sleep(auditWait)
+		return nil
+	}
+	fmt.Printf("Press Enter to connect, or type n to abort: ")
+	line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil {
+		return errAuditRejected
+	}
+	switch strings.ToLower(strings.TrimSpace(line)) {
+	case "", "y", "yes":
+		fmt.Println()
+		return nil
+	}
+	return errAuditRejected
+}
@@ -0,0 +1,215 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"net"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/AerNos/firefrp-client/internal/api"
+	"github.com/AerNos/firefrp-client/internal/config"
+	"github.com/AerNos/firefrp-client/internal/tunnel"
+	"github.com/AerNos/firefrp-client/internal/updater"
+)
+
+// localDialTimeout bounds the check that something listens on the local port.
+const localDialTimeout = 2 * time.Second
+
+// doctorReport collects check results and prints them as they come in.
+type doctorReport struct {
+	failed int
+}
+
+func (r *doctorReport) pass(name, detail string) {
+	fmt.Printf("  [PASS] %s: %s\n", name, detail)
+}
+
+func (r *doctorReport) fail(name string, err error) {
+	r.failed++
+	fmt.Printf("  [FAIL] %s: %v\n", name, err)
+}
+
+func (r *doctorReport) warn(name, detail string) {
+	fmt.Printf("  [WARN] %s: %s\n", name, detail)
+}
+
+func (r *doctorReport) skip(name, why string) {
+	fmt.Printf("  [SKIP] %s: %s\n", name, why)
+}
+
+// runDoctor handles `firefrp doctor`: it prints the effective config with
+// secrets redacted, then checks the server list, each server's server-info,
+// GitHub (for updates) and the local port, and fails if any check failed.
+func runDoctor(cfg *config.Config) error {
+	fmt.Printf("FireFrp Client - Doctor\n\n")
+	printEffectiveConfig(cfg)
+
+	var r doctorReport
+	fmt.Printf("\nChecks:\n")
+
+	if err := cfg.Validate(); err != nil {
+		r.fail("config", err)
+	} else {
+		r.pass("config", "flags are valid")
+	}
+
+	// Servers to probe: those named explicitly, else the server list's.
+	var servers []string
+	switch {
+	case cfg.MirrorMode():
+		servers = cfg.MirrorServers
+	case len(cfg.Servers) > 0:
+		servers = cfg.Servers
+	case serverFlagSet():
+		servers = []string{cfg.ServerURL}
+	}
+
+	if cfg.ServerListURL == "" || cfg.MirrorMode() {
+		r.skip("server list", "not used")
+	} else {
+		entries, err := api.FetchServerList(cfg.ServerListURL, cfg.ServerListAuth, cfg.ProbeTimeout)
+		switch {
+		case err != nil:
+			r.fail("server list", err)
+		case len(entries) == 0:
+			r.fail("server list", fmt.Errorf("list is empty"))
+		default:
+			r.pass("server list", fmt.Sprintf("%d server(s)", len(entries)))
+			if len(servers) == 0 {
+				for _, e := range entries {
+					servers = append(servers, e.APIUrl)
+				}
+			}
+		}
+	}
+	if len(servers) == 0 && cfg.ServerListURL == "" {
+		servers = []string{cfg.ServerURL}
+	}
+
+	if len(servers) == 0 {
+		r.skip("server-info", "no servers to check")
+	}
+	for _, server := range servers {
+		name := "server-info " + api.RedactURL(server)
+		client := api.NewAPIClient(server, api.WithTimeout(cfg.APITimeout), api.WithInsecureSkipVerify(cfg.InsecureSkipVerify))
+		info, err := client.FetchServerInfo()
+		if err != nil {
+			r.fail(name, err)
+			continue
+		}
+		detail := fmt.Sprintf("%s (%s), client version %s", info.Name, info.PublicAddr, info.ClientVersion)
+		if info.ClientVersion != "" && info.ClientVersion != "unknown" && info.ClientVersion != version {
+			detail += fmt.Sprintf(" (this client: %s)", version)
+		}
+		if warning := tunnel.CheckFrpVersion(info.FrpVersion); warning != "" {
+			r.warn(name, detail+"; "+warning)
+			continue
+		}
+		r.pass(name, detail)
+	}
+
+	if err := updater.CheckReachable(cfg.ProbeTimeout); errors.Is(err, updater.ErrRateLimited) {
+		r.warn("GitHub (updates)", err.Error())
+	} else if err != nil {
+		r.fail("GitHub (updates)", err)
+	} else {
+		r.pass("GitHub (updates)", "reachable")
+	}
+
+	if cfg.LocalPort <= 0 {
+		r.skip("local port", "no --port given")
+	} else {
+		addr := net.JoinHostPort(cfg.LocalIP, strconv.Itoa(cfg.LocalPort))
+		conn, err := net.DialTimeout("tcp", addr, localDialTimeout)
+		if err != nil {
+			r.fail("local port", fmt.Errorf("nothing is listening on %s: %w", addr, err))
+		} else {
+			conn.Close()
+			r.pass("local port", addr+" is listening")
+		}
+	}
+
+	fmt.Println()
+	if r.failed > 0 {
+		return fmt.Errorf("%d check(s) failed", r.failed)
+	}
+	fmt.Printf("All checks passed.\n")
+	return nil
+}
+
+// printEffectiveConfig prints the settings that affect connectivity.
+// Credentials are never printed: only whether they are set.
+func printEffectiveConfig(cfg *config.Config) {
+	set := func(v string) string {
+		if v == "" {
+			return "(not set)"
+		}
+		return "(set)"
+	}
+	port := "(not set)"
+	if cfg.LocalPort > 0 {
+		port = strconv.Itoa(cfg.LocalPort)
+	}
+	mirrors := make([]string, len(cfg.MirrorServers))
+	for i, s := range cfg.MirrorServers {
+		mirrors[i] = api.RedactURL(s)
+	}
+
+	fmt.Printf("Config:\n")
+	fmt.Printf("  version:          %s (%s/%s)\n", version, runtime.GOOS, runtime.GOARCH)
+	fmt.Printf("  server-list:      %s\n", api.RedactURL(cfg.ServerListURL))
+	fmt.Printf("  server-list-auth: %s\n", set(cfg.ServerListAuth))
+	fmt.Printf("  server:           %s\n", api.RedactURL(cfg.ServerURL))
+	if len(cfg.Servers) > 0 {
+		list := make([]string, len(cfg.Servers))
+		for i, s := range cfg.Servers {
+			list[i] = api.RedactURL(s)
+		}
+		fmt.Printf("  servers:          %s\n", strings.Join(list, ", "))
+	}
+	if len(mirrors) > 0 {
+		fmt.Printf("  mirror-servers:   %s\n", strings.Join(mirrors, ", "))
+	}
+	fmt.Printf("  key:              %s\n", set(cfg.AccessKey))
+	if cfg.Token != "" {
+		fmt.Printf("  token:            %s\n", set(cfg.Token))
+	}
+	if len(cfg.Keys) > 1 {
+		fmt.Printf("  keys:             %d (rotated as each expires)\n", len(cfg.Keys))
+	}
+	fmt.Printf("  local:            %s\n", net.JoinHostPort(cfg.LocalIP, port))
+	fmt.Printf("  api-timeout:      %s\n", cfg.APITimeout)
+	fmt.Printf("  probe-timeout:    %s\n", cfg.ProbeTimeout)
+	fmt.Printf("  probe-concurrency: %d\n", cfg.ProbeConcurrency)
+	if cfg.TunnelProxy != "" {
+		fmt.Printf("  tunnel-proxy:     %s\n", api.RedactURL(cfg.TunnelProxy))
+	}
+	if cfg.InsecureSkipVerify {
+		fmt.Printf("  insecure-skip-verify: true (TLS certificates are NOT verified)\n")
+	}
+	fmt.Printf("  no-update:        %t\n", cfg.NoUpdate)
+	if cfg.UpdateAsset != "" {
+		fmt.Printf("  update-asset:     %s\n", cfg.UpdateAsset)
+	}
+	if dir, err := config.Dir(); err == nil {
+		fmt.Printf("  config dir:       %s\n", dir)
+	} else {
+		fmt.Printf("  config dir:       %v\n", err)
+	}
+}
+
+// serverFlagSet reports whether --server was given explicitly (its default
+// points at localhost and is only meaningful without a server list).
+func serverFlagSet() bool {
+	set := false
+	flag.Visit(func(f *flag.Flag) {
+		if f.Name == "server" {
+			set = true
+		}
+	})
+	return set
+}
@@ -7,14 +7,21 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os"
 	"os/signal"
+	"strings"
+	"sync"
 	"syscall"
+	"time"
 
 	"github.com/AerNos/firefrp-client/internal/api"
 	"github.com/AerNos/firefrp-client/internal/config"
+	"github.com/AerNos/firefrp-client/internal/history"
+	"github.com/AerNos/firefrp-client/internal/keylock"
 	"github.com/AerNos/firefrp-client/internal/tui"
+	"github.com/AerNos/firefrp-client/internal/tui/theme"
 	"github.com/AerNos/firefrp-client/internal/tunnel"
 	"github.com/AerNos/firefrp-client/internal/updater"
 )
@@ -26,7 +33,45 @@ func main() {
 	cfg := config.ParseFlags()
 
 	if cfg.ShowVersion {
-		fmt.Printf("firefrp version %s\n", version)
+		if err := printVersion(cfg.VersionJSON); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if cfg.ClearHistory {
+		if err := history.Clear(); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Println("Session history cleared.")
+		return
+	}
+
+	if cfg.Command == "profiles" {
+		if err := runProfiles(); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if err := applyProfile(cfg); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	if err := cfg.LoadKeyFile(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	if cfg.Command == "doctor" {
+		if err := runDoctor(cfg); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
 		return
 	}
 
@@ -37,12 +82,35 @@ func main() {
 
 	if cfg.DirectMode() {
 		// Direct connect mode: skip TUI, validate key and start tunnel.
-		if err := runDirect(cfg); err != nil {
+		if cfg.AutoLocalIP {
+			if ip, changed := tunnel.ResolveLocalIP(cfg.LocalIP, cfg.LocalPort); changed {
+				fmt.Fprintf(os.Stderr, "Note: nothing answers on %s, using LAN address %s instead (--auto-local-ip)\n", tunnel.JoinHostPort(cfg.LocalIP, cfg.LocalPort), ip)
+				cfg.LocalIP = ip
+			}
+		}
+		if cfg.AutoServer && cfg.NeedsServerSelect() {
+			if err := pickFastestServer(cfg); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+		}
+		run := runDirect
+		switch {
+		case cfg.MirrorMode():
+			run = runMirrored
+		case len(cfg.Keys) > 1:
+			run = runKeyRotation
+		}
+		if err := run(cfg); err != nil {
 			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 			os.Exit(1)
 		}
 	} else {
 		// TUI mode: launch interactive terminal UI.
+		if !cfg.Interactive() {
+			fmt.Fprintf(os.Stderr, "非交互式终端，请使用 --key 和 --port 进行直连\n")
+			os.Exit(1)
+		}
 		if err := tui.Run(cfg, version); err != nil {
 			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 			os.Exit(1)
@@ -52,21 +120,57 @@ func main() {
 
 // checkDirectModeUpdate checks for updates when running in direct mode.
 // For release version mismatch it forces the update; for dev it prints a hint.
-func checkDirectModeUpdate(serverURL string) {
-	client := api.NewAPIClient(serverURL)
+// It also warns about (or, with --strict-frp-version, refuses) a server
+// whose frps version may not work with the embedded frp client, and has
+// the user accept the server's terms of service if it announces any.
+func checkDirectModeUpdate(cfg *config.Config) {
+	client := api.NewAPIClient(cfg.ServerURL, api.WithTimeout(cfg.APITimeout), api.WithInsecureSkipVerify(cfg.InsecureSkipVerify))
 	info, err := client.FetchServerInfo()
-	if err != nil || info.ClientVersion == "" || info.ClientVersion == "unknown" {
+	if err != nil {
 		return // Can't check, skip silently.
 	}
 
+	if warning := tunnel.CheckFrpVersion(info.FrpVersion); warning != "" {
+		if cfg.StrictFrpVersion {
+			fmt.Fprintf(os.Stderr, "%s，已拒绝连接 (--strict-frp-version)\n", warning)
+			os.Exit(1)
+		}
+		fmt.Fprintf(os.Stderr, "Warning: %s\n", warning)
+	}
+
+	if err := checkTOS(cfg, cfg.ServerURL, info); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	if info.ClientVersion == "" || info.ClientVersion == "unknown" {
+		return
+	}
+
 	updateInfo, err := updater.CheckUpdate(info.ClientVersion, version, info.UpdateChannel)
 	if err != nil || updateInfo == nil || !updateInfo.Available {
 		return
 	}
 
 	if updateInfo.Force {
-		fmt.Fprintf(os.Stderr, "版本不匹配 (当前: %s, 要求: %s)，正在更新...\n", version, updateInfo.Version)
-		if err := updater.DoUpdate(updateInfo.TargetTag); err != nil {
+		if cfg.NoUpdate {
+			fmt.Fprintf(os.Stderr, "该服务器要求客户端版本 %s (当前: %s)，已禁用自动更新 (--no-update)\n", updateInfo.Version, version)
+			fmt.Fprintf(os.Stderr, "请从 %s 下载新版本，或去掉 --no-update 后重新运行\n", updater.ReleasePageURL(updateInfo.TargetTag))
+			os.Exit(1)
+		}
+		fmt.Fprintf(os.Stderr, "该服务器要求客户端版本 %s (当前: %s)，正在自动升级...\n", updateInfo.Version, version)
+		onProgress := func(p updater.Progress) {
+			if p.Err != nil {
+				fmt.Fprintf(os.Stderr, "下载中断 (%v)，正在重试 (%d/%d)...\n", p.Err, p.Attempt, updater.MaxDownloadAttempts)
+			}
+		}
+		if err := updater.DoUpdate(updateInfo.TargetTag, cfg.UpdateAsset, onProgress); err != nil {
+			if errors.Is(err, updater.ErrReleaseNotFound) {
+				// Retrying can't help; let the server decide whether the
+				// current binary is still acceptable.
+				fmt.Fprintf(os.Stderr, "服务器要求的版本不存在，请联系管理员 (%s)，继续使用当前版本 %s\n", updateInfo.TargetTag, version)
+				return
+			}
 			fmt.Fprintf(os.Stderr, "更新失败: %v\n", err)
 			os.Exit(1)
 		}
@@ -75,109 +179,521 @@ func checkDirectModeUpdate(serverURL string) {
 			fmt.Fprintf(os.Stderr, "重启失败: %v，请手动重新运行\n", err)
 			os.Exit(1)
 		}
-	} else {
+	} else if !cfg.NoUpdate {
 		fmt.Fprintf(os.Stderr, "提示: 有新版本可用 (%s)，当前版本: %s\n", updateInfo.Version, version)
 	}
 }
 
+// pickFastestServer handles --auto-server in direct mode: it probes the
+// listed servers (or --servers) and points cfg at the fastest reachable one.
+func pickFastestServer(cfg *config.Config) error {
+	urls := cfg.Servers
+	if len(urls) == 0 {
+		entries, err := api.FetchServerList(cfg.ServerListURL, cfg.ServerListAuth, cfg.ProbeTimeout)
+		if err != nil {
+			return fmt.Errorf("--auto-server: %w", err)
+		}
+		for _, e := range entries {
+			urls = append(urls, e.APIUrl)
+		}
+	}
+	fmt.Printf("Probing %d server(s)...\n", len(urls))
+	best, ok := api.Fastest(api.ProbeServers(urls, cfg.ProbeTimeout, cfg.ProbeConcurrency, api.WithInsecureSkipVerify(cfg.InsecureSkipVerify)))
+	if !ok {
+		return errors.New("--auto-server: no reachable server")
+	}
+	cfg.ServerURL = best.APIUrl
+	fmt.Printf("Auto-selected server: %s (%s, %d ms)\n\n", best.Info.Name, api.RedactURL(best.APIUrl), best.Latency.Milliseconds())
+	return nil
+}
+
 // runDirect handles the direct connect mode (no TUI).
 // It validates the access key with the server, then starts the frp tunnel.
 func runDirect(cfg *config.Config) error {
 	fmt.Printf("FireFrp Client - Direct Mode\n")
 	fmt.Printf("Server: %s\n", cfg.ServerURL)
-	fmt.Printf("Local:  %s:%d\n\n", cfg.LocalIP, cfg.LocalPort)
+	fmt.Printf("Local:  %s\n\n", tunnel.JoinHostPort(cfg.LocalIP, cfg.LocalPort))
+	printInsecureBanner(cfg)
 
 	// Step 0: Check for client updates.
-	checkDirectModeUpdate(cfg.ServerURL)
+	checkDirectModeUpdate(cfg)
 
-	// Step 1: Validate the access key with the management server.
-	fmt.Printf("Validating access key...\n")
-	apiClient := api.NewAPIClient(cfg.ServerURL)
-	resp, err := apiClient.Validate(cfg.AccessKey)
+	status, err := startStatusServer(cfg)
 	if err != nil {
-		return fmt.Errorf("failed to validate key: %w", err)
+		return err
 	}
+	defer status.Close()
 
-	if !resp.OK {
-		if resp.Error != nil {
-			return fmt.Errorf("validation failed [%s]: %s", resp.Error.Code, resp.Error.Message)
+	if cfg.UseToken() {
+		if err := issueKey(cfg); err != nil {
+			return err
 		}
-		return fmt.Errorf("validation failed: unknown error")
 	}
 
-	if resp.Data == nil {
-		return fmt.Errorf("validation succeeded but no connection data returned")
+	// Refuse early if another local instance is already using this key.
+	// The lock is advisory: failure to manage the lock file is ignored.
+	lock, err := keylock.Acquire(cfg.AccessKey)
+	if errors.Is(err, keylock.ErrLocked) {
+		return err
 	}
+	defer lock.Release()
 
-	data := resp.Data
+	// Step 1: Validate the access key with the management server.
+	fmt.Printf("Validating access key...\n")
+	data, err := validateKey(cfg, cfg.ServerURL)
+	if err != nil {
+		return err
+	}
+
+	tunnelCfg := buildTunnelConfig(cfg, data)
+	status.attach(&tunnelCfg, data)
 	fmt.Printf("Key validated successfully!\n")
-	fmt.Printf("  Remote: %s:%d -> localhost:%d\n", data.FrpsAddr, data.RemotePort, cfg.LocalPort)
+	fmt.Printf("  Remote: %s -> %s\n", tunnelCfg.RemoteAddr(), tunnelCfg.LocalAddr())
 	fmt.Printf("  Proxy:  %s\n", data.ProxyName)
-	fmt.Printf("  Expires: %s\n\n", data.ExpiresAt)
+	fmt.Printf("  Expires: %s\n\n", expiryText(data))
+	printPortWarnings(cfg, "", tunnelCfg)
+	printClockSkew("", data)
+	printMotd("", data)
+	if cfg.Audit {
+		printAudit(cfg, "", data, tunnelCfg)
+		if err := confirmAudit(); err != nil {
+			return err
+		}
+	}
 
 	// Step 2: Set up context with signal handling for graceful shutdown.
-	ctx, cancel := context.WithCancel(context.Background())
+	ctx, cancel := signalContext()
 	defer cancel()
+	if cfg.EndpointFile != "" {
+		defer tunnel.RemoveEndpointFile(cfg.EndpointFile)
+	}
 
-	sigCh := make(chan os.Signal, 1)
-	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
+	// Step 3: Start the tunnel and monitor status updates.
+	statusCh := make(chan tunnel.StatusUpdate, 16)
+	logCh := make(chan tunnel.LogEntry, 64)
 
+	// Monitor status updates in a separate goroutine.
+	stats := sessionStats{status: status, notifier: newDesktopNotifier(cfg, "", tunnelCfg)}
+	monitorDone := make(chan struct{})
 	go func() {
-		sig := <-sigCh
-		fmt.Printf("\nReceived signal %v, shutting down...\n", sig)
-		cancel()
+		monitorStatus("", statusCh, &stats, endpointWriter(cfg, "", tunnelCfg, data))
+		close(monitorDone)
+	}()
+
+	// Drain log entries in a separate goroutine (CLI mode prints to stdout anyway).
+	go func() {
+		for range logCh {
+		}
 	}()
 
-	// Step 3: Build tunnel configuration from validation response.
-	tunnelCfg := tunnel.TunnelConfig{
+	// StartTunnel blocks until context is cancelled or an error occurs.
+	fmt.Printf("Starting tunnel...\n")
+	err = tunnel.StartTunnel(ctx, tunnelCfg, statusCh, logCh)
+	close(statusCh)
+	<-monitorDone
+	stats.print("", exitReason(ctx, err, &stats))
+	return err
+}
+
+// runMirrored handles direct mode with --mirror-servers: the key is
+// validated on every server and a tunnel is kept up on each one that
+// accepts it. Servers that fail are reported and skipped; the command
+// only fails if no tunnel could be started at all.
+func runMirrored(cfg *config.Config) error {
+	fmt.Printf("FireFrp Client - Mirror Mode\n")
+	for i, server := range cfg.MirrorServers {
+		fmt.Printf("Server %d: %s\n", i+1, server)
+	}
+	fmt.Printf("Local:    %s\n\n", tunnel.JoinHostPort(cfg.LocalIP, cfg.LocalPort))
+	printInsecureBanner(cfg)
+
+	checkDirectModeUpdate(cfg)
+
+	status, err := startStatusServer(cfg)
+	if err != nil {
+		return err
+	}
+	defer status.Close()
+
+	lock, err := keylock.Acquire(cfg.AccessKey)
+	if errors.Is(err, keylock.ErrLocked) {
+		return err
+	}
+	defer lock.Release()
+
+	var tunnelCfgs []tunnel.TunnelConfig
+	var datas []*api.ValidateData
+	var prefixes []string
+	for i, server := range cfg.MirrorServers {
+		prefix := fmt.Sprintf("[#%d] ", i+1)
+		if i > 0 {
+			// checkDirectModeUpdate covered the first server.
+			if err := checkMirrorTOS(cfg, server); err != nil {
+				fmt.Fprintf(os.Stderr, "%sError: %v\n", prefix, err)
+				continue
+			}
+		}
+		fmt.Printf("%sValidating access key on %s...\n", prefix, server)
+		data, err := validateKey(cfg, server)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%sError: %v\n", prefix, err)
+			continue
+		}
+		tunnelCfg := buildTunnelConfig(cfg, data)
+		if len(tunnelCfgs) == 0 {
+			// --status-addr reports the first validated server's tunnel.
+			status.attach(&tunnelCfg, data)
+		}
+		fmt.Printf("%sRemote: %s (expires %s)\n", prefix, tunnelCfg.RemoteAddr(), expiryText(data))
+		printPortWarnings(cfg, prefix, tunnelCfg)
+		printClockSkew(prefix, data)
+		printMotd(prefix, data)
+		if cfg.Audit {
+			printAudit(cfg, prefix, data, tunnelCfg)
+		}
+		tunnelCfgs = append(tunnelCfgs, tunnelCfg)
+		datas = append(datas, data)
+		prefixes = append(prefixes, prefix)
+	}
+	if len(tunnelCfgs) == 0 {
+		return fmt.Errorf("key validation failed on all mirror servers")
+	}
+	fmt.Println()
+	if cfg.Audit {
+		if err := confirmAudit(); err != nil {
+			return err
+		}
+	}
+
+	ctx, cancel := signalContext()
+	defer cancel()
+	if cfg.EndpointFile != "" {
+		defer tunnel.RemoveEndpointFile(cfg.EndpointFile)
+	}
+
+	var wg sync.WaitGroup
+	errs := make([]error, len(tunnelCfgs))
+	stats := make([]sessionStats, len(tunnelCfgs))
+	stats[0].status = status
+	for i := range stats {
+		stats[i].notifier = newDesktopNotifier(cfg, prefixes[i], tunnelCfgs[i])
+	}
+	for i := range tunnelCfgs {
+		statusCh := make(chan tunnel.StatusUpdate, 16)
+		logCh := make(chan tunnel.LogEntry, 64)
+		monitorDone := make(chan struct{})
+		// The endpoint file names the first validated server's tunnel.
+		var onConnected func(string)
+		if i == 0 {
+			onConnected = endpointWriter(cfg, prefixes[i], tunnelCfgs[i], datas[i])
+		}
+		go func() {
+			monitorStatus(prefixes[i], statusCh, &stats[i], onConnected)
+			close(monitorDone)
+		}()
+		go func() {
+			for range logCh {
+			}
+		}()
+
+		wg.Add(1)
+		go func(idx int) {
+			defer wg.Done()
+			errs[idx] = tunnel.StartTunnel(ctx, tunnelCfgs[idx], statusCh, logCh)
+			close(statusCh)
+			<-monitorDone
+		}(i)
+	}
+	wg.Wait()
+
+	for i := range stats {
+		stats[i].print(prefixes[i], exitReason(ctx, errs[i], &stats[i]))
+	}
+
+	// Success as long as at least one mirror ran until shutdown.
+	for _, err := range errs {
+		if err == nil {
+			return nil
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// printInsecureBanner warns, in red where the terminal supports it, that
+// TLS certificate verification is disabled (--insecure-skip-verify).
+func printInsecureBanner(cfg *config.Config) {
+	if !cfg.InsecureSkipVerify {
+		return
+	}
+	fmt.Println(theme.InsecureBannerStyle.Render(theme.GlyphWarning + " 证书校验已禁用 (--insecure-skip-verify)"))
+	fmt.Println()
+}
+
+// issueKey has the server issue an access key for --token and uses it as
+// cfg.AccessKey from then on.
+func issueKey(cfg *config.Config) error {
+	fmt.Printf("Requesting access key for account token...\n")
+	apiClient := api.NewAPIClient(cfg.ServerURL, api.WithTimeout(cfg.APITimeout), api.WithInsecureSkipVerify(cfg.InsecureSkipVerify), api.WithRedirectApproval(confirmRedirect))
+	key, err := apiClient.IssueKey(cfg.Token, cfg.LocalPort)
+	if errors.Is(err, api.ErrTokenExpired) {
+		return errors.New("account token expired, log in again for a new --token")
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get access key: %w", err)
+	}
+	cfg.AccessKey = key
+	return nil
+}
+
+// validateKey validates the access key against serverURL and returns the
+// frps connection parameters.
+func validateKey(cfg *config.Config, serverURL string) (*api.ValidateData, error) {
+	apiClient := api.NewAPIClient(serverURL, api.WithTimeout(cfg.APITimeout), api.WithProxyName(cfg.ProxyName), api.WithKeyPlacement(api.KeyPlacement(cfg.KeyIn)), api.WithInsecureSkipVerify(cfg.InsecureSkipVerify), api.WithRedirectApproval(confirmRedirect))
+	resp, err := apiClient.Validate(cfg.AccessKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to validate key: %w", err)
+	}
+	if from, ok := apiClient.Redirected(); ok {
+		fmt.Printf("Note: the server moved its API from %s to %s\n", api.RedactURL(from), api.RedactURL(apiClient.BaseURL()))
+	}
+
+	if !resp.OK {
+		if resp.Error != nil {
+			if resp.Error.Code == "PROXY_NAME_TAKEN" {
+				return nil, fmt.Errorf("proxy name %q is already taken, choose another --proxy-name", cfg.ProxyName)
+			}
+			return nil, &keyRejectedError{info: resp.Error}
+		}
+		return nil, fmt.Errorf("validation failed: unknown error")
+	}
+
+	if resp.Data == nil {
+		return nil, fmt.Errorf("validation succeeded but no connection data returned")
+	}
+	if !resp.Data.ValidRemotePort() {
+		return nil, fmt.Errorf("server returned invalid remote port %d", resp.Data.RemotePort)
+	}
+	if _, err := resp.Data.Expiry(); err != nil {
+		return nil, fmt.Errorf("server returned an invalid expiry: %w", err)
+	}
+	if cfg.ProxyName != "" && resp.Data.ProxyName != cfg.ProxyName {
+		fmt.Fprintf(os.Stderr, "Note: server assigned proxy name %q instead of %q\n", resp.Data.ProxyName, cfg.ProxyName)
+	}
+	return resp.Data, nil
+}
+
+// keyRejectedError is returned by validateKey when the server refuses
+// the key.
+type keyRejectedError struct {
+	info *api.ErrorInfo
+}
+
+func (e *keyRejectedError) Error() string {
+	return fmt.Sprintf("validation failed [%s]: %s", e.info.Code, e.info.Message)
+}
+
+// buildTunnelConfig builds the tunnel configuration from a validation response.
+func buildTunnelConfig(cfg *config.Config, data *api.ValidateData) tunnel.TunnelConfig {
+	return tunnel.TunnelConfig{
 		ServerAddr: data.FrpsAddr,
 		ServerPort: data.FrpsPort,
+		Fallbacks:  data.FallbackEndpoints(),
+		PublicAddr: data.PublicAddr,
 		Token:      data.Token,
+		AuthMethod: data.AuthMethod,
+		OIDC:       (*tunnel.OIDCConfig)(data.OIDC),
 		AccessKey:  cfg.AccessKey,
 		ProxyName:  data.ProxyName,
 		LocalIP:    cfg.LocalIP,
 		LocalPort:  cfg.LocalPort,
 		RemotePort: data.RemotePort,
+
+		Metadata:    tunnel.ClientMetadata(version, cfg.Label, cfg.Metadata),
+		IdleTimeout: cfg.IdleTimeout,
+		ProxyURL:    cfg.TunnelProxy,
 	}
+}
 
-	// Step 4: Start the tunnel and monitor status updates.
-	statusCh := make(chan tunnel.StatusUpdate, 16)
-	logCh := make(chan tunnel.LogEntry, 64)
+// expiryText formats the key's expiry on the local clock. validateKey has
+// already rejected expiries that don't parse.
+func expiryText(data *api.ValidateData) string {
+	t, err := data.Expiry()
+	if err != nil {
+		return string(data.ExpiresAt)
+	}
+	return t.Local().Format(time.RFC3339)
+}
 
-	// Monitor status updates in a separate goroutine.
-	go monitorStatus(statusCh)
+// printPortWarnings prints the tunnel's advisory port warnings unless
+// silenced with --no-port-warnings.
+func printPortWarnings(cfg *config.Config, prefix string, tunnelCfg tunnel.TunnelConfig) {
+	if cfg.NoPortWarnings {
+		return
+	}
+	for _, w := range tunnelCfg.Warnings() {
+		fmt.Fprintf(os.Stderr, "%sWarning: %s\n", prefix, w)
+	}
+}
+
+// printClockSkew warns when the local clock is far off the server's, since
+// expiry times would otherwise look wrong.
+func printClockSkew(prefix string, data *api.ValidateData) {
+	skew, ok := data.ClockSkew()
+	if !ok || (skew <= api.ClockSkewWarning && skew >= -api.ClockSkewWarning) {
+		return
+	}
+	dir := "ahead of"
+	if skew > 0 {
+		dir = "behind"
+	}
+	fmt.Fprintf(os.Stderr, "%sWarning: local clock is %s %s the server's; check your system time\n",
+		prefix, skew.Abs().Round(time.Second), dir)
+}
+
+// printMotd prints the server's connect message, if any.
+func printMotd(prefix string, data *api.ValidateData) {
+	motd := api.CleanMotd(data.Motd)
+	if motd == "" {
+		return
+	}
+	fmt.Printf("%sServer message:\n", prefix)
+	for _, line := range strings.Split(motd, "\n") {
+		fmt.Printf("%s  %s\n", prefix, line)
+	}
+	fmt.Println()
+}
+
+// signalContext returns a context that is cancelled on SIGINT/SIGTERM.
+func signalContext() (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
 
-	// Drain log entries in a separate goroutine (CLI mode prints to stdout anyway).
 	go func() {
-		for range logCh {
-		}
+		sig := <-sigCh
+		fmt.Printf("\nReceived signal %v, shutting down...\n", sig)
+		cancel()
 	}()
+	return ctx, cancel
+}
 
-	// StartTunnel blocks until context is cancelled or an error occurs.
-	fmt.Printf("Starting tunnel...\n")
-	return tunnel.StartTunnel(ctx, tunnelCfg, statusCh, logCh)
+// sessionStats accumulates what monitorStatus observed, for the summary
+// printed when the tunnel ends.
+type sessionStats struct {
+	connectedAt  time.Time // first successful connection; zero if never connected
+	reconnects   int
+	reconnecting bool
+	lastMessage  string // message of the last error/rejection/close update
+
+	// status, if non-nil, is the --status-addr server fed the same updates.
+	status *statusServer
+	// notifier, if non-nil, shows --notify desktop notifications.
+	notifier *desktopNotifier
+}
+
+// observe records a status update.
+func (s *sessionStats) observe(u tunnel.StatusUpdate) {
+	s.status.observe(u)
+	s.notifier.observe(u)
+	switch u.Status {
+	case tunnel.StatusConnected:
+		if s.connectedAt.IsZero() {
+			s.connectedAt = time.Now()
+		}
+		s.reconnecting = false
+	case tunnel.StatusReconnecting:
+		// frpc reports every retry; count each outage once.
+		if !s.reconnecting {
+			s.reconnects++
+			s.reconnecting = true
+		}
+	case tunnel.StatusRejected, tunnel.StatusError, tunnel.StatusClosed:
+		s.lastMessage = u.Message
+	}
 }
 
-// monitorStatus reads status updates from the tunnel and prints them to stdout.
-func monitorStatus(statusCh <-chan tunnel.StatusUpdate) {
+// print writes the session summary to stdout.
+func (s *sessionStats) print(prefix, reason string) {
+	uptime := "never connected"
+	if !s.connectedAt.IsZero() {
+		uptime = time.Since(s.connectedAt).Round(time.Second).String()
+	}
+	fmt.Printf("\n%sSession summary:\n", prefix)
+	fmt.Printf("%s  Uptime:     %s\n", prefix, uptime)
+	fmt.Printf("%s  Reconnects: %d\n", prefix, s.reconnects)
+	fmt.Printf("%s  Exit:       %s\n", prefix, reason)
+}
+
+// exitReason describes why a tunnel stopped, given StartTunnel's result.
+func exitReason(ctx context.Context, err error, s *sessionStats) string {
+	switch {
+	case err != nil:
+		return err.Error()
+	case ctx.Err() != nil:
+		return "stopped by user"
+	case s.lastMessage != "":
+		return s.lastMessage
+	default:
+		return "tunnel closed"
+	}
+}
+
+// endpointWriter returns a callback that writes the tunnel's endpoint to
+// --write-endpoint-file, or nil if the flag is unset. The callback takes
+// the frps endpoint the tunnel connected through, since the public host
+// may depend on it. Write failures are reported but don't stop the tunnel.
+func endpointWriter(cfg *config.Config, prefix string, tunnelCfg tunnel.TunnelConfig, data *api.ValidateData) func(string) {
+	if cfg.EndpointFile == "" {
+		return nil
+	}
+	expiresAt, _ := data.Expiry()
+	return func(endpoint string) {
+		if endpoint != "" {
+			tunnelCfg.UseEndpoint(endpoint)
+		}
+		addr := tunnelCfg.RemoteAddr()
+		if err := tunnel.WriteEndpointFile(cfg.EndpointFile, addr, expiresAt); err != nil {
+			fmt.Fprintf(os.Stderr, "%sWarning: %v\n", prefix, err)
+		}
+	}
+}
+
+// monitorStatus reads status updates from the tunnel and prints them to
+// stdout, each line starting with prefix (used to tell mirrors apart). Each
+// update is also recorded in stats, and onConnected (if non-nil) runs on
+// every successful (re)connect with the frps endpoint in use.
+func monitorStatus(prefix string, statusCh <-chan tunnel.StatusUpdate, stats *sessionStats, onConnected func(endpoint string)) {
 	for update := range statusCh {
+		stats.observe(update)
 		switch update.Status {
 		case tunnel.StatusConnecting:
-			fmt.Printf("[CONNECTING] %s\n", update.Message)
+			fmt.Printf("%s[CONNECTING] %s\n", prefix, update.Message)
 		case tunnel.StatusConnected:
-			fmt.Printf("[CONNECTED]  %s\n", update.Message)
+			if update.Endpoint != "" {
+				fmt.Printf("%s[CONNECTED]  %s (via %s)\n", prefix, update.Message, update.Endpoint)
+			} else {
+				fmt.Printf("%s[CONNECTED]  %s\n", prefix, update.Message)
+			}
+			if onConnected != nil {
+				onConnected(update.Endpoint)
+			}
 		case tunnel.StatusReconnecting:
-			fmt.Printf("[RECONNECT]  %s\n", update.Message)
+			if update.Attempt > 0 {
+				fmt.Printf("%s[RECONNECT]  %s (attempt %d)\n", prefix, update.Message, update.Attempt)
+			} else {
+				fmt.Printf("%s[RECONNECT]  %s\n", prefix, update.Message)
+			}
 		case tunnel.StatusRejected:
-			fmt.Printf("[REJECTED]   %s\n", update.Message)
+			fmt.Printf("%s[REJECTED]   %s\n", prefix, update.Message)
 		case tunnel.StatusError:
 			if update.Error != nil {
-				fmt.Printf("[ERROR]      %s: %v\n", update.Message, update.Error)
+				fmt.Printf("%s[ERROR]      %s: %v\n", prefix, update.Message, update.Error)
 			} else {
-				fmt.Printf("[ERROR]      %s\n", update.Message)
+				fmt.Printf("%s[ERROR]      %s\n", prefix, update.Message)
 			}
 		case tunnel.StatusClosed:
-			fmt.Printf("[CLOSED]     %s\n", update.Message)
+			fmt.Printf("%s[CLOSED]     %s\n", prefix, update.Message)
 			return
 		}
 	}
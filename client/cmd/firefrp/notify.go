@@ -0,0 +1,62 @@
+package main
+
+import (
+	"strings"
+
+	"github.com/AerNos/firefrp-client/internal/config"
+	"github.com/AerNos/firefrp-client/internal/notify"
+	"github.com/AerNos/firefrp-client/internal/tunnel"
+)
+
+// desktopNotifier shows --notify desktop notifications for one tunnel.
+// A nil *desktopNotifier does nothing.
+type desktopNotifier struct {
+	prefix    string
+	tunnelCfg tunnel.TunnelConfig
+	last      tunnel.Status // last status notified
+}
+
+// newDesktopNotifier returns a notifier for tunnelCfg, or nil without
+// --notify. prefix tells mirrors and rotated keys apart.
+func newDesktopNotifier(cfg *config.Config, prefix string, tunnelCfg tunnel.TunnelConfig) *desktopNotifier {
+	if !cfg.Notify {
+		return nil
+	}
+	return &desktopNotifier{prefix: strings.TrimSpace(prefix), tunnelCfg: tunnelCfg}
+}
+
+// observe notifies when the tunnel connects, is rejected or fails, once
+// per change of status, so reconnects don't repeat the connect
+// notification. Where no notification can be shown (headless, SSH)
+// nothing happens.
+func (n *desktopNotifier) observe(u tunnel.StatusUpdate) {
+	if n == nil {
+		return
+	}
+	if u.Status == tunnel.StatusConnected && u.Endpoint != "" {
+		n.tunnelCfg.UseEndpoint(u.Endpoint)
+	}
+	if u.Status == n.last {
+		return
+	}
+	var title string
+	switch u.Status {
+	case tunnel.StatusConnected:
+		title = "Tunnel connected"
+	case tunnel.StatusRejected:
+		title = "Tunnel rejected"
+	case tunnel.StatusError:
+		title = "Tunnel error"
+	default:
+		return
+	}
+	n.last = u.Status
+	if n.prefix != "" {
+		title = n.prefix + " " + title
+	}
+	body := n.tunnelCfg.RemoteAddr()
+	if u.Status != tunnel.StatusConnected && u.Message != "" {
+		body += "\n" + u.Message
+	}
+	_ = notify.Send("FireFrp: "+title, body)
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+
+	"github.com/AerNos/firefrp-client/internal/config"
+	"github.com/AerNos/firefrp-client/internal/profile"
+	"github.com/AerNos/firefrp-client/internal/tunnel"
+)
+
+// runProfiles lists the saved connection profiles.
+func runProfiles() error {
+	profiles, err := profile.List()
+	if err != nil {
+		return err
+	}
+	if len(profiles) == 0 {
+		fmt.Println("No saved profiles. Save one from the TUI input view with Ctrl+S.")
+		return nil
+	}
+	for _, p := range profiles {
+		server := p.ServerURL
+		if p.ServerName != "" {
+			server = fmt.Sprintf("%s (%s)", p.ServerName, p.ServerURL)
+		}
+		local := fmt.Sprintf("%d", p.LocalPort)
+		if p.LocalIP != "" {
+			local = tunnel.JoinHostPort(p.LocalIP, p.LocalPort)
+		}
+		fmt.Printf("%-16s %-21s %s\n", p.Name, local, server)
+	}
+	return nil
+}
+
+// applyProfile fills cfg from the profile named by --profile. Settings
+// given explicitly on the command line win over the profile's. A profile
+// names one server, so it also skips the server list.
+func applyProfile(cfg *config.Config) error {
+	if cfg.Profile == "" {
+		return nil
+	}
+	p, err := profile.Get(cfg.Profile)
+	if err != nil {
+		return err
+	}
+
+	set := make(map[string]bool)
+	flag.Visit(func(f *flag.Flag) { set[f.Name] = true })
+
+	if p.ServerURL != "" && !set["server"] && !set["servers"] && !set["mirror-servers"] {
+		cfg.ServerURL = p.ServerURL
+		cfg.ServerName = p.ServerName
+		if !set["server-list"] {
+			cfg.ServerListURL = ""
+		}
+	}
+	if p.LocalIP != "" && !set["local-ip"] {
+		cfg.LocalIP = p.LocalIP
+	}
+	if p.LocalPort > 0 && !set["port"] {
+		cfg.LocalPort = p.LocalPort
+	}
+	if p.Label != "" && !set["label"] {
+		cfg.Label = p.Label
+	}
+	return nil
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/charmbracelet/x/term"
+
+	"github.com/AerNos/firefrp-client/internal/api"
+)
+
+// approvedRedirects remembers the redirects confirmRedirect was allowed
+// to follow, so key rotation and mirrors don't ask again.
+var approvedRedirects = map[[2]string]bool{}
+
+// confirmRedirect asks whether a key or token may be sent to the host the
+// server moved its API to (see api.WithRedirectApproval). Without a
+// terminal to ask on, the redirect is refused.
+func confirmRedirect(from, to string) bool {
+	if approvedRedirects[[2]string{from, to}] {
+		return true
+	}
+	fmt.Printf("The server moved its API from %s to another host: %s\n", api.RedactURL(from), api.RedactURL(to))
+	if !term.IsTerminal(os.Stdin.Fd()) {
+		fmt.Printf("Not following it without confirmation; use the new address as --server if you trust it.\n")
+		return false
+	}
+	fmt.Printf("Send your credentials there? Type y to continue: ")
+	line, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+	answer := strings.ToLower(strings.TrimSpace(line))
+	if answer != "y" && answer != "yes" {
+		return false
+	}
+	approvedRedirects[[2]string{from, to}] = true
+	return true
+}
@@ -0,0 +1,182 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sync/atomic"
+	"time"
+
+	"github.com/AerNos/firefrp-client/internal/config"
+	"github.com/AerNos/firefrp-client/internal/keylock"
+	"github.com/AerNos/firefrp-client/internal/tunnel"
+)
+
+// runKeyRotation handles direct mode with several keys (--keys,
+// --key-file). Keys are used in order: once the one in use expires or the
+// server rejects it, the tunnel is stopped and the next unused key is
+// validated and connected without asking. Keys the server refuses for
+// good are skipped; other validation failures are retried a few times
+// before giving up. It returns when the user stops it or every key has
+// been used.
+func runKeyRotation(cfg *config.Config) error {
+	fmt.Printf("FireFrp Client - Direct Mode\n")
+	fmt.Printf("Server: %s\n", cfg.ServerURL)
+	fmt.Printf("Local:  %s\n", tunnel.JoinHostPort(cfg.LocalIP, cfg.LocalPort))
+	fmt.Printf("Keys:   %d\n\n", len(cfg.Keys))
+	printInsecureBanner(cfg)
+
+	checkDirectModeUpdate(cfg)
+
+	status, err := startStatusServer(cfg)
+	if err != nil {
+		return err
+	}
+	defer status.Close()
+
+	ctx, cancel := signalContext()
+	defer cancel()
+	if cfg.EndpointFile != "" {
+		defer tunnel.RemoveEndpointFile(cfg.EndpointFile)
+	}
+
+	connected := 0
+	for i, key := range cfg.Keys {
+		prefix := fmt.Sprintf("[key %d/%d] ", i+1, len(cfg.Keys))
+		if i > 0 {
+			fmt.Printf("\n%sSwitching to the next key...\n", prefix)
+		}
+		next, ran, err := runWithKey(ctx, cfg, key, prefix, connected == 0, status)
+		if ran {
+			connected++
+		}
+		if !next {
+			return err
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%sError: %v\n", prefix, err)
+		}
+	}
+
+	fmt.Printf("\nAll %d keys have been used (%d connected); stopping.\n", len(cfg.Keys), connected)
+	if connected == 0 {
+		return errors.New("none of the keys could be used")
+	}
+	return nil
+}
+
+// runWithKey validates key and keeps a tunnel up with it until the key
+// expires, the server rejects it or ctx is cancelled. next reports whether
+// the caller should move on to the following key; ran whether a tunnel was
+// started at all. Only the first key's --audit report asks for confirmation.
+func runWithKey(ctx context.Context, cfg *config.Config, key, prefix string, confirm bool, status *statusServer) (next, ran bool, err error) {
+	lock, err := keylock.Acquire(key)
+	if errors.Is(err, keylock.ErrLocked) {
+		return true, false, err
+	}
+	defer lock.Release()
+
+	// validateKey and buildTunnelConfig read the key from cfg.
+	cfg.AccessKey = key
+	fmt.Printf("%sValidating access key...\n", prefix)
+	data, err := validateKey(cfg, cfg.ServerURL)
+	for attempt := 1; err != nil; attempt++ {
+		if keyUnusable(err) {
+			return true, false, err
+		}
+		if attempt >= maxKeyAttempts {
+			// Not the key's fault as far as we know: don't burn the next.
+			return false, false, err
+		}
+		fmt.Fprintf(os.Stderr, "%sError: %v; retrying in %s (%d/%d)\n", prefix, err, keyRetryDelay, attempt, maxKeyAttempts)
+		select {
+		case <-ctx.Done():
+			return false, false, err
+		case <-time.After(keyRetryDelay):
+		}
+		data, err = validateKey(cfg, cfg.ServerURL)
+	}
+
+	tunnelCfg := buildTunnelConfig(cfg, data)
+	status.attach(&tunnelCfg, data)
+	fmt.Printf("%sRemote: %s -> %s (expires %s)\n", prefix, tunnelCfg.RemoteAddr(), tunnelCfg.LocalAddr(), expiryText(data))
+	printPortWarnings(cfg, prefix, tunnelCfg)
+	printClockSkew(prefix, data)
+	printMotd(prefix, data)
+	if cfg.Audit {
+		printAudit(cfg, prefix, data, tunnelCfg)
+		if confirm {
+			if err := confirmAudit(); err != nil {
+				return false, false, err
+			}
+		}
+	}
+
+	// The key is done once its expiry passes or the server rejects it;
+	// either way only this key's tunnel is stopped.
+	keyCtx, stop := context.WithCancel(ctx)
+	defer stop()
+	var used atomic.Bool
+	if t, err := data.Expiry(); err == nil {
+		timer := time.AfterFunc(time.Until(t), func() {
+			used.Store(true)
+			stop()
+		})
+		defer timer.Stop()
+	}
+
+	tunnelCh := make(chan tunnel.StatusUpdate, 16)
+	statusCh := make(chan tunnel.StatusUpdate, 16)
+	logCh := make(chan tunnel.LogEntry, 64)
+	go func() {
+		for update := range tunnelCh {
+			if update.Status == tunnel.StatusRejected {
+				used.Store(true)
+				stop()
+			}
+			statusCh <- update
+		}
+		close(statusCh)
+	}()
+
+	stats := sessionStats{status: status, notifier: newDesktopNotifier(cfg, prefix, tunnelCfg)}
+	monitorDone := make(chan struct{})
+	go func() {
+		monitorStatus(prefix, statusCh, &stats, endpointWriter(cfg, prefix, tunnelCfg, data))
+		close(monitorDone)
+	}()
+	go func() {
+		for range logCh {
+		}
+	}()
+
+	fmt.Printf("%sStarting tunnel...\n", prefix)
+	err = tunnel.StartTunnel(keyCtx, tunnelCfg, tunnelCh, logCh)
+	close(tunnelCh)
+	<-monitorDone
+	for range statusCh {
+	}
+
+	if ctx.Err() == nil && used.Load() {
+		stats.print(prefix, "key expired or was rejected")
+		return true, true, nil
+	}
+	stats.print(prefix, exitReason(ctx, err, &stats))
+	return false, true, err
+}
+
+// maxKeyAttempts bounds how often a key is validated before key rotation
+// gives up on a failure that isn't a permanent rejection; keyRetryDelay
+// spaces out the attempts.
+const (
+	maxKeyAttempts = 3
+	keyRetryDelay  = 5 * time.Second
+)
+
+// keyUnusable reports whether a validateKey error means the server
+// refuses the key for good, so rotation should move on to the next one.
+func keyUnusable(err error) bool {
+	var rejected *keyRejectedError
+	return errors.As(err, &rejected) && rejected.info.Permanent()
+}
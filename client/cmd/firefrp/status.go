@@ -0,0 +1,165 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/AerNos/firefrp-client/internal/api"
+	"github.com/AerNos/firefrp-client/internal/config"
+	"github.com/AerNos/firefrp-client/internal/tunnel"
+)
+
+// statusServer serves --status-addr: a single JSON document describing the
+// tunnel, for dashboards and health checks of a headless client. It is
+// updated from the same status stream monitorStatus prints. A nil
+// *statusServer ignores every call, so callers need not check the flag.
+type statusServer struct {
+	srv     *http.Server
+	traffic *tunnel.TrafficCounter
+	started time.Time
+
+	mu           sync.Mutex
+	status       tunnel.Status
+	message      string
+	tunnelCfg    *tunnel.TunnelConfig // nil until attach
+	endpoint     string               // frps endpoint of the last connect
+	expiresAt    time.Time
+	connectedAt  time.Time
+	reconnects   int
+	reconnecting bool
+}
+
+// statusReport is the JSON body served by statusServer.
+type statusReport struct {
+	Status        string `json:"status"`
+	Message       string `json:"message,omitempty"`
+	Remote        string `json:"remote,omitempty"`
+	ExpiresAt     string `json:"expires_at,omitempty"`
+	UptimeSeconds int64  `json:"uptime_seconds"`
+	Reconnects    int    `json:"reconnects"`
+	TrafficBytes  int64  `json:"traffic_bytes"`
+}
+
+// startStatusServer starts listening on cfg.StatusAddr, or returns nil if
+// the flag is unset. A bare ":port" binds to 127.0.0.1 so the report is
+// not exposed on the network by accident. The server runs until Close.
+func startStatusServer(cfg *config.Config) (*statusServer, error) {
+	if cfg.StatusAddr == "" {
+		return nil, nil
+	}
+	addr := cfg.StatusAddr
+	if host, port, err := net.SplitHostPort(addr); err == nil && host == "" {
+		addr = net.JoinHostPort("127.0.0.1", port)
+	}
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("--status-addr: %w", err)
+	}
+
+	s := &statusServer{traffic: &tunnel.TrafficCounter{}, started: time.Now()}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /{$}", s.serveHTTP)
+	mux.HandleFunc("GET /status", s.serveHTTP)
+	s.srv = &http.Server{Handler: mux, ReadHeaderTimeout: 5 * time.Second}
+	go func() {
+		if err := s.srv.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			fmt.Fprintf(os.Stderr, "Warning: status server stopped: %v\n", err)
+		}
+	}()
+	fmt.Printf("Status:  http://%s/status\n\n", ln.Addr())
+	return s, nil
+}
+
+// Close stops the server, waiting briefly for in-flight requests.
+func (s *statusServer) Close() {
+	if s == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	s.srv.Shutdown(ctx)
+}
+
+// attach points the report at a newly validated tunnel and makes the
+// tunnel count its traffic into the report. The counter is shared, so
+// traffic keeps adding up across key rotations.
+func (s *statusServer) attach(tunnelCfg *tunnel.TunnelConfig, data *api.ValidateData) {
+	if s == nil {
+		return
+	}
+	tunnelCfg.Traffic = s.traffic
+	expiresAt, _ := data.Expiry()
+	cfgCopy := *tunnelCfg
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tunnelCfg = &cfgCopy
+	s.endpoint = ""
+	s.expiresAt = expiresAt
+}
+
+// observe records a status update, counting reconnects the same way as
+// sessionStats.
+func (s *statusServer) observe(u tunnel.StatusUpdate) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.status = u.Status
+	s.message = u.Message
+	switch u.Status {
+	case tunnel.StatusConnected:
+		if s.connectedAt.IsZero() {
+			s.connectedAt = time.Now()
+		}
+		s.reconnecting = false
+		s.endpoint = u.Endpoint
+	case tunnel.StatusReconnecting:
+		if !s.reconnecting {
+			s.reconnects++
+			s.reconnecting = true
+		}
+	}
+}
+
+// report returns the current state. Uptime counts from the first
+// successful connection and is zero before it.
+func (s *statusServer) report() statusReport {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	r := statusReport{
+		Status:       s.status.String(),
+		Message:      s.message,
+		Reconnects:   s.reconnects,
+		TrafficBytes: s.traffic.Bytes(),
+	}
+	if s.tunnelCfg != nil {
+		// The public host may depend on the frps endpoint in use.
+		tunnelCfg := *s.tunnelCfg
+		if s.endpoint != "" {
+			tunnelCfg.UseEndpoint(s.endpoint)
+		}
+		r.Remote = tunnelCfg.RemoteAddr()
+	}
+	if !s.expiresAt.IsZero() {
+		r.ExpiresAt = s.expiresAt.Format(time.RFC3339)
+	}
+	if !s.connectedAt.IsZero() {
+		r.UptimeSeconds = int64(time.Since(s.connectedAt) / time.Second)
+	}
+	return r
+}
+
+func (s *statusServer) serveHTTP(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	json.NewEncoder(w).Encode(s.report())
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/charmbracelet/x/term"
+
+	"github.com/AerNos/firefrp-client/internal/api"
+	"github.com/AerNos/firefrp-client/internal/config"
+	"github.com/AerNos/firefrp-client/internal/tos"
+)
+
+// errTOSDeclined is returned when the server's terms of service were not
+// accepted.
+var errTOSDeclined = errors.New("the server's terms of service were not accepted")
+
+// checkTOS makes sure the user accepted the terms of service the server
+// announces, if any, before the first connection and after each version
+// bump. --accept-tos accepts them without asking; otherwise the user is
+// asked on the terminal, and without one the connection is refused.
+// serverURL is the server whose info was fetched.
+func checkTOS(cfg *config.Config, serverURL string, info *api.ServerInfo) error {
+	if tos.Accepted(serverURL, info.TosVersion) {
+		return nil
+	}
+	fmt.Printf("This server requires accepting its terms of service (version %s):\n", info.TosVersion)
+	if info.TosURL != "" {
+		fmt.Printf("  %s\n", info.TosURL)
+	}
+
+	switch {
+	case cfg.AcceptTOS:
+		fmt.Printf("Accepted (--accept-tos).\n")
+	case !term.IsTerminal(os.Stdin.Fd()):
+		return fmt.Errorf("%w; read them and rerun with --accept-tos", errTOSDeclined)
+	default:
+		fmt.Printf("Type y to accept: ")
+		line, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+		if answer := strings.ToLower(strings.TrimSpace(line)); answer != "y" && answer != "yes" {
+			return errTOSDeclined
+		}
+	}
+	fmt.Println()
+
+	if err := tos.Accept(serverURL, info.TosVersion); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+	}
+	return nil
+}
+
+// checkMirrorTOS runs checkTOS for a further server of --mirror-servers.
+// A server whose info can't be fetched is let through, as in
+// checkDirectModeUpdate.
+func checkMirrorTOS(cfg *config.Config, serverURL string) error {
+	client := api.NewAPIClient(serverURL, api.WithTimeout(cfg.APITimeout), api.WithInsecureSkipVerify(cfg.InsecureSkipVerify))
+	info, err := client.FetchServerInfo()
+	if err != nil {
+		return nil
+	}
+	return checkTOS(cfg, serverURL, info)
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"runtime"
+	"runtime/debug"
+
+	"github.com/AerNos/firefrp-client/internal/tunnel"
+)
+
+// versionInfo is the --version --json output, for tooling and bug reports.
+type versionInfo struct {
+	Version string `json:"version"`
+	Frp     string `json:"frp"`
+	OS      string `json:"os"`
+	Arch    string `json:"arch"`
+	Commit  string `json:"commit,omitempty"`
+}
+
+// printVersion handles --version: a plain line by default, or a JSON
+// object including the embedded frp version and the VCS commit the binary
+// was built from, when known.
+func printVersion(asJSON bool) error {
+	if !asJSON {
+		fmt.Printf("firefrp version %s\n", version)
+		return nil
+	}
+	info := versionInfo{
+		Version: version,
+		Frp:     tunnel.FrpVersion(),
+		OS:      runtime.GOOS,
+		Arch:    runtime.GOARCH,
+		Commit:  buildCommit(),
+	}
+	return json.NewEncoder(os.Stdout).Encode(info)
+}
+
+// buildCommit returns the VCS revision Go stamped into the binary, or ""
+// when it was built outside a repository.
+func buildCommit() string {
+	bi, ok := debug.ReadBuildInfo()
+	if !ok {
+		return ""
+	}
+	for _, s := range bi.Settings {
+		if s.Key == "vcs.revision" {
+			return s.Value
+		}
+	}
+	return ""
+}
@@ -0,0 +1,61 @@
+package api
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestUnbracketHost(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"[2001:db8::1]", "2001:db8::1"},
+		{"2001:db8::1", "2001:db8::1"},
+		{"[::1]", "::1"},
+		{"frps.example.com", "frps.example.com"},
+		{"[broken", "[broken"},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			if got := unbracketHost(tt.in); got != tt.want {
+				t.Errorf("unbracketHost(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFallbackEndpoints(t *testing.T) {
+	tests := []struct {
+		name string
+		data ValidateData
+		want []string
+	}{
+		{
+			name: "none",
+			data: ValidateData{FrpsAddr: "frps.example.com", FrpsPort: 7000},
+		},
+		{
+			name: "default port and explicit port",
+			data: ValidateData{FrpsAddr: "a.example.com", FrpsPort: 7000, FrpsAddrs: []string{"b.example.com", "c.example.com:7001"}},
+			want: []string{"b.example.com:7000", "c.example.com:7001"},
+		},
+		{
+			name: "ipv6 bracketed and bare",
+			data: ValidateData{FrpsAddr: "2001:db8::1", FrpsPort: 7000, FrpsAddrs: []string{"[2001:db8::2]", "[2001:db8::3]:7002"}},
+			want: []string{"[2001:db8::2]:7000", "[2001:db8::3]:7002"},
+		},
+		{
+			name: "blanks and repeats dropped",
+			data: ValidateData{FrpsAddr: "a.example.com", FrpsPort: 7000, FrpsAddrs: []string{" ", "a.example.com", "b.example.com", "b.example.com:7000"}},
+			want: []string{"b.example.com:7000"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.data.FallbackEndpoints(); !slices.Equal(got, tt.want) {
+				t.Errorf("FallbackEndpoints() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,42 @@
+package api
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// gzipMagic starts every gzip stream; no JSON document begins with it.
+var gzipMagic = []byte{0x1f, 0x8b}
+
+// maxDecompressedBody caps a gunzipped response, so a small compressed
+// body can't expand without bound.
+const maxDecompressedBody = 16 << 20
+
+// readBody reads a response body, decompressing it if it is still gzip
+// data. None of our requests set Accept-Encoding, so the transport asks
+// for gzip and decodes it by itself; this covers what it leaves alone,
+// e.g. a server list stored pre-compressed (.json.gz) and served without
+// a Content-Encoding header, or gzip sent although it wasn't negotiated.
+func readBody(resp *http.Response) ([]byte, error) {
+	body, err := io.ReadAll(resp.Body)
+	if err != nil || !bytes.HasPrefix(body, gzipMagic) {
+		return body, err
+	}
+	zr, err := gzip.NewReader(bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decompress response: %w", err)
+	}
+	defer zr.Close()
+	out, err := io.ReadAll(io.LimitReader(zr, maxDecompressedBody+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decompress response: %w", err)
+	}
+	if len(out) > maxDecompressedBody {
+		return nil, errors.New("decompressed response is too large")
+	}
+	return out, nil
+}
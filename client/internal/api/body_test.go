@@ -0,0 +1,58 @@
+package api
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func gzipBytes(t *testing.T, data []byte) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write(data); err != nil {
+		t.Fatal(err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestReadBody(t *testing.T) {
+	const doc = `{"servers":[]}`
+	tests := []struct {
+		name    string
+		body    []byte
+		want    string
+		wantErr string
+	}{
+		{"plain json", []byte(doc), doc, ""},
+		{"gzip without content-encoding", gzipBytes(t, []byte(doc)), doc, ""},
+		{"empty", nil, "", ""},
+		{"at the cap", gzipBytes(t, bytes.Repeat([]byte{' '}, maxDecompressedBody)), strings.Repeat(" ", maxDecompressedBody), ""},
+		{"over the cap", gzipBytes(t, bytes.Repeat([]byte{' '}, maxDecompressedBody+1)), "", "decompressed response is too large"},
+		{"corrupt gzip", append([]byte{0x1f, 0x8b}, "not gzip"...), "", "failed to decompress response"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := &http.Response{Body: io.NopCloser(bytes.NewReader(tt.body))}
+			got, err := readBody(resp)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("readBody() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("readBody() error = %v", err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("readBody() = %.40q (%d bytes), want %.40q (%d bytes)", got, len(got), tt.want, len(tt.want))
+			}
+		})
+	}
+}
@@ -3,11 +3,19 @@ package api
 
 import (
 	"bytes"
+	"context"
+	"crypto/tls"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"io"
+	"net"
 	"net/http"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
+
+	"github.com/AerNos/firefrp-client/internal/httputil"
 )
 
 // ValidateResponse is the response from the POST /api/v1/validate endpoint.
@@ -19,12 +27,114 @@ type ValidateResponse struct {
 
 // ValidateData contains the frps connection parameters returned on successful validation.
 type ValidateData struct {
-	FrpsAddr   string `json:"frps_addr"`
-	FrpsPort   int    `json:"frps_port"`
-	RemotePort int    `json:"remote_port"`
-	Token      string `json:"token"`
-	ProxyName  string `json:"proxy_name"`
-	ExpiresAt  string `json:"expires_at"`
+	FrpsAddr   string    `json:"frps_addr"`
+	FrpsPort   int       `json:"frps_port"`
+	FrpsAddrs  []string  `json:"frps_addrs,omitempty"`  // failover frps addresses ("host" or "host:port"); optional
+	PublicAddr string    `json:"public_addr,omitempty"` // shareable host; may differ from frps_addr
+	RemotePort int       `json:"remote_port"`
+	Token      string    `json:"token"`
+	AuthMethod string    `json:"auth_method,omitempty"` // "token" (default) or "oidc"
+	OIDC       *OIDCAuth `json:"oidc,omitempty"`        // required when auth_method is "oidc"
+	ProxyName  string    `json:"proxy_name"`
+	ExpiresAt  Timestamp `json:"expires_at"`
+	ServerTime string    `json:"server_time,omitempty"` // server clock when answering; optional
+	Motd       string    `json:"motd,omitempty"`        // operator message shown on connect; optional
+
+	receivedAt time.Time // local clock when the response arrived
+}
+
+// OIDCAuth holds the OIDC client-credentials settings frpc needs when frps
+// authenticates clients with OIDC.
+type OIDCAuth struct {
+	ClientID         string `json:"client_id"`
+	ClientSecret     string `json:"client_secret"`
+	Audience         string `json:"audience,omitempty"`
+	Scope            string `json:"scope,omitempty"`
+	TokenEndpointURL string `json:"token_endpoint_url"`
+}
+
+// ClockSkewWarning is the clock difference beyond which clients warn the
+// user that the local clock is off.
+const ClockSkewWarning = time.Minute
+
+// ClockSkew returns how far the server's clock is ahead of the local one
+// (negative if behind), judged from server_time and when the response
+// arrived. ok is false if the server sent no usable server_time.
+func (d *ValidateData) ClockSkew() (skew time.Duration, ok bool) {
+	if d.ServerTime == "" || d.receivedAt.IsZero() {
+		return 0, false
+	}
+	t, err := time.Parse(time.RFC3339, d.ServerTime)
+	if err != nil {
+		return 0, false
+	}
+	return t.Sub(d.receivedAt), true
+}
+
+// Expiry returns ExpiresAt expressed on the local clock: when the server
+// reported its time, the expiry is shifted by the clock skew, so comparing
+// it with time.Now() yields the true remaining time even on a machine with
+// a wrong clock.
+func (d *ValidateData) Expiry() (time.Time, error) {
+	t, err := ParseExpiry(string(d.ExpiresAt))
+	if err != nil {
+		return time.Time{}, err
+	}
+	if skew, ok := d.ClockSkew(); ok {
+		t = t.Add(-skew)
+	}
+	return t, nil
+}
+
+// AuditJSON returns the response data as indented JSON, so --audit can show
+// exactly what the server sent. The frps token and OIDC client secret are
+// masked unless showSecrets is set.
+func (d ValidateData) AuditJSON(showSecrets bool) string {
+	if !showSecrets {
+		if d.Token != "" {
+			d.Token = "(redacted)"
+		}
+		if d.OIDC != nil && d.OIDC.ClientSecret != "" {
+			oidc := *d.OIDC
+			oidc.ClientSecret = "(redacted)"
+			d.OIDC = &oidc
+		}
+	}
+	b, err := json.MarshalIndent(d, "", "  ")
+	if err != nil {
+		return err.Error()
+	}
+	return string(b)
+}
+
+// ValidRemotePort reports whether remote_port is a usable TCP port. The
+// client only maps TCP, so a zero or out-of-range port is a server bug
+// rather than a proxy type without ports.
+func (d *ValidateData) ValidRemotePort() bool {
+	return d.RemotePort >= 1 && d.RemotePort <= 65535
+}
+
+// FallbackEndpoints returns the failover frps endpoints from frps_addrs as
+// "host:port", in the server's order. Entries without a port use
+// frps_port; blanks and repeats of frps_addr are dropped.
+func (d *ValidateData) FallbackEndpoints() []string {
+	primary := net.JoinHostPort(d.FrpsAddr, strconv.Itoa(d.FrpsPort))
+	seen := map[string]bool{primary: true}
+	var endpoints []string
+	for _, addr := range d.FrpsAddrs {
+		addr = strings.TrimSpace(addr)
+		if addr == "" {
+			continue
+		}
+		if _, _, err := net.SplitHostPort(addr); err != nil {
+			addr = net.JoinHostPort(unbracketHost(addr), strconv.Itoa(d.FrpsPort))
+		}
+		if !seen[addr] {
+			seen[addr] = true
+			endpoints = append(endpoints, addr)
+		}
+	}
+	return endpoints
 }
 
 // ErrorInfo describes an error returned by the server.
@@ -33,67 +143,459 @@ type ErrorInfo struct {
 	Message string `json:"message"`
 }
 
+// Permanent reports whether the error means the key can never be used
+// again (revoked, expired, unknown or malformed), as opposed to a state
+// that may clear up once the server releases the key.
+func (e *ErrorInfo) Permanent() bool {
+	switch e.Code {
+	case "KEY_REVOKED", "KEY_NOT_FOUND", "KEY_EXPIRED", "KEY_INVALID":
+		return true
+	}
+	return false
+}
+
+// ErrRenewUnsupported is returned by Renew when the server has no renew
+// endpoint.
+var ErrRenewUnsupported = errors.New("server does not support key renewal")
+
+// ErrEmptyKey is returned by key requests given an empty access key, which
+// would otherwise vanish from the body (key is omitempty) or be sent as a
+// bare "Bearer " header.
+var ErrEmptyKey = errors.New("access key is empty")
+
+// ErrStatusUnsupported is returned by Status when the server has no status
+// endpoint; callers fall back to Validate.
+var ErrStatusUnsupported = errors.New("server does not support key status")
+
+// KeyStatus is the data of a GET /api/v1/status response.
+type KeyStatus struct {
+	// Active reports whether the server still considers the key's tunnel
+	// allocation in use, so it can be reused without validating again.
+	Active    bool      `json:"active"`
+	ExpiresAt Timestamp `json:"expires_at"`
+}
+
+// statusResponse is the response from the GET /api/v1/status endpoint.
+type statusResponse struct {
+	OK    bool       `json:"ok"`
+	Data  *KeyStatus `json:"data,omitempty"`
+	Error *ErrorInfo `json:"error,omitempty"`
+}
+
+// ErrNotJSON is returned when a server answers with something other than
+// JSON, typically an HTML login or error page from a reverse proxy when
+// the URL points at a website rather than the FireFrp API.
+var ErrNotJSON = errors.New("server returned a non-JSON response, check that the URL points at the FireFrp API")
+
+// notJSON reports whether a response with the given Content-Type and body
+// is HTML (or otherwise not JSON) rather than an API answer.
+func notJSON(contentType string, body []byte) bool {
+	if strings.Contains(strings.ToLower(contentType), "html") {
+		return true
+	}
+	trimmed := bytes.TrimSpace(body)
+	return len(trimmed) > 0 && trimmed[0] == '<'
+}
+
+// unbracketHost strips the brackets a server may put around an IPv6
+// literal ("[2001:db8::1]"); frpc and net.JoinHostPort want it bare.
+func unbracketHost(host string) string {
+	if strings.HasPrefix(host, "[") && strings.HasSuffix(host, "]") {
+		return host[1 : len(host)-1]
+	}
+	return host
+}
+
+// ErrRateLimited matches any RateLimitedError via errors.Is.
+var ErrRateLimited = errors.New("rate limited")
+
+// defaultRetryAfter is assumed when a 429 response carries no usable
+// Retry-After header.
+const defaultRetryAfter = 60 * time.Second
+
+// RateLimitedError is returned when the server answers 429 Too Many Requests.
+type RateLimitedError struct {
+	// RetryAfter is how long the server asked the client to wait.
+	RetryAfter time.Duration
+}
+
+func (e *RateLimitedError) Error() string {
+	return fmt.Sprintf("rate limited by server, retry after %s", e.RetryAfter)
+}
+
+// Is reports whether target is ErrRateLimited.
+func (e *RateLimitedError) Is(target error) bool {
+	return target == ErrRateLimited
+}
+
+// parseRetryAfter reads a Retry-After header value, which is either a
+// number of seconds or an HTTP date.
+func parseRetryAfter(v string) time.Duration {
+	if secs, err := strconv.Atoi(v); err == nil && secs >= 0 {
+		return time.Duration(secs) * time.Second
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		if d := time.Until(t); d > 0 {
+			return d
+		}
+		return 0
+	}
+	return defaultRetryAfter
+}
+
 // validateRequest is the request body for the validate endpoint.
 type validateRequest struct {
-	Key string `json:"key"`
+	Key       string `json:"key,omitempty"`
+	ProxyName string `json:"proxy_name,omitempty"`
 }
 
 // APIClient handles HTTP communication with the FireFrp management server.
 type APIClient struct {
-	baseURL    string
+	mu         sync.Mutex
+	baseURL    string // guarded by mu; changed by an API redirect
+	origURL    string // guarded by mu; base URL before a redirect, if any
 	httpClient *http.Client
+	proxyName  string // requested proxy name sent with validate, if any
+	keyIn      KeyPlacement
+
+	approveRedirect func(from, to string) bool // see WithRedirectApproval
+	unapproved      bool                       // guarded by mu; see checkOrigin
+}
+
+// KeyPlacement selects where key requests carry the access key.
+type KeyPlacement string
+
+const (
+	// KeyInBody sends the key in the JSON body (the default).
+	KeyInBody KeyPlacement = "body"
+	// KeyInHeader sends it only as "Authorization: Bearer <key>", for
+	// gateways that authenticate requests before they reach the server.
+	KeyInHeader KeyPlacement = "header"
+	// KeyInBoth sends it in the body and the header.
+	KeyInBoth KeyPlacement = "both"
+)
+
+// DefaultTimeout is the HTTP timeout used when no WithTimeout option is given.
+const DefaultTimeout = 15 * time.Second
+
+// Option configures optional APIClient behavior.
+type Option func(*APIClient)
+
+// WithTimeout sets the per-request HTTP timeout. Non-positive values are
+// ignored and the default is kept.
+func WithTimeout(d time.Duration) Option {
+	return func(c *APIClient) {
+		if d > 0 {
+			c.httpClient.Timeout = d
+		}
+	}
+}
+
+// WithProxyName asks the server to use name as the proxy name in validate
+// requests. Servers that don't permit client-chosen names ignore it and
+// assign one as usual; an empty name keeps the default behavior.
+func WithProxyName(name string) Option {
+	return func(c *APIClient) {
+		c.proxyName = name
+	}
+}
+
+// WithInsecureSkipVerify turns off TLS certificate verification for the
+// management API, for servers with self-signed certificates. It must only
+// be set on explicit user request (--insecure-skip-verify).
+func WithInsecureSkipVerify(skip bool) Option {
+	return func(c *APIClient) {
+		if !skip {
+			return
+		}
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+		transport.TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
+		c.httpClient.Transport = transport
+	}
+}
+
+// InsecureSkipVerify reports whether the client skips TLS certificate
+// verification. A nil client reports false.
+func (c *APIClient) InsecureSkipVerify() bool {
+	if c == nil {
+		return false
+	}
+	t, ok := c.httpClient.Transport.(*http.Transport)
+	return ok && t.TLSClientConfig != nil && t.TLSClientConfig.InsecureSkipVerify
+}
+
+// WithKeyPlacement sets where key requests (validate, renew) carry the
+// access key. An empty placement keeps the default, KeyInBody.
+func WithKeyPlacement(p KeyPlacement) Option {
+	return func(c *APIClient) {
+		if p != "" {
+			c.keyIn = p
+		}
+	}
 }
 
 // NewAPIClient creates a new APIClient with the given server base URL.
-func NewAPIClient(baseURL string) *APIClient {
-	return &APIClient{
+func NewAPIClient(baseURL string, opts ...Option) *APIClient {
+	c := &APIClient{
 		baseURL: baseURL,
 		httpClient: &http.Client{
-			Timeout: 15 * time.Second,
+			Timeout: DefaultTimeout,
 		},
+		keyIn: KeyInBody,
 	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
 }
 
 // Validate sends an access key to the server for validation and returns
 // the frps connection parameters on success.
 // Endpoint: POST /api/v1/validate
 func (c *APIClient) Validate(key string) (*ValidateResponse, error) {
-	reqBody := validateRequest{Key: key}
-	bodyBytes, err := json.Marshal(reqBody)
-	if err != nil {
-		return nil, fmt.Errorf("failed to marshal request body: %w", err)
+	return c.ValidateContext(context.Background(), key)
+}
+
+// ValidateContext is like Validate, but the request is aborted when ctx is
+// cancelled.
+func (c *APIClient) ValidateContext(ctx context.Context, key string) (*ValidateResponse, error) {
+	resp, _, err := c.postKey(ctx, "/api/v1/validate", validateRequest{Key: key, ProxyName: c.proxyName})
+	return resp, err
+}
+
+// Renew asks the server to extend the lease of a key whose tunnel is up.
+// On success Data.ExpiresAt holds the new expiry. The endpoint is optional:
+// servers without it answer 404, reported as ErrRenewUnsupported.
+// Endpoint: POST /api/v1/renew
+func (c *APIClient) Renew(key string) (*ValidateResponse, error) {
+	resp, status, err := c.postKey(context.Background(), "/api/v1/renew", validateRequest{Key: key})
+	if status == http.StatusNotFound && (resp == nil || resp.Error == nil || resp.Error.Code != "KEY_NOT_FOUND") {
+		return nil, ErrRenewUnsupported
 	}
+	return resp, err
+}
 
-	url := c.baseURL + "/api/v1/validate"
-	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(bodyBytes))
+// Status asks whether key is still active on the server, without
+// allocating anything or counting against its usage. The key is sent as
+// "Authorization: Bearer <key>". The endpoint is optional: servers without
+// it answer 404, reported as ErrStatusUnsupported.
+// Endpoint: GET /api/v1/status
+func (c *APIClient) Status(key string) (*KeyStatus, error) {
+	if err := c.checkOrigin(); err != nil {
+		return nil, err
+	}
+	if strings.TrimSpace(key) == "" {
+		return nil, ErrEmptyKey
+	}
+	url := c.BaseURL() + "/api/v1/status"
+	req, err := http.NewRequest(http.MethodGet, url, nil)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
-	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+key)
 
-	resp, err := c.httpClient.Do(req)
+	resp, err := httputil.DoWithRetry(c.httpClient, req, httputil.DefaultPolicy)
 	if err != nil {
 		return nil, fmt.Errorf("failed to send request to %s: %w", url, err)
 	}
 	defer resp.Body.Close()
 
-	respBody, err := io.ReadAll(resp.Body)
+	if resp.StatusCode == http.StatusTooManyRequests {
+		return nil, &RateLimitedError{RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After"))}
+	}
+	body, err := readBody(resp)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read response body: %w", err)
 	}
+	if to, ok := redirectTarget(body); ok {
+		if err := c.follow(to, true); err != nil {
+			return nil, err
+		}
+		return c.Status(key)
+	}
+	var result statusResponse
+	if err := json.Unmarshal(body, &result); err != nil {
+		if resp.StatusCode == http.StatusNotFound {
+			return nil, ErrStatusUnsupported
+		}
+		if notJSON(resp.Header.Get("Content-Type"), body) {
+			return nil, fmt.Errorf("%w (HTTP %d)", ErrNotJSON, resp.StatusCode)
+		}
+		return nil, fmt.Errorf("failed to parse response (status %d): %w", resp.StatusCode, err)
+	}
+	if resp.StatusCode == http.StatusNotFound && (result.Error == nil || result.Error.Code != "KEY_NOT_FOUND") {
+		return nil, ErrStatusUnsupported
+	}
+	if !result.OK || result.Data == nil {
+		if result.Error != nil {
+			return nil, fmt.Errorf("status failed [%s]: %s", result.Error.Code, result.Error.Message)
+		}
+		return nil, fmt.Errorf("status failed: HTTP %d", resp.StatusCode)
+	}
+	return result.Data, nil
+}
+
+// ErrIssueKeyUnsupported is returned by IssueKey when the server has no
+// key issuing endpoint.
+var ErrIssueKeyUnsupported = errors.New("server does not support issuing keys for account tokens")
+
+// ErrTokenExpired is returned by IssueKey when the account token has
+// expired; the user has to log in again for a new one. Other refusals
+// (unknown or revoked token) are reported as plain errors.
+var ErrTokenExpired = errors.New("account token expired")
+
+// issueKeyRequest is the request body for the issue-key endpoint.
+type issueKeyRequest struct {
+	LocalPort int `json:"local_port,omitempty"`
+}
+
+// issueKeyResponse is the response from the POST /api/v1/issue-key endpoint.
+type issueKeyResponse struct {
+	OK   bool `json:"ok"`
+	Data *struct {
+		Key string `json:"key"`
+	} `json:"data,omitempty"`
+	Error *ErrorInfo `json:"error,omitempty"`
+}
+
+// IssueKey exchanges a long-lived account token for a fresh ephemeral
+// access key, to be validated like a key the user typed. The token is sent
+// as "Authorization: Bearer <token>"; port is the local port about to be
+// mapped, for the server's records. The endpoint is optional: servers
+// without it answer 404, reported as ErrIssueKeyUnsupported.
+// Endpoint: POST /api/v1/issue-key
+func (c *APIClient) IssueKey(token string, port int) (string, error) {
+	if err := c.checkOrigin(); err != nil {
+		return "", err
+	}
+	bodyBytes, err := json.Marshal(issueKeyRequest{LocalPort: port})
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal request body: %w", err)
+	}
+	url := c.BaseURL() + "/api/v1/issue-key"
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(bodyBytes))
+	if err != nil {
+		return "", fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	resp, err := httputil.DoWithRetry(c.httpClient, req, httputil.DefaultPolicy)
+	if err != nil {
+		return "", fmt.Errorf("failed to send request to %s: %w", url, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusTooManyRequests {
+		return "", &RateLimitedError{RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After"))}
+	}
+	body, err := readBody(resp)
+	if err != nil {
+		return "", fmt.Errorf("failed to read response body: %w", err)
+	}
+	if to, ok := redirectTarget(body); ok {
+		if err := c.follow(to, true); err != nil {
+			return "", err
+		}
+		return c.IssueKey(token, port)
+	}
+	var result issueKeyResponse
+	if err := json.Unmarshal(body, &result); err != nil {
+		if resp.StatusCode == http.StatusNotFound {
+			return "", ErrIssueKeyUnsupported
+		}
+		if notJSON(resp.Header.Get("Content-Type"), body) {
+			return "", fmt.Errorf("%w (HTTP %d)", ErrNotJSON, resp.StatusCode)
+		}
+		return "", fmt.Errorf("failed to parse response (status %d): %w", resp.StatusCode, err)
+	}
+	if result.Error != nil {
+		if result.Error.Code == "TOKEN_EXPIRED" {
+			return "", ErrTokenExpired
+		}
+		return "", fmt.Errorf("issue key failed [%s]: %s", result.Error.Code, result.Error.Message)
+	}
+	if resp.StatusCode == http.StatusNotFound {
+		return "", ErrIssueKeyUnsupported
+	}
+	if !result.OK || result.Data == nil || result.Data.Key == "" {
+		return "", fmt.Errorf("issue key failed: HTTP %d", resp.StatusCode)
+	}
+	return result.Data.Key, nil
+}
+
+// postKey sends a key request to path and parses the validate-shaped
+// response. The HTTP status is returned alongside, even on error, once the
+// request got an answer.
+func (c *APIClient) postKey(ctx context.Context, path string, reqBody validateRequest) (*ValidateResponse, int, error) {
+	if err := c.checkOrigin(); err != nil {
+		return nil, 0, err
+	}
+	if strings.TrimSpace(reqBody.Key) == "" {
+		return nil, 0, ErrEmptyKey
+	}
+	key := reqBody.Key
+	sent := reqBody
+	if c.keyIn == KeyInHeader {
+		sent.Key = ""
+	}
+	bodyBytes, err := json.Marshal(sent)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to marshal request body: %w", err)
+	}
+
+	url := c.BaseURL() + path
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(bodyBytes))
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if c.keyIn == KeyInHeader || c.keyIn == KeyInBoth {
+		req.Header.Set("Authorization", "Bearer "+key)
+	}
+
+	resp, err := httputil.DoWithRetry(c.httpClient, req, httputil.DefaultPolicy)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to send request to %s: %w", url, err)
+	}
+	defer resp.Body.Close()
+	receivedAt := time.Now()
+
+	if resp.StatusCode == http.StatusTooManyRequests {
+		return nil, resp.StatusCode, &RateLimitedError{RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After"))}
+	}
+
+	respBody, err := readBody(resp)
+	if err != nil {
+		return nil, resp.StatusCode, fmt.Errorf("failed to read response body: %w", err)
+	}
+	if to, ok := redirectTarget(respBody); ok {
+		if err := c.follow(to, true); err != nil {
+			return nil, resp.StatusCode, err
+		}
+		return c.postKey(ctx, path, reqBody)
+	}
 
 	// Parse the response regardless of HTTP status code,
 	// since the server uses the JSON body to communicate errors.
 	var validateResp ValidateResponse
 	if err := json.Unmarshal(respBody, &validateResp); err != nil {
-		return nil, fmt.Errorf("failed to parse response (status %d): %w", resp.StatusCode, err)
+		if notJSON(resp.Header.Get("Content-Type"), respBody) {
+			return nil, resp.StatusCode, fmt.Errorf("%w (HTTP %d)", ErrNotJSON, resp.StatusCode)
+		}
+		return nil, resp.StatusCode, fmt.Errorf("failed to parse response (status %d): %w", resp.StatusCode, err)
 	}
 
 	// If HTTP status indicates a server error and the JSON body doesn't have error info,
 	// create a generic error.
 	if resp.StatusCode >= 500 && validateResp.Error == nil {
-		return nil, fmt.Errorf("server error: HTTP %d", resp.StatusCode)
+		return nil, resp.StatusCode, fmt.Errorf("server error: HTTP %d", resp.StatusCode)
+	}
+	if d := validateResp.Data; d != nil {
+		d.receivedAt = receivedAt
+		d.FrpsAddr = unbracketHost(d.FrpsAddr)
+		d.PublicAddr = unbracketHost(d.PublicAddr)
 	}
 
-	return &validateResp, nil
+	return &validateResp, resp.StatusCode, nil
 }
@@ -0,0 +1,154 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// keyRequest is what a test server saw of a key request.
+type keyRequest struct {
+	bodyKey string
+	hasKey  bool // the body had a "key" field at all
+	auth    string
+}
+
+// keyServer answers key requests with a bare success and records each
+// request's key placement.
+func keyServer(t *testing.T) (*httptest.Server, *[]keyRequest) {
+	t.Helper()
+	var seen []keyRequest
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]any
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Errorf("decode request body: %v", err)
+		}
+		key, hasKey := body["key"].(string)
+		seen = append(seen, keyRequest{bodyKey: key, hasKey: hasKey, auth: r.Header.Get("Authorization")})
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"ok":true,"data":{"frps_addr":"frps.example.com","frps_port":7000,"remote_port":30001,"token":"t","proxy_name":"ff-1-mc","expires_at":"2026-10-16T12:00:00Z"}}`))
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &seen
+}
+
+func TestKeyPlacement(t *testing.T) {
+	tests := []struct {
+		name      string
+		placement KeyPlacement
+		wantBody  bool
+		wantAuth  string
+	}{
+		{"default", "", true, ""},
+		{"body", KeyInBody, true, ""},
+		{"header", KeyInHeader, false, "Bearer ff-key"},
+		{"both", KeyInBoth, true, "Bearer ff-key"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv, seen := keyServer(t)
+			c := NewAPIClient(srv.URL, WithKeyPlacement(tt.placement))
+			for _, call := range []func(string) (*ValidateResponse, error){c.Validate, c.Renew} {
+				if _, err := call("ff-key"); err != nil {
+					t.Fatalf("request failed: %v", err)
+				}
+			}
+			if len(*seen) != 2 {
+				t.Fatalf("server saw %d requests, want 2", len(*seen))
+			}
+			for _, req := range *seen {
+				if req.hasKey != tt.wantBody || (tt.wantBody && req.bodyKey != "ff-key") {
+					t.Errorf("body key = %q (present %v), want present %v", req.bodyKey, req.hasKey, tt.wantBody)
+				}
+				if req.auth != tt.wantAuth {
+					t.Errorf("Authorization = %q, want %q", req.auth, tt.wantAuth)
+				}
+			}
+		})
+	}
+}
+
+func TestEmptyKeyNotSent(t *testing.T) {
+	for _, placement := range []KeyPlacement{KeyInBody, KeyInHeader, KeyInBoth} {
+		for _, key := range []string{"", "  "} {
+			t.Run(string(placement)+"/"+key, func(t *testing.T) {
+				srv, seen := keyServer(t)
+				c := NewAPIClient(srv.URL, WithKeyPlacement(placement))
+				if _, err := c.Validate(key); !errors.Is(err, ErrEmptyKey) {
+					t.Errorf("Validate(%q) error = %v, want ErrEmptyKey", key, err)
+				}
+				if _, err := c.Renew(key); !errors.Is(err, ErrEmptyKey) {
+					t.Errorf("Renew(%q) error = %v, want ErrEmptyKey", key, err)
+				}
+				if _, err := c.Status(key); !errors.Is(err, ErrEmptyKey) {
+					t.Errorf("Status(%q) error = %v, want ErrEmptyKey", key, err)
+				}
+				if len(*seen) != 0 {
+					t.Errorf("server saw %d requests, want none", len(*seen))
+				}
+			})
+		}
+	}
+}
+
+func TestNotJSON(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		want        bool
+	}{
+		{"html content type", "text/html; charset=utf-8", `{"ok":true}`, true},
+		{"html content type uppercase", "Text/HTML", "", true},
+		{"html body", "text/plain", "\n  <!DOCTYPE html><html></html>", true},
+		{"html body without content type", "", "<html>", true},
+		{"json", "application/json", `{"ok":false}`, false},
+		{"broken json", "application/json", `{"ok":`, false},
+		{"empty", "", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := notJSON(tt.contentType, []byte(tt.body)); got != tt.want {
+				t.Errorf("notJSON(%q, %q) = %v, want %v", tt.contentType, tt.body, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHTMLResponseIsNotJSON(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte("<!DOCTYPE html><html><body>Welcome to nginx!</body></html>"))
+	}))
+	defer srv.Close()
+
+	c := NewAPIClient(srv.URL)
+	if _, err := c.Validate("ff-key"); !errors.Is(err, ErrNotJSON) {
+		t.Errorf("Validate() error = %v, want ErrNotJSON", err)
+	}
+	if _, err := c.FetchServerInfo(); !errors.Is(err, ErrNotJSON) {
+		t.Errorf("FetchServerInfo() error = %v, want ErrNotJSON", err)
+	}
+}
+
+func TestValidRemotePort(t *testing.T) {
+	tests := []struct {
+		port int
+		want bool
+	}{
+		{-1, false},
+		{0, false},
+		{1, true},
+		{25565, true},
+		{65535, true},
+		{65536, false},
+	}
+	for _, tt := range tests {
+		d := &ValidateData{RemotePort: tt.port}
+		if got := d.ValidRemotePort(); got != tt.want {
+			t.Errorf("ValidRemotePort() with port %d = %v, want %v", tt.port, got, tt.want)
+		}
+	}
+}
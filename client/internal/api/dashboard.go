@@ -0,0 +1,25 @@
+package api
+
+import (
+	"net/url"
+	"strings"
+	"unicode"
+)
+
+// CleanDashboardURL checks a dashboard link from the server before it is
+// shown or opened: control characters are dropped, so the server can't
+// inject terminal escape sequences, and anything but an absolute http or
+// https URL with a host is rejected as "".
+func CleanDashboardURL(s string) string {
+	s = strings.TrimSpace(strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) {
+			return -1
+		}
+		return r
+	}, s))
+	u, err := url.Parse(s)
+	if err != nil || u.Host == "" || (u.Scheme != "http" && u.Scheme != "https") {
+		return ""
+	}
+	return u.String()
+}
@@ -1,11 +1,17 @@
 package api
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
-	"io"
 	"net/http"
+	neturl "net/url"
+	"os"
+	"path/filepath"
+	"strings"
 	"time"
+
+	"github.com/AerNos/firefrp-client/internal/httputil"
 )
 
 // ServerListEntry represents a single entry in the remote server list JSON.
@@ -24,7 +30,12 @@ type ServerInfo struct {
 	Description   string `json:"description"`
 	ClientVersion string `json:"client_version"`
 	UpdateChannel string `json:"update_channel"`
-	APIUrl        string `json:"-"` // set locally, not from JSON
+	FrpVersion    string `json:"frp_version,omitempty"`   // frps version; empty if not advertised
+	Motd          string `json:"motd,omitempty"`          // operator message shown on connect; optional
+	TosURL        string `json:"tos_url,omitempty"`       // terms of service users must accept; optional
+	TosVersion    string `json:"tos_version,omitempty"`   // version of those terms; a bump asks again
+	DashboardURL  string `json:"dashboard_url,omitempty"` // relay's web dashboard; optional
+	APIUrl        string `json:"-"`                       // set locally, not from JSON
 }
 
 // serverInfoResponse wraps the API response.
@@ -33,17 +44,74 @@ type serverInfoResponse struct {
 	Data *ServerInfo `json:"data,omitempty"`
 }
 
-// FetchServerList downloads and parses the server list JSON from the given URL.
-func FetchServerList(url string) ([]ServerListEntry, error) {
-	client := &http.Client{Timeout: 10 * time.Second}
+// DefaultProbeTimeout is the timeout used for discovery requests (server list
+// download and per-server probes) when none is configured.
+const DefaultProbeTimeout = 10 * time.Second
+
+// FetchServerList loads and parses the server list JSON. source is normally
+// an HTTP(S) URL; a file:// URL or a bare local path is read from disk,
+// which suits offline setups and testing. A non-positive timeout falls back
+// to DefaultProbeTimeout (it only applies to HTTP).
+//
+// For private lists, auth is sent verbatim as the Authorization header
+// (e.g. "Bearer <token>"); otherwise user:password in the URL is sent as
+// basic auth. Credentials never appear in returned errors.
+func FetchServerList(source, auth string, timeout time.Duration) ([]ServerListEntry, error) {
+	var body []byte
+	var err error
+	if path, ok := localServerListPath(source); ok {
+		body, err = os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read server list file: %w", err)
+		}
+	} else {
+		body, err = downloadServerList(source, auth, timeout)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var entries []ServerListEntry
+	if err := json.Unmarshal(body, &entries); err != nil {
+		if notJSON("", body) {
+			return nil, fmt.Errorf("server list: %w", ErrNotJSON)
+		}
+		return nil, fmt.Errorf("failed to parse server list JSON: %w", err)
+	}
+
+	return entries, nil
+}
+
+// downloadServerList fetches the server list body over HTTP(S), sending
+// auth or the URL's user info as credentials.
+func downloadServerList(url, auth string, timeout time.Duration) ([]byte, error) {
+	if timeout <= 0 {
+		timeout = DefaultProbeTimeout
+	}
+	client := &http.Client{Timeout: timeout}
 
-	resp, err := client.Get(url)
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		// The parse error quotes the raw URL, which may hold a password.
+		return nil, fmt.Errorf("invalid server list URL: %s", RedactURL(url))
+	}
+	if auth != "" {
+		req.Header.Set("Authorization", auth)
+	} else if u := req.URL.User; u != nil {
+		password, _ := u.Password()
+		req.SetBasicAuth(u.Username(), password)
+	}
+	// Credentials travel in the header only, keeping them out of the URL
+	// that transport errors quote.
+	req.URL.User = nil
+
+	resp, err := httputil.DoWithRetry(client, req, httputil.DefaultPolicy)
 	if err != nil {
 		return nil, fmt.Errorf("failed to fetch server list: %w", err)
 	}
 	defer resp.Body.Close()
 
-	body, err := io.ReadAll(resp.Body)
+	body, err := readBody(resp)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read server list response: %w", err)
 	}
@@ -51,44 +119,104 @@ func FetchServerList(url string) ([]ServerListEntry, error) {
 	if resp.StatusCode != http.StatusOK {
 		return nil, fmt.Errorf("server list returned HTTP %d", resp.StatusCode)
 	}
+	return body, nil
+}
+
+// RedactURL returns rawURL with any password in its user info masked, for
+// display in logs and diagnostics. Unparseable input is hidden entirely.
+func RedactURL(rawURL string) string {
+	u, err := neturl.Parse(rawURL)
+	if err != nil {
+		return "<invalid URL>"
+	}
+	return u.Redacted()
+}
 
-	var entries []ServerListEntry
-	if err := json.Unmarshal(body, &entries); err != nil {
-		return nil, fmt.Errorf("failed to parse server list JSON: %w", err)
+// localServerListPath reports whether source names a local file (a file://
+// URL or a path without a URL scheme) and returns its filesystem path.
+func localServerListPath(source string) (string, bool) {
+	if !strings.Contains(source, "://") {
+		return source, true
+	}
+	u, err := neturl.Parse(source)
+	if err != nil || u.Scheme != "file" {
+		return "", false
+	}
+	path := u.Path
+	// file:///C:/servers.json parses to "/C:/servers.json" on Windows.
+	if len(path) >= 3 && path[0] == '/' && path[2] == ':' {
+		path = path[1:]
 	}
+	return filepath.FromSlash(path), true
+}
 
-	return entries, nil
+// bareServerInfo decodes a ServerInfo sent without the {ok, data}
+// envelope, as some simpler self-hosted servers do. Bodies that carry
+// envelope fields, or name no server, don't qualify.
+func bareServerInfo(body []byte) (*ServerInfo, bool) {
+	var envelope struct {
+		OK   *bool           `json:"ok"`
+		Data json.RawMessage `json:"data"`
+	}
+	if json.Unmarshal(body, &envelope) != nil || envelope.OK != nil || envelope.Data != nil {
+		return nil, false
+	}
+	var info ServerInfo
+	if json.Unmarshal(body, &info) != nil || (info.ID == "" && info.Name == "") {
+		return nil, false
+	}
+	return &info, true
 }
 
 // FetchServerInfo queries the server's /api/v1/server-info endpoint
 // and returns the server's self-configuration.
 func (c *APIClient) FetchServerInfo() (*ServerInfo, error) {
-	url := c.baseURL + "/api/v1/server-info"
+	return c.FetchServerInfoContext(context.Background())
+}
 
-	req, err := http.NewRequest(http.MethodGet, url, nil)
+// FetchServerInfoContext is like FetchServerInfo, but the request (and any
+// retry) is aborted when ctx is cancelled.
+func (c *APIClient) FetchServerInfoContext(ctx context.Context) (*ServerInfo, error) {
+	url := c.BaseURL() + "/api/v1/server-info"
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
 
-	resp, err := c.httpClient.Do(req)
+	resp, err := httputil.DoWithRetry(c.httpClient, req, httputil.DefaultPolicy)
 	if err != nil {
 		return nil, fmt.Errorf("failed to fetch server info from %s: %w", url, err)
 	}
 	defer resp.Body.Close()
 
-	body, err := io.ReadAll(resp.Body)
+	body, err := readBody(resp)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read server info response: %w", err)
 	}
+	if to, ok := redirectTarget(body); ok {
+		if err := c.follow(to, false); err != nil {
+			return nil, err
+		}
+		return c.FetchServerInfoContext(ctx)
+	}
 
 	var result serverInfoResponse
 	if err := json.Unmarshal(body, &result); err != nil {
+		if notJSON(resp.Header.Get("Content-Type"), body) {
+			return nil, fmt.Errorf("%w (HTTP %d)", ErrNotJSON, resp.StatusCode)
+		}
 		return nil, fmt.Errorf("failed to parse server info JSON: %w", err)
 	}
 
 	if !result.OK || result.Data == nil {
-		return nil, fmt.Errorf("server info response not ok")
+		info, ok := bareServerInfo(body)
+		if !ok {
+			return nil, fmt.Errorf("server info response not ok")
+		}
+		result.Data = info
 	}
+	result.Data.PublicAddr = unbracketHost(result.Data.PublicAddr)
 
 	return result.Data, nil
 }
@@ -0,0 +1,73 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestFetchServerInfoShapes(t *testing.T) {
+	tests := []struct {
+		name       string
+		body       string
+		wantName   string
+		wantPublic string
+		wantErr    bool
+	}{
+		{
+			name:     "envelope",
+			body:     `{"ok":true,"data":{"id":"cn-1","name":"Shanghai","public_addr":"mc.example.com"}}`,
+			wantName: "Shanghai", wantPublic: "mc.example.com",
+		},
+		{
+			name:     "bare",
+			body:     `{"id":"cn-1","name":"Shanghai","public_addr":"[2001:db8::1]"}`,
+			wantName: "Shanghai", wantPublic: "2001:db8::1",
+		},
+		{
+			name:     "bare with id only",
+			body:     `{"id":"cn-1"}`,
+			wantName: "",
+		},
+		{
+			name:    "envelope not ok",
+			body:    `{"ok":false,"error":{"code":"INTERNAL","message":"boom"}}`,
+			wantErr: true,
+		},
+		{
+			name:    "envelope without data",
+			body:    `{"ok":true}`,
+			wantErr: true,
+		},
+		{
+			name:    "data only",
+			body:    `{"data":null,"name":"Shanghai"}`,
+			wantErr: true,
+		},
+		{
+			name:    "names no server",
+			body:    `{"description":"hello"}`,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				w.Write([]byte(tt.body))
+			}))
+			defer srv.Close()
+
+			info, err := NewAPIClient(srv.URL).FetchServerInfo()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("FetchServerInfo() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if info.Name != tt.wantName || info.PublicAddr != tt.wantPublic {
+				t.Errorf("FetchServerInfo() = name %q public %q, want %q %q", info.Name, info.PublicAddr, tt.wantName, tt.wantPublic)
+			}
+		})
+	}
+}
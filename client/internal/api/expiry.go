@@ -0,0 +1,58 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Timestamp is a time as sent by the server: usually an RFC 3339 string,
+// but some servers send Unix time as a JSON number. Numbers are kept as
+// their decimal text so ParseExpiry can interpret them.
+type Timestamp string
+
+// UnmarshalJSON accepts a JSON string, number or null.
+func (t *Timestamp) UnmarshalJSON(b []byte) error {
+	b = bytes.TrimSpace(b)
+	switch {
+	case bytes.Equal(b, []byte("null")):
+		*t = ""
+	case len(b) > 0 && b[0] == '"':
+		var s string
+		if err := json.Unmarshal(b, &s); err != nil {
+			return err
+		}
+		*t = Timestamp(s)
+	default:
+		var n json.Number
+		if err := json.Unmarshal(b, &n); err != nil {
+			return fmt.Errorf("timestamp must be a string or number: %w", err)
+		}
+		*t = Timestamp(n.String())
+	}
+	return nil
+}
+
+// unixMillisThreshold separates Unix seconds from milliseconds: seconds
+// won't reach 1e12 until the year 33658, milliseconds passed it in 2001.
+const unixMillisThreshold = 1e12
+
+// ParseExpiry parses an expiry time in any format servers are known to
+// send: RFC 3339 (with or without fractional seconds) or Unix time in
+// seconds or milliseconds.
+func ParseExpiry(s string) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	if t, err := time.Parse(time.RFC3339Nano, s); err == nil {
+		return t, nil
+	}
+	if n, err := strconv.ParseInt(s, 10, 64); err == nil && n > 0 {
+		if n >= unixMillisThreshold {
+			return time.UnixMilli(n), nil
+		}
+		return time.Unix(n, 0), nil
+	}
+	return time.Time{}, fmt.Errorf("unrecognized expiry time %q (expected RFC 3339 or Unix seconds/milliseconds)", s)
+}
@@ -0,0 +1,66 @@
+package api
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestParseExpiry(t *testing.T) {
+	tests := []struct {
+		name    string
+		in      string
+		want    time.Time
+		wantErr bool
+	}{
+		{"rfc3339", "2026-10-16T12:00:00Z", time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC), false},
+		{"rfc3339 fractional", "2026-10-16T12:00:00.250Z", time.Date(2026, 10, 16, 12, 0, 0, 250e6, time.UTC), false},
+		{"rfc3339 offset", "2026-10-16T20:00:00+08:00", time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC), false},
+		{"unix seconds", "1792152000", time.Unix(1792152000, 0), false},
+		{"unix millis", "1792152000123", time.UnixMilli(1792152000123), false},
+		{"seconds below threshold", "999999999999", time.Unix(999999999999, 0), false},
+		{"millis at threshold", "1000000000000", time.UnixMilli(1e12), false},
+		{"surrounding space", " 1792152000 ", time.Unix(1792152000, 0), false},
+		{"empty", "", time.Time{}, true},
+		{"zero", "0", time.Time{}, true},
+		{"negative", "-5", time.Time{}, true},
+		{"garbage", "tomorrow", time.Time{}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseExpiry(tt.in)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseExpiry(%q) error = %v, wantErr %v", tt.in, err, tt.wantErr)
+			}
+			if !got.Equal(tt.want) {
+				t.Errorf("ParseExpiry(%q) = %v, want %v", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTimestampUnmarshalJSON(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    Timestamp
+		wantErr bool
+	}{
+		{`"2026-10-16T12:00:00Z"`, "2026-10-16T12:00:00Z", false},
+		{`1792152000`, "1792152000", false},
+		{`1792152000123`, "1792152000123", false},
+		{`null`, "", false},
+		{`true`, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			var got Timestamp
+			err := json.Unmarshal([]byte(tt.in), &got)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Unmarshal(%s) error = %v, wantErr %v", tt.in, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("Unmarshal(%s) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
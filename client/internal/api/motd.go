@@ -0,0 +1,23 @@
+package api
+
+import (
+	"strings"
+	"unicode"
+)
+
+// CleanMotd prepares an operator message from the server for display: it
+// drops control characters other than newlines (tabs become spaces), so the
+// server can't inject terminal escape sequences, and trims surrounding
+// blank space.
+func CleanMotd(s string) string {
+	s = strings.Map(func(r rune) rune {
+		switch {
+		case r == '\t':
+			return ' '
+		case r == '\n' || !unicode.IsControl(r):
+			return r
+		}
+		return -1
+	}, s)
+	return strings.TrimSpace(s)
+}
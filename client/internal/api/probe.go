@@ -0,0 +1,148 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrProbeTimeout marks servers whose probe was still pending when the
+// overall probe deadline passed.
+var ErrProbeTimeout = errors.New("probe timed out")
+
+// ErrNotProbed marks servers whose probe never started because the
+// overall probe deadline passed while they waited for a free slot.
+var ErrNotProbed = errors.New("not probed")
+
+// DefaultProbeConcurrency caps how many servers are probed at once when
+// no limit is given, so a very large list doesn't open hundreds of
+// connections or trip the relays' rate limits.
+const DefaultProbeConcurrency = 8
+
+// ProbeResult is the outcome of probing one server's server-info endpoint.
+type ProbeResult struct {
+	Index   int // position of APIUrl in the probed list
+	APIUrl  string
+	Info    *ServerInfo   // nil if the probe failed
+	Latency time.Duration // round trip of the probe, retries included
+	Err     error         // non-nil if the server is unreachable
+}
+
+// ProbeDeadline is the overall time allowed for probing a list when each
+// request is bounded by timeout: enough for one retry, so a single hung
+// server can't stall discovery for longer.
+func ProbeDeadline(timeout time.Duration) time.Duration {
+	return 2 * timeout
+}
+
+// StreamProbes queries the server-info endpoint of every API URL with at
+// most concurrency requests in flight (DefaultProbeConcurrency if not
+// positive), bounding each request by timeout. Results are sent as they
+// complete and the channel is closed once all are in. When ctx ends
+// first, servers still pending are reported right away: with
+// ErrProbeTimeout if their probe had started, else with ErrNotProbed.
+func StreamProbes(ctx context.Context, urls []string, timeout time.Duration, concurrency int, opts ...Option) <-chan ProbeResult {
+	out := make(chan ProbeResult, len(urls))
+	jobs := make(chan int)
+	done := make([]bool, len(urls))
+	var mu sync.Mutex // guards done and sending to out
+
+	report := func(r ProbeResult) {
+		mu.Lock()
+		defer mu.Unlock()
+		if !done[r.Index] {
+			done[r.Index] = true
+			out <- r
+		}
+	}
+
+	if concurrency <= 0 {
+		concurrency = DefaultProbeConcurrency
+	}
+	workers := min(len(urls), concurrency)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for idx := range jobs {
+				apiUrl := urls[idx]
+				client := NewAPIClient(apiUrl, append([]Option{WithTimeout(timeout)}, opts...)...)
+				start := time.Now()
+				info, err := client.FetchServerInfoContext(ctx)
+				if ctx.Err() != nil {
+					err = ErrProbeTimeout
+				}
+				if info != nil {
+					info.APIUrl = apiUrl
+				}
+				report(ProbeResult{Index: idx, APIUrl: apiUrl, Info: info, Latency: time.Since(start), Err: err})
+			}
+		}()
+	}
+
+	go func() {
+		dispatched := 0
+	feed:
+		for i := range urls {
+			select {
+			case jobs <- i:
+				dispatched++
+			case <-ctx.Done():
+				break feed
+			}
+		}
+		close(jobs)
+
+		finished := make(chan struct{})
+		go func() {
+			wg.Wait()
+			close(finished)
+		}()
+		select {
+		case <-finished:
+		case <-ctx.Done():
+		}
+		// Whatever hasn't reported by now timed out or never started.
+		for i, apiUrl := range urls {
+			err := ErrProbeTimeout
+			if i >= dispatched {
+				err = ErrNotProbed
+			}
+			report(ProbeResult{Index: i, APIUrl: apiUrl, Err: err})
+		}
+		close(out)
+	}()
+	return out
+}
+
+// ProbeServers probes every API URL like StreamProbes, within
+// ProbeDeadline(timeout) overall, and returns the results in the order of
+// urls.
+func ProbeServers(urls []string, timeout time.Duration, concurrency int, opts ...Option) []ProbeResult {
+	ctx, cancel := context.WithTimeout(context.Background(), ProbeDeadline(timeout))
+	defer cancel()
+	results := make([]ProbeResult, len(urls))
+	for r := range StreamProbes(ctx, urls, timeout, concurrency, opts...) {
+		results[r.Index] = r
+	}
+	return results
+}
+
+// Fastest returns the reachable server that answered its probe quickest,
+// or false if none was reachable.
+func Fastest(results []ProbeResult) (ProbeResult, bool) {
+	var best ProbeResult
+	found := false
+	for _, r := range results {
+		if r.Err != nil || r.Info == nil {
+			continue
+		}
+		if !found || r.Latency < best.Latency {
+			best = r
+			found = true
+		}
+	}
+	return best, found
+}
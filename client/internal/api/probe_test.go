@@ -0,0 +1,152 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// probeServer answers server-info for any path prefix after delay, and
+// records the peak number of requests in flight.
+func probeServer(t *testing.T, delay time.Duration) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+	var inflight, peak atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := inflight.Add(1)
+		defer inflight.Add(-1)
+		for {
+			p := peak.Load()
+			if n <= p || peak.CompareAndSwap(p, n) {
+				break
+			}
+		}
+		if !strings.HasSuffix(r.URL.Path, "/api/v1/server-info") {
+			http.NotFound(w, r)
+			return
+		}
+		select {
+		case <-time.After(delay):
+		case <-r.Context().Done():
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"ok":true,"data":{"id":"s","name":"Test"}}`)
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &peak
+}
+
+// probeURLs returns n distinct API URLs on srv.
+func probeURLs(srv *httptest.Server, n int) []string {
+	urls := make([]string, n)
+	for i := range urls {
+		urls[i] = fmt.Sprintf("%s/s%d", srv.URL, i)
+	}
+	return urls
+}
+
+func TestStreamProbesConcurrency(t *testing.T) {
+	tests := []struct {
+		name        string
+		urls        int
+		concurrency int
+		wantPeak    int32
+	}{
+		{"limited", 10, 3, 3},
+		{"one at a time", 4, 1, 1},
+		{"default", 12, 0, DefaultProbeConcurrency},
+		{"fewer urls than slots", 2, 5, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv, peak := probeServer(t, 30*time.Millisecond)
+			urls := probeURLs(srv, tt.urls)
+			seen := make(map[int]bool)
+			for r := range StreamProbes(context.Background(), urls, time.Second, tt.concurrency) {
+				if r.Err != nil {
+					t.Errorf("probe %d: %v", r.Index, r.Err)
+				}
+				if r.Info == nil || r.Info.APIUrl != urls[r.Index] {
+					t.Errorf("probe %d: info = %+v, want APIUrl %s", r.Index, r.Info, urls[r.Index])
+				}
+				seen[r.Index] = true
+			}
+			if len(seen) != tt.urls {
+				t.Errorf("got %d results, want %d", len(seen), tt.urls)
+			}
+			if got := peak.Load(); got > tt.wantPeak {
+				t.Errorf("peak concurrency = %d, want at most %d", got, tt.wantPeak)
+			}
+		})
+	}
+}
+
+func TestStreamProbesDeadline(t *testing.T) {
+	srv, _ := probeServer(t, time.Minute)
+	urls := probeURLs(srv, 30)
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	results := make([]ProbeResult, 0, len(urls))
+	for r := range StreamProbes(ctx, urls, time.Minute, 2) {
+		results = append(results, r)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Fatalf("StreamProbes took %v after a 100ms deadline", elapsed)
+	}
+	if len(results) != len(urls) {
+		t.Fatalf("got %d results, want %d", len(results), len(urls))
+	}
+
+	notProbed := 0
+	for _, r := range results {
+		switch {
+		case r.Index < 2 && !errors.Is(r.Err, ErrProbeTimeout):
+			t.Errorf("probe %d: err = %v, want ErrProbeTimeout", r.Index, r.Err)
+		case errors.Is(r.Err, ErrNotProbed):
+			notProbed++
+		case !errors.Is(r.Err, ErrProbeTimeout):
+			t.Errorf("probe %d: err = %v, want ErrProbeTimeout or ErrNotProbed", r.Index, r.Err)
+		}
+	}
+	if notProbed == 0 {
+		t.Error("no server was reported as not probed")
+	}
+}
+
+func TestFastest(t *testing.T) {
+	info := &ServerInfo{}
+	tests := []struct {
+		name    string
+		results []ProbeResult
+		want    int
+		wantOK  bool
+	}{
+		{"none", nil, 0, false},
+		{"all failed", []ProbeResult{{Index: 0, Err: ErrProbeTimeout}, {Index: 1, Err: ErrNotProbed}}, 0, false},
+		{"quickest wins", []ProbeResult{
+			{Index: 0, Info: info, Latency: 80 * time.Millisecond},
+			{Index: 1, Info: info, Latency: 20 * time.Millisecond},
+			{Index: 2, Info: info, Latency: 50 * time.Millisecond},
+		}, 1, true},
+		{"failures skipped", []ProbeResult{
+			{Index: 0, Err: ErrProbeTimeout, Latency: time.Millisecond},
+			{Index: 1, Info: info, Latency: 40 * time.Millisecond},
+		}, 1, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := Fastest(tt.results)
+			if ok != tt.wantOK || (ok && got.Index != tt.want) {
+				t.Errorf("Fastest() = %d, %v; want %d, %v", got.Index, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
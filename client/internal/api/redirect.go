@@ -0,0 +1,159 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// ErrRedirectLoop is returned when the server moves the API again after
+// the client already followed one redirect.
+var ErrRedirectLoop = errors.New("server redirected the API more than once")
+
+// ErrCrossOriginRedirect matches any CrossOriginRedirectError via
+// errors.Is.
+var ErrCrossOriginRedirect = errors.New("server redirected the API to another host")
+
+// CrossOriginRedirectError is returned when the server moves the API to
+// another scheme, host or port while the request carries a key or token,
+// and the move wasn't approved (see WithRedirectApproval).
+type CrossOriginRedirectError struct {
+	To string // the new base URL
+}
+
+func (e *CrossOriginRedirectError) Error() string {
+	return ErrCrossOriginRedirect.Error() + ": " + RedactURL(e.To)
+}
+
+// Is reports whether target is ErrCrossOriginRedirect.
+func (e *CrossOriginRedirectError) Is(target error) bool {
+	return target == ErrCrossOriginRedirect
+}
+
+// WithRedirectApproval sets the function asked before a request carrying
+// a key or token follows an API redirect to another origin; from and to
+// are the base URLs. Without one such redirects are refused with
+// ErrCrossOriginRedirect, so credentials only go where the user sent them.
+func WithRedirectApproval(approve func(from, to string) bool) Option {
+	return func(c *APIClient) {
+		c.approveRedirect = approve
+	}
+}
+
+// apiRedirect is what a relay answers any API request with after moving
+// its API, e.g. {"redirect": "https://new.example.com:9001"}. This is an
+// API-level redirect, separate from HTTP 3xx, which would turn POSTs into
+// GETs.
+type apiRedirect struct {
+	Redirect string          `json:"redirect"`
+	OK       bool            `json:"ok"`
+	Data     json.RawMessage `json:"data"`
+}
+
+// redirectTarget returns the new base URL if body is an API redirect.
+func redirectTarget(body []byte) (string, bool) {
+	var r apiRedirect
+	if err := json.Unmarshal(body, &r); err != nil {
+		return "", false
+	}
+	if r.Redirect == "" || r.OK || r.Data != nil {
+		return "", false
+	}
+	return r.Redirect, true
+}
+
+// BaseURL returns the API base URL in use: the one the client was created
+// with, or where the server redirected it.
+func (c *APIClient) BaseURL() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.baseURL
+}
+
+// Redirected reports whether the server moved the API, and from where,
+// so callers can log it. Saved server entries keep the original URL.
+func (c *APIClient) Redirected() (from string, ok bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.origURL, c.origURL != ""
+}
+
+// follow switches the client to the base URL the server redirected to.
+// Only one hop is followed per client, and never from HTTPS to plain
+// HTTP, so a misconfigured pair of relays can't bounce requests forever
+// or strip TLS. credentials marks a request carrying a key or token,
+// which only moves to another origin with the user's approval.
+func (c *APIClient) follow(to string, credentials bool) error {
+	from := c.BaseURL()
+	if _, ok := c.Redirected(); ok {
+		return fmt.Errorf("%w (%s -> %s)", ErrRedirectLoop, RedactURL(from), RedactURL(to))
+	}
+	u, err := url.Parse(to)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("server redirected the API to an invalid URL: %q", RedactURL(to))
+	}
+	cur, err := url.Parse(from)
+	if err != nil {
+		return fmt.Errorf("invalid API base URL: %w", err)
+	}
+	if cur.Scheme == "https" && u.Scheme != "https" {
+		return fmt.Errorf("refusing API redirect from HTTPS to %s", RedactURL(to))
+	}
+	to = strings.TrimRight(to, "/")
+	cross := !sameOrigin(cur, u)
+	if credentials && cross && !c.approve(from, to) {
+		return &CrossOriginRedirectError{To: to}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.origURL = c.baseURL
+	c.baseURL = to
+	// A request without credentials moved freely; the first one with
+	// credentials still needs approval (see checkOrigin).
+	c.unapproved = cross && !credentials
+	return nil
+}
+
+// checkOrigin is called before a request carrying a key or token. If an
+// earlier request without credentials followed a redirect to another
+// origin, the move is approved now or the request is refused.
+func (c *APIClient) checkOrigin() error {
+	c.mu.Lock()
+	from, to, unapproved := c.origURL, c.baseURL, c.unapproved
+	c.mu.Unlock()
+	if !unapproved {
+		return nil
+	}
+	if !c.approve(from, to) {
+		return &CrossOriginRedirectError{To: to}
+	}
+	c.mu.Lock()
+	c.unapproved = false
+	c.mu.Unlock()
+	return nil
+}
+
+// approve asks the WithRedirectApproval function, if any, whether
+// credentials may follow the API from one origin to another.
+func (c *APIClient) approve(from, to string) bool {
+	return c.approveRedirect != nil && c.approveRedirect(from, to)
+}
+
+// sameOrigin reports whether a and b share scheme, host and port.
+func sameOrigin(a, b *url.URL) bool {
+	return a.Scheme == b.Scheme && strings.EqualFold(a.Hostname(), b.Hostname()) && originPort(a) == originPort(b)
+}
+
+// originPort returns u's port, or the scheme's default port.
+func originPort(u *url.URL) string {
+	if p := u.Port(); p != "" {
+		return p
+	}
+	if u.Scheme == "https" {
+		return "443"
+	}
+	return "80"
+}
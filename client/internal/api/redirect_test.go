@@ -0,0 +1,194 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// redirectServer answers every request with an API redirect to to().
+func redirectServer(t *testing.T, tls bool, to func() string) *httptest.Server {
+	t.Helper()
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"redirect":"` + to() + `"}`))
+	})
+	var srv *httptest.Server
+	if tls {
+		srv = httptest.NewTLSServer(h)
+	} else {
+		srv = httptest.NewServer(h)
+	}
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestRedirectTarget(t *testing.T) {
+	tests := []struct {
+		body   string
+		want   string
+		wantOK bool
+	}{
+		{`{"redirect":"https://new.example.com:9001"}`, "https://new.example.com:9001", true},
+		{`{"redirect":""}`, "", false},
+		{`{"ok":true,"redirect":"https://new.example.com"}`, "", false},
+		{`{"redirect":"https://new.example.com","data":{}}`, "", false},
+		{`{"ok":false,"error":{"code":"x"}}`, "", false},
+		{`not json`, "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.body, func(t *testing.T) {
+			got, ok := redirectTarget([]byte(tt.body))
+			if got != tt.want || ok != tt.wantOK {
+				t.Errorf("redirectTarget() = %q, %v; want %q, %v", got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestValidateRedirect(t *testing.T) {
+	tests := []struct {
+		name    string
+		approve func(from, to string) bool
+		// hops is the number of redirecting servers in front of the
+		// key server.
+		hops      int
+		wantErr   error
+		wantSeen  int // requests that reached the key server
+		wantAsked bool
+	}{
+		{"cross origin refused without approval", nil, 1, ErrCrossOriginRedirect, 0, false},
+		{"cross origin denied", func(string, string) bool { return false }, 1, ErrCrossOriginRedirect, 0, true},
+		{"cross origin approved", func(string, string) bool { return true }, 1, nil, 1, true},
+		{"second hop", func(string, string) bool { return true }, 2, ErrRedirectLoop, 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			target, seen := keyServer(t)
+			next := target.URL
+			for i := 0; i < tt.hops; i++ {
+				to := next
+				next = redirectServer(t, false, func() string { return to }).URL
+			}
+
+			asked := false
+			var opts []Option
+			if tt.approve != nil {
+				opts = append(opts, WithRedirectApproval(func(from, to string) bool {
+					asked = true
+					return tt.approve(from, to)
+				}))
+			}
+			c := NewAPIClient(next, opts...)
+			_, err := c.Validate("ff-key")
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Validate() error = %v, want %v", err, tt.wantErr)
+			}
+			if len(*seen) != tt.wantSeen {
+				t.Errorf("key server saw %d requests, want %d", len(*seen), tt.wantSeen)
+			}
+			if asked != tt.wantAsked {
+				t.Errorf("approval asked = %v, want %v", asked, tt.wantAsked)
+			}
+			if tt.wantErr == nil {
+				if c.BaseURL() != target.URL {
+					t.Errorf("BaseURL() = %q, want %q", c.BaseURL(), target.URL)
+				}
+				if from, ok := c.Redirected(); !ok || from != next {
+					t.Errorf("Redirected() = %q, %v; want %q, true", from, ok, next)
+				}
+			}
+			var cross *CrossOriginRedirectError
+			if errors.As(err, &cross) && cross.To != target.URL {
+				t.Errorf("CrossOriginRedirectError.To = %q, want %q", cross.To, target.URL)
+			}
+		})
+	}
+}
+
+func TestRedirectSameOrigin(t *testing.T) {
+	var srv *httptest.Server
+	srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if !strings.HasPrefix(r.URL.Path, "/v2/") {
+			w.Write([]byte(`{"redirect":"` + srv.URL + `/v2/"}`))
+			return
+		}
+		w.Write([]byte(`{"ok":true,"data":{"frps_addr":"frps.example.com","frps_port":7000,"remote_port":30001,"token":"t","proxy_name":"ff-1-mc","expires_at":"2026-10-16T12:00:00Z"}}`))
+	}))
+	defer srv.Close()
+
+	c := NewAPIClient(srv.URL)
+	if _, err := c.Validate("ff-key"); err != nil {
+		t.Fatalf("Validate() error = %v", err)
+	}
+	if want := srv.URL + "/v2"; c.BaseURL() != want {
+		t.Errorf("BaseURL() = %q, want %q", c.BaseURL(), want)
+	}
+}
+
+func TestRedirectHTTPSToHTTP(t *testing.T) {
+	target, seen := keyServer(t)
+	src := redirectServer(t, true, func() string { return target.URL })
+
+	c := NewAPIClient(src.URL, WithInsecureSkipVerify(true), WithRedirectApproval(func(string, string) bool { return true }))
+	_, err := c.Validate("ff-key")
+	if err == nil || !strings.Contains(err.Error(), "refusing API redirect from HTTPS") {
+		t.Fatalf("Validate() error = %v, want an HTTPS downgrade refusal", err)
+	}
+	if len(*seen) != 0 {
+		t.Errorf("key server saw %d requests, want 0", len(*seen))
+	}
+}
+
+func TestRedirectInvalidURL(t *testing.T) {
+	for _, to := range []string{"ftp://new.example.com", "new.example.com", "https://"} {
+		t.Run(to, func(t *testing.T) {
+			src := redirectServer(t, false, func() string { return to })
+			_, err := NewAPIClient(src.URL).FetchServerInfo()
+			if err == nil || !strings.Contains(err.Error(), "invalid URL") {
+				t.Fatalf("FetchServerInfo() error = %v, want an invalid URL error", err)
+			}
+		})
+	}
+}
+
+func TestRedirectWithoutCredentialsNeedsApprovalLater(t *testing.T) {
+	posts := 0
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			posts++
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"ok":true,"data":{"name":"relay"}}`))
+	}))
+	defer target.Close()
+	src := redirectServer(t, false, func() string { return target.URL })
+
+	// Server info carries no credentials, so it follows the move freely.
+	c := NewAPIClient(src.URL)
+	if _, err := c.FetchServerInfo(); err != nil {
+		t.Fatalf("FetchServerInfo() error = %v", err)
+	}
+	if c.BaseURL() != target.URL {
+		t.Fatalf("BaseURL() = %q, want %q", c.BaseURL(), target.URL)
+	}
+
+	if _, err := c.Validate("ff-key"); !errors.Is(err, ErrCrossOriginRedirect) {
+		t.Fatalf("Validate() error = %v, want %v", err, ErrCrossOriginRedirect)
+	}
+	if posts != 0 {
+		t.Errorf("key reached the new origin without approval")
+	}
+
+	c = NewAPIClient(src.URL, WithRedirectApproval(func(string, string) bool { return true }))
+	c.FetchServerInfo()
+	if _, err := c.Validate("ff-key"); err != nil {
+		t.Fatalf("Validate() after approval error = %v", err)
+	}
+	if posts != 1 {
+		t.Errorf("key server saw %d key requests, want 1", posts)
+	}
+}
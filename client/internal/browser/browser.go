@@ -0,0 +1,53 @@
+// Package browser opens URLs in the user's default web browser. It refuses
+// when no browser can be shown, e.g. in an SSH session or on a Linux box
+// without a display, so callers can show the URL instead.
+package browser
+
+import (
+	"errors"
+	"net/url"
+	"os"
+	"os/exec"
+	"runtime"
+)
+
+// ErrUnavailable is returned when no browser can be launched.
+var ErrUnavailable = errors.New("no browser available")
+
+// Open opens rawURL, which must be an http or https URL, in the default
+// browser. It returns once the opener has started, without waiting for
+// the browser.
+func Open(rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return errors.New("not an http(s) URL: " + rawURL)
+	}
+	if isRemote() {
+		return ErrUnavailable
+	}
+
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "windows":
+		cmd = exec.Command("rundll32", "url.dll,FileProtocolHandler", u.String())
+	case "darwin":
+		cmd = exec.Command("open", u.String())
+	default:
+		if os.Getenv("DISPLAY") == "" && os.Getenv("WAYLAND_DISPLAY") == "" {
+			return ErrUnavailable
+		}
+		cmd = exec.Command("xdg-open", u.String())
+	}
+	if err := cmd.Start(); err != nil {
+		return ErrUnavailable
+	}
+	// Reap the opener in the background; its exit status isn't reliable.
+	go cmd.Wait()
+	return nil
+}
+
+// isRemote reports whether we run inside an SSH session, where a browser
+// would open on the remote machine, if at all.
+func isRemote() bool {
+	return os.Getenv("SSH_CONNECTION") != "" || os.Getenv("SSH_TTY") != "" || os.Getenv("SSH_CLIENT") != ""
+}
@@ -0,0 +1,96 @@
+// Package clipboard copies text to the user's clipboard. It uses the
+// native clipboard tools when available and falls back to the OSC 52
+// terminal escape, which the terminal emulator handles, so copying also
+// works over SSH. Over SSH OSC 52 is tried first, since native tools there
+// would reach the remote machine's clipboard, not the user's.
+package clipboard
+
+import (
+	"encoding/base64"
+	"errors"
+	"io"
+	"os"
+	"strings"
+
+	native "github.com/atotto/clipboard"
+)
+
+// Method identifies how text was copied.
+type Method int
+
+const (
+	// Native means the system clipboard tools were used.
+	Native Method = iota
+	// OSC52 means the text was handed to the terminal emulator. Whether
+	// the terminal honours it can't be detected.
+	OSC52
+)
+
+// ErrUnavailable is returned when no clipboard backend could be used.
+var ErrUnavailable = errors.New("clipboard unavailable")
+
+// terminal, if set, takes OSC 52 escapes instead of the controlling
+// terminal.
+var terminal io.Writer
+
+// SetTerminal routes OSC 52 escapes through w, such as the writer a TUI
+// renders to, so they go out between frames rather than in the middle of
+// one. It must be called before Write is used.
+func SetTerminal(w io.Writer) {
+	terminal = w
+}
+
+// Write copies text to the clipboard and reports which backend took it.
+func Write(text string) (Method, error) {
+	remote := isRemote()
+	if !remote && writeNative(text) == nil {
+		return Native, nil
+	}
+	if writeOSC52(text) == nil {
+		return OSC52, nil
+	}
+	if remote && writeNative(text) == nil {
+		return Native, nil
+	}
+	return 0, ErrUnavailable
+}
+
+// isRemote reports whether we run inside an SSH session.
+func isRemote() bool {
+	return os.Getenv("SSH_CONNECTION") != "" || os.Getenv("SSH_TTY") != "" || os.Getenv("SSH_CLIENT") != ""
+}
+
+func writeNative(text string) error {
+	if native.Unsupported {
+		return ErrUnavailable
+	}
+	return native.WriteAll(text)
+}
+
+// writeOSC52 sends the OSC 52 "set clipboard" escape to the terminal,
+// wrapped for tmux and screen, which otherwise swallow it.
+func writeOSC52(text string) error {
+	if terminal != nil {
+		_, err := io.WriteString(terminal, osc52(text))
+		return err
+	}
+	w, closeTerm, err := openTerminal()
+	if err != nil {
+		return err
+	}
+	defer closeTerm()
+	_, err = io.WriteString(w, osc52(text))
+	return err
+}
+
+// osc52 builds the escape sequence for text.
+func osc52(text string) string {
+	seq := "\x1b]52;c;" + base64.StdEncoding.EncodeToString([]byte(text)) + "\x07"
+	switch {
+	case os.Getenv("TMUX") != "":
+		return "\x1bPtmux;" + strings.ReplaceAll(seq, "\x1b", "\x1b\x1b") + "\x1b\\"
+	case strings.HasPrefix(os.Getenv("TERM"), "screen"):
+		return "\x1bP" + seq + "\x1b\\"
+	}
+	return seq
+}
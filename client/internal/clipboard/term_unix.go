@@ -0,0 +1,18 @@
+//go:build !windows
+
+package clipboard
+
+import (
+	"io"
+	"os"
+)
+
+// openTerminal opens the controlling terminal, so the escape reaches it
+// even when stdout is redirected.
+func openTerminal() (io.Writer, func(), error) {
+	tty, err := os.OpenFile("/dev/tty", os.O_WRONLY, 0)
+	if err != nil {
+		return nil, nil, err
+	}
+	return tty, func() { tty.Close() }, nil
+}
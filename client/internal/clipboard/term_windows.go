@@ -0,0 +1,18 @@
+//go:build windows
+
+package clipboard
+
+import (
+	"io"
+	"os"
+)
+
+// openTerminal opens the console output, so the escape reaches it even
+// when stdout is redirected.
+func openTerminal() (io.Writer, func(), error) {
+	con, err := os.OpenFile("CONOUT$", os.O_WRONLY, 0)
+	if err != nil {
+		return nil, nil, err
+	}
+	return con, func() { con.Close() }, nil
+}
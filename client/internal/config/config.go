@@ -4,22 +4,85 @@ package config
 import (
 	"flag"
 	"fmt"
+	"net"
+	"net/url"
 	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/x/term"
+)
+
+// Defaults for --server-list and --server. They are variables so a
+// self-hosted build can bake in its own endpoints, the same way the version
+// is injected:
+//
+//	-ldflags "-X github.com/AerNos/firefrp-client/internal/config.DefaultServerList=https://example.com/servers.json"
+//	-ldflags "-X github.com/AerNos/firefrp-client/internal/config.DefaultServer=https://frp.example.com"
+//
+// Setting DefaultServerList to an empty string makes the client connect to
+// DefaultServer directly, without downloading a list.
+var (
+	DefaultServerList = "https://static.lieyan.work/project/FireFrp/config/server-list.json"
+	DefaultServer     = "http://localhost:9001"
 )
 
 // Config holds the runtime configuration for the FireFrp client.
 type Config struct {
-	// ServerListURL is the URL of a remote JSON file containing the server list.
+	// ServerListURL is the URL of a remote JSON file containing the server list,
+	// or a file:// URL / local path for offline use.
 	// Each entry only contains an apiUrl field; details are fetched from each server.
 	ServerListURL string
 
+	// ServerListAuth is sent as the Authorization header when downloading
+	// the server list (e.g. "Bearer <token>"), for private lists. Falls
+	// back to $FIREFRP_SERVER_LIST_AUTH so the secret can stay out of the
+	// command line. Basic-auth credentials may instead be embedded in
+	// ServerListURL as user:password@host.
+	ServerListAuth string
+
 	// ServerURL is the FireFrp management API address.
-	// Default: http://localhost:9001
+	// Default: DefaultServer (http://localhost:9001)
 	ServerURL string
 
+	// ServerName is the display name for ServerURL, when known (e.g. from
+	// a profile). Empty shows the URL.
+	ServerName string
+
+	// Profile names a saved connection profile whose server, local address,
+	// port and label are used unless given explicitly.
+	Profile string
+
+	// Servers, when set, is offered in the server selection view instead
+	// of fetching ServerListURL, for users who always use the same relays.
+	// The entries are still probed. The first also acts as ServerURL.
+	Servers []string
+
+	// MirrorServers lists management API URLs on which the same key is used
+	// to keep tunnels up concurrently, so one relay outage doesn't cut off
+	// access. When set, the first entry also acts as ServerURL.
+	MirrorServers []string
+
 	// AccessKey is the user-provided access key for tunnel authentication.
 	AccessKey string
 
+	// Keys lists access keys to use one after another, from --keys and
+	// --key-file. When the key in use expires or is rejected, the client
+	// switches to the next one. AccessKey is always the first entry.
+	Keys []string
+
+	// KeyFile is a file with one access key per line, appended to Keys by
+	// LoadKeyFile.
+	KeyFile string
+
+	// Token is a long-lived account token. When set and no key is given,
+	// the client has the server issue an ephemeral access key for it
+	// before connecting. Falls back to $FIREFRP_TOKEN. An explicit key
+	// takes precedence.
+	Token string
+
 	// LocalPort is the local port to be mapped through the tunnel.
 	LocalPort int
 
@@ -27,24 +90,335 @@ type Config struct {
 	// Default: 127.0.0.1
 	LocalIP string
 
+	// AutoLocalIP, when LocalIP is the default 127.0.0.1 and nothing
+	// answers there, forwards to the machine's LAN address instead if the
+	// service listens on that (common with Windows/WSL setups).
+	AutoLocalIP bool
+
+	// ProxyName requests a specific frp proxy name instead of the one the
+	// server assigns. Only honored by servers that allow client-chosen names.
+	ProxyName string
+
+	// WatchNetwork polls the network interfaces while connected and
+	// restarts the tunnel as soon as they change (e.g. Wi-Fi to Ethernet,
+	// VPN up or down) instead of waiting for frp to notice (TUI).
+	WatchNetwork bool
+
+	// AutoServer probes every listed server (or --servers) and connects to
+	// the fastest reachable one without showing the selection. An explicit
+	// --server skips the list altogether.
+	AutoServer bool
+
+	// AutoSingleServer selects the only reachable listed server without
+	// showing the selection (TUI). It is on unless --pick-server or an
+	// explicit --server is given.
+	AutoSingleServer bool
+
+	// IdleTimeout disconnects the tunnel (releasing the key) once no traffic
+	// has flowed through it for this long. Zero disables it.
+	IdleTimeout time.Duration
+
+	// BreakerFailures is how many failed connection attempts within
+	// BreakerWindow make the TUI pause before allowing another attempt, so
+	// retries don't hammer the server. Zero disables the pause.
+	// Default: 5
+	BreakerFailures int
+
+	// BreakerWindow is the period in which BreakerFailures failures trip
+	// the pause. Any successful connection resets the count.
+	// Default: 2m
+	BreakerWindow time.Duration
+
+	// APITimeout bounds each request to the selected management server
+	// (key validation and server info).
+	// Default: 15s
+	APITimeout time.Duration
+
+	// InsecureSkipVerify turns off TLS certificate verification for the
+	// management API and server probes, for servers with self-signed
+	// certificates. Never on by default; a warning banner is shown while
+	// it is in effect.
+	InsecureSkipVerify bool
+
+	// KeyIn is where the access key goes in validate and renew requests:
+	// "body" (JSON body), "header" (Authorization: Bearer) or "both".
+	// Default: body
+	KeyIn string
+
+	// ProbeTimeout bounds discovery requests: the server list download and
+	// the server-info probe sent to each listed server.
+	// Default: 10s
+	ProbeTimeout time.Duration
+
+	// ProbeConcurrency caps how many servers are probed at once.
+	// Default: 8
+	ProbeConcurrency int
+
+	// WrapLogs soft-wraps long log lines in the running view instead of
+	// truncating them. Can also be toggled at runtime.
+	WrapLogs bool
+
+	// Audit shows the server's validation response and the derived frpc
+	// config after validation, and waits for confirmation before
+	// connecting (direct mode without a terminal waits a few seconds).
+	Audit bool
+
+	// ShowToken reveals the frps token and other secrets in --audit output.
+	ShowToken bool
+
+	// AcceptTOS accepts a server's terms of service in direct mode without
+	// asking, for automation. The TUI always asks.
+	AcceptTOS bool
+
+	// ShowVisitors lists the IPs of recent visitors in the running view.
+	// Off by default, since visitor addresses are personal data.
+	ShowVisitors bool
+
+	// ShowLogSource keeps frp's "[source/file.go:line]" reference on log
+	// lines in the TUI, for correlating them with the frp source.
+	ShowLogSource bool
+
+	// InfoFields selects the running view's connection info rows and their
+	// order. Empty shows the default rows.
+	InfoFields []string
+
+	// Compact starts the running view as a single status line (status,
+	// remote address, uptime, remaining time). Can also be toggled at runtime.
+	Compact bool
+
+	// AutoRenew asks the server to extend the key RenewBefore ahead of its
+	// expiry, and re-validates the key and restarts the tunnel when the
+	// server rejects it mid-session (e.g. on expiry), instead of returning
+	// to the key input. Revoked or unknown keys still end the session.
+	AutoRenew bool
+
+	// RenewBefore is how long before expiry an auto-renewing session asks
+	// the server to extend the key. Only used with AutoRenew.
+	// Default: 5m
+	RenewBefore time.Duration
+
+	// Label is a free-form session label sent to the server plugin as the
+	// "label" metadata field, e.g. for telling sessions apart in logs.
+	Label string
+
+	// Metadata overrides or extends the metadata hints sent to the server
+	// plugin (client_version, os, arch, label). Set with repeated
+	// --meta key=value; an empty value removes a field. access_key can't
+	// be set this way.
+	Metadata map[string]string
+
+	// EndpointFile, if set, is written with the tunnel's public "host:port"
+	// and expiry once connected, kept current across reconnects and
+	// renewals, and removed on shutdown. Scripts can poll it instead of
+	// parsing output.
+	EndpointFile string
+
+	// StayOnExit keeps the TUI on a review of the final stats and logs
+	// when a session ends on its own, instead of going straight back to
+	// the input or error view.
+	StayOnExit bool
+
+	// TunnelProxy, if set, is an http://, socks5:// or ntlm:// proxy URL
+	// the frp tunnel connects to frps through (frpc's transport.proxyURL).
+	// Management API requests don't use it.
+	TunnelProxy string
+
+	// Notify shows a desktop notification when the tunnel connects, is
+	// rejected or fails.
+	Notify bool
+
+	// StatusAddr, if set, serves a small JSON status report (status,
+	// uptime, remote address, expiry, reconnects, traffic) over HTTP in
+	// direct mode. A bare ":port" binds to loopback only.
+	StatusAddr string
+
+	// NoPortWarnings silences the advisory warnings about implausible local
+	// or remote ports (privileged ports, UDP-only services).
+	NoPortWarnings bool
+
+	// StrictFrpVersion refuses to connect when the server advertises an frps
+	// version that may be incompatible with the embedded frp client,
+	// instead of only warning.
+	StrictFrpVersion bool
+
+	// NoUpdate disables automatic client updates. If a server requires a
+	// different client version, the connection is refused with upgrade
+	// instructions instead.
+	NoUpdate bool
+
+	// UpdateAsset is the release asset name template used when updating,
+	// for forks that name their binaries differently. Placeholders: {os},
+	// {arch}, {tag}, {version} and {ext}. Empty uses the official scheme
+	// (firefrp-{os}-{arch}{ext}).
+	UpdateAsset string
+
+	// SpinnerStyle selects the spinner animation: dot, line or points.
+	// Empty keeps the default (dot, or line in ASCII mode).
+	SpinnerStyle string
+
+	// SpinnerInterval is the time between spinner frames. Zero keeps the
+	// animation's default rate; larger values mean less motion.
+	SpinnerInterval time.Duration
+
+	// ASCII renders the TUI with ASCII-only glyphs and borders. It is also
+	// enabled automatically on dumb terminals (see UseASCII).
+	ASCII bool
+
+	// NoAltScreen renders the TUI inline instead of on the alternate
+	// screen, so the final view stays in the terminal scrollback after
+	// exit.
+	NoAltScreen bool
+
+	// ClearHistory deletes the recorded session history and exits.
+	ClearHistory bool
+
 	// ShowVersion prints version and exits.
 	ShowVersion bool
+
+	// VersionJSON makes --version print a JSON object (version, frp
+	// version, OS, architecture, commit) instead of a plain line.
+	VersionJSON bool
+
+	// Command is the subcommand given before the flags ("doctor" or
+	// "profiles"), or empty for the normal TUI / direct mode.
+	Command string
+}
+
+// serverListAuthEnv names the environment variable consulted when
+// --server-list-auth is not given.
+const serverListAuthEnv = "FIREFRP_SERVER_LIST_AUTH"
+
+// tokenEnv names the environment variable consulted when --token is not
+// given.
+const tokenEnv = "FIREFRP_TOKEN"
+
+// Dir returns the per-user directory where the client keeps persisted
+// state (locks, history, etc.), creating it if necessary.
+func Dir() (string, error) {
+	base, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to locate user config dir: %w", err)
+	}
+	dir := filepath.Join(base, "firefrp")
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return "", fmt.Errorf("failed to create config dir: %w", err)
+	}
+	return dir, nil
 }
 
-// DirectMode returns true if both AccessKey and LocalPort are provided,
-// indicating the client should skip TUI and connect directly.
+// DirectMode returns true if both AccessKey (or Token) and LocalPort are
+// provided, indicating the client should skip TUI and connect directly.
 func (c *Config) DirectMode() bool {
-	return c.AccessKey != "" && c.LocalPort > 0
+	return (c.AccessKey != "" || c.Token != "") && c.LocalPort > 0
+}
+
+// UseToken returns true if access keys should be issued for Token rather
+// than entered by the user: a token is set and no key was given.
+func (c *Config) UseToken() bool {
+	return c.Token != "" && c.AccessKey == ""
+}
+
+// UseASCII returns true if the TUI should avoid Unicode glyphs, either
+// because --ascii was given or the terminal declares itself dumb.
+func (c *Config) UseASCII() bool {
+	return c.ASCII || os.Getenv("TERM") == "dumb"
+}
+
+// UseAltScreen returns true if the TUI should take over the alternate
+// screen, i.e. unless --no-altscreen was given.
+func (c *Config) UseAltScreen() bool {
+	return !c.NoAltScreen
 }
 
-// NeedsServerSelect returns true if a server list URL is configured,
-// indicating the TUI should show the server selection view first.
+// Interactive returns true if both stdin and stdout are terminals, which
+// the TUI needs. Under nohup, cron or a pipe only direct mode works.
+func (c *Config) Interactive() bool {
+	return isTerminal(os.Stdin) && isTerminal(os.Stdout)
+}
+
+// isTerminal reports whether f is a terminal. /dev/null, which nohup
+// gives as stdin, is a character device but not a terminal.
+func isTerminal(f *os.File) bool {
+	return term.IsTerminal(f.Fd())
+}
+
+// MirrorMode returns true if tunnels should be kept on several servers at once.
+func (c *Config) MirrorMode() bool {
+	return len(c.MirrorServers) > 0
+}
+
+// NeedsServerSelect returns true if a server list URL or --servers is
+// configured, indicating the TUI should show the server selection view
+// first. Mirror mode names its servers explicitly and never shows the
+// selection.
 func (c *Config) NeedsServerSelect() bool {
-	return c.ServerListURL != ""
+	return (c.ServerListURL != "" || len(c.Servers) > 0) && !c.MirrorMode()
 }
 
-// Validate checks the config for logical errors when used in direct mode.
+// Validate checks the config for logical errors.
 func (c *Config) Validate() error {
+	if c.APITimeout <= 0 {
+		return fmt.Errorf("invalid --api-timeout: %s (must be positive)", c.APITimeout)
+	}
+	if c.RenewBefore < 0 {
+		return fmt.Errorf("invalid --renew-before: %s (must not be negative)", c.RenewBefore)
+	}
+	if c.IdleTimeout < 0 {
+		return fmt.Errorf("invalid --idle-timeout: %s (must not be negative)", c.IdleTimeout)
+	}
+	if c.BreakerFailures < 0 {
+		return fmt.Errorf("invalid --breaker-failures: %d (must not be negative)", c.BreakerFailures)
+	}
+	if c.BreakerFailures > 0 && c.BreakerWindow <= 0 {
+		return fmt.Errorf("invalid --breaker-window: %s (must be positive)", c.BreakerWindow)
+	}
+	if c.ProbeTimeout <= 0 {
+		return fmt.Errorf("invalid --probe-timeout: %s (must be positive)", c.ProbeTimeout)
+	}
+	if c.ProbeConcurrency < 1 {
+		return fmt.Errorf("invalid --probe-concurrency: %d (must be at least 1)", c.ProbeConcurrency)
+	}
+	switch c.KeyIn {
+	case "", "body", "header", "both":
+	default:
+		return fmt.Errorf("invalid --key-in: %q (must be body, header or both)", c.KeyIn)
+	}
+	switch c.SpinnerStyle {
+	case "", "dot", "line", "points":
+	default:
+		return fmt.Errorf("invalid --spinner: %q (must be dot, line or points)", c.SpinnerStyle)
+	}
+	if c.SpinnerInterval < 0 {
+		return fmt.Errorf("invalid --spinner-interval: %s (must not be negative)", c.SpinnerInterval)
+	}
+	if len(c.Keys) > 1 && c.MirrorMode() {
+		return fmt.Errorf("--keys and --key-file can't be combined with --mirror-servers")
+	}
+	if c.UseToken() && c.MirrorMode() {
+		return fmt.Errorf("--token can't be combined with --mirror-servers")
+	}
+	if len(c.MirrorServers) == 1 {
+		return fmt.Errorf("--mirror-servers needs at least two servers")
+	}
+	if c.TunnelProxy != "" {
+		u, err := url.Parse(c.TunnelProxy)
+		if err != nil || u.Host == "" {
+			return fmt.Errorf("invalid --tunnel-proxy: %q (must be a URL like socks5://host:port)", c.TunnelProxy)
+		}
+		switch u.Scheme {
+		case "socks5", "http", "ntlm":
+		default:
+			return fmt.Errorf("invalid --tunnel-proxy scheme %q (must be socks5, http or ntlm)", u.Scheme)
+		}
+	}
+	if c.StatusAddr != "" {
+		if !c.DirectMode() {
+			return fmt.Errorf("--status-addr needs direct mode (--key or --token, and --port)")
+		}
+		if _, port, err := net.SplitHostPort(c.StatusAddr); err != nil || port == "" {
+			return fmt.Errorf("invalid --status-addr: %q (must be host:port or :port)", c.StatusAddr)
+		}
+	}
 	if c.DirectMode() {
 		if c.LocalPort < 1 || c.LocalPort > 65535 {
 			return fmt.Errorf("invalid local port: %d (must be 1-65535)", c.LocalPort)
@@ -56,31 +430,209 @@ func (c *Config) Validate() error {
 // ParseFlags parses command-line flags and returns a Config.
 // If --key and --port are both provided, the client enters direct connect mode
 // (skipping the TUI). Otherwise, it starts in TUI mode.
+// A leading "doctor" or "profiles" argument selects that subcommand instead.
 func ParseFlags() *Config {
 	cfg := &Config{}
+	var servers, mirrorServers, infoFields, keys string
+	var pickServer bool
 
-	flag.StringVar(&cfg.ServerListURL, "server-list", "https://static.lieyan.work/project/FireFrp/config/server-list.json", "Remote server list JSON URL (hosted on object storage)")
-	flag.StringVar(&cfg.ServerURL, "server", "http://localhost:9001", "FireFrp management API URL")
+	flag.StringVar(&cfg.ServerListURL, "server-list", DefaultServerList, "Server list JSON URL (hosted on object storage), file:// URL or local path")
+	flag.StringVar(&cfg.ServerListAuth, "server-list-auth", "", "Authorization header for the server list, e.g. \"Bearer <token>\" (or set "+serverListAuthEnv+")")
+	flag.StringVar(&cfg.ServerURL, "server", DefaultServer, "FireFrp management API URL")
+	flag.StringVar(&cfg.Profile, "profile", "", "Use a saved connection profile (server, local address, port, label); flags override it")
+	flag.StringVar(&servers, "servers", "", "Comma-separated management API URLs to choose from instead of fetching --server-list")
+	flag.StringVar(&mirrorServers, "mirror-servers", "", "Comma-separated management API URLs to tunnel through concurrently")
 	flag.StringVar(&cfg.AccessKey, "key", "", "Access key for tunnel authentication")
+	flag.StringVar(&keys, "keys", "", "Comma-separated access keys to switch through as each one expires")
+	flag.StringVar(&cfg.KeyFile, "key-file", "", "File with one access key per line, used like --keys")
+	flag.StringVar(&cfg.Token, "token", "", "Account token; the server issues an access key for it, so no --key is needed (or set "+tokenEnv+")")
 	flag.IntVar(&cfg.LocalPort, "port", 0, "Local port to map through the tunnel")
 	flag.StringVar(&cfg.LocalIP, "local-ip", "127.0.0.1", "Local IP address to bind to")
+	flag.BoolVar(&cfg.AutoLocalIP, "auto-local-ip", false, "If nothing listens on 127.0.0.1:<port>, try the machine's LAN address (e.g. for WSL)")
+	flag.StringVar(&cfg.ProxyName, "proxy-name", "", "Request a specific proxy name (server must allow it; default: server-assigned)")
+	flag.BoolVar(&cfg.WatchNetwork, "watch-network", false, "Reconnect as soon as the network changes (Wi-Fi/Ethernet switch, VPN), in the TUI")
+	flag.BoolVar(&cfg.AutoServer, "auto-server", false, "Probe all listed servers and use the fastest one instead of asking")
+	flag.BoolVar(&pickServer, "pick-server", false, "Always show the server selection, even when only one listed server is reachable")
+	flag.DurationVar(&cfg.IdleTimeout, "idle-timeout", 0, "Disconnect after this long without tunnel traffic (0 = never)")
+	flag.IntVar(&cfg.BreakerFailures, "breaker-failures", 5, "Pause the TUI after this many failed attempts within --breaker-window (0 = never)")
+	flag.DurationVar(&cfg.BreakerWindow, "breaker-window", 2*time.Minute, "Window in which --breaker-failures failed attempts trip the pause")
+	flag.DurationVar(&cfg.APITimeout, "api-timeout", 15*time.Second, "Timeout for management API requests (validation, server info)")
+	flag.StringVar(&cfg.KeyIn, "key-in", "body", "Where to send the access key to the management API: body, header (Authorization: Bearer) or both")
+	flag.BoolVar(&cfg.InsecureSkipVerify, "insecure-skip-verify", false, "Don't verify the management API's TLS certificate (INSECURE, for self-signed servers)")
+	flag.DurationVar(&cfg.ProbeTimeout, "probe-timeout", 10*time.Second, "Timeout for server discovery requests (server list, probes)")
+	flag.IntVar(&cfg.ProbeConcurrency, "probe-concurrency", 8, "How many servers to probe at once during discovery")
+	flag.BoolVar(&cfg.WrapLogs, "wrap-logs", false, "Wrap long log lines in the TUI instead of truncating them")
+	flag.BoolVar(&cfg.Audit, "audit", false, "After validation, show the server's response and the derived frpc config, and confirm before connecting")
+	flag.BoolVar(&cfg.ShowToken, "show-token", false, "With --audit, show the frps token and other secrets instead of masking them")
+	flag.BoolVar(&cfg.AcceptTOS, "accept-tos", false, "In direct mode, accept the server's terms of service without asking")
+	flag.BoolVar(&cfg.ShowVisitors, "show-visitors", false, "Show the IPs of recent visitors in the TUI (needs a frps that reports them)")
+	flag.BoolVar(&cfg.ShowLogSource, "show-source", false, "Keep frp's source file reference on log lines in the TUI, for debugging")
+	flag.StringVar(&infoFields, "info-fields", "", "Comma-separated info rows for the TUI: server, remote, endpoint, local, expiry, remaining, renew, uptime, traffic, connection, session, connected, reconnected")
+	flag.BoolVar(&cfg.Compact, "compact", false, "Show only a one-line status while the tunnel runs (toggle with C)")
+	flag.BoolVar(&cfg.AutoRenew, "auto-renew", false, "Renew the key before it expires, and reconnect if it is rejected mid-session (TUI)")
+	flag.DurationVar(&cfg.RenewBefore, "renew-before", 5*time.Minute, "With --auto-renew, extend the key this long before it expires")
+	flag.StringVar(&cfg.Label, "label", "", "Session label sent to the server as metadata")
+	flag.Func("meta", "Extra server metadata as key=value (repeatable; empty value drops an auto field)", func(s string) error {
+		k, v, ok := strings.Cut(s, "=")
+		k = strings.TrimSpace(k)
+		if !ok || k == "" {
+			return fmt.Errorf("expected key=value, got %q", s)
+		}
+		if k == "access_key" {
+			return fmt.Errorf("access_key can't be set with --meta")
+		}
+		if cfg.Metadata == nil {
+			cfg.Metadata = make(map[string]string)
+		}
+		cfg.Metadata[k] = v
+		return nil
+	})
+	flag.StringVar(&cfg.EndpointFile, "write-endpoint-file", "", "Write the public host:port and expiry to this file while connected")
+	flag.BoolVar(&cfg.StayOnExit, "stay-on-exit", false, "When a TUI session ends on its own, show its final stats and logs until a key is pressed")
+	flag.StringVar(&cfg.TunnelProxy, "tunnel-proxy", "", "Connect the frp tunnel through this proxy, e.g. socks5://127.0.0.1:1080 (http, socks5 or ntlm)")
+	flag.BoolVar(&cfg.Notify, "notify", false, "Show a desktop notification when the tunnel connects, is rejected or fails")
+	flag.StringVar(&cfg.StatusAddr, "status-addr", "", "Serve a JSON status report on this address in direct mode, e.g. :8080 (loopback unless a host is given)")
+	flag.BoolVar(&cfg.NoPortWarnings, "no-port-warnings", false, "Don't warn about privileged or UDP-only ports")
+	flag.BoolVar(&cfg.StrictFrpVersion, "strict-frp-version", false, "Refuse servers whose frps version may be incompatible (default: warn)")
+	flag.BoolVar(&cfg.NoUpdate, "no-update", false, "Never update automatically; refuse servers that require another client version")
+	flag.StringVar(&cfg.UpdateAsset, "update-asset", "", "Release asset name template for updates, e.g. firefrp-{os}-{arch}-{tag} (default firefrp-{os}-{arch}{ext})")
+	flag.StringVar(&cfg.SpinnerStyle, "spinner", "", "Spinner style: dot, line or points (default dot)")
+	flag.DurationVar(&cfg.SpinnerInterval, "spinner-interval", 0, "Time between spinner frames, e.g. 500ms (default: style's own rate)")
+	flag.BoolVar(&cfg.ASCII, "ascii", false, "Render the TUI with ASCII characters only (auto-enabled when TERM=dumb)")
+	flag.BoolVar(&cfg.NoAltScreen, "no-altscreen", false, "Render the TUI inline so it stays in the scrollback after exit")
+	flag.BoolVar(&cfg.ClearHistory, "clear-history", false, "Delete the recent-sessions history and exit")
 	flag.BoolVar(&cfg.ShowVersion, "version", false, "Print version and exit")
+	flag.BoolVar(&cfg.VersionJSON, "json", false, "With --version, print version details as JSON")
 
 	flag.Usage = func() {
 		fmt.Fprintf(os.Stderr, "FireFrp Client - TCP tunnel powered by frp\n\n")
 		fmt.Fprintf(os.Stderr, "Usage:\n")
-		fmt.Fprintf(os.Stderr, "  firefrp [flags]\n\n")
+		fmt.Fprintf(os.Stderr, "  firefrp [flags]\n")
+		fmt.Fprintf(os.Stderr, "  firefrp doctor [flags]\n")
+		fmt.Fprintf(os.Stderr, "  firefrp profiles\n\n")
 		fmt.Fprintf(os.Stderr, "Examples:\n")
 		fmt.Fprintf(os.Stderr, "  firefrp                                    # Start in TUI mode\n")
 		fmt.Fprintf(os.Stderr, "  firefrp --server-list https://cdn.example.com/servers.json\n")
 		fmt.Fprintf(os.Stderr, "                                             # TUI mode with server selection\n")
 		fmt.Fprintf(os.Stderr, "  firefrp --key ff-abc123 --port 25565       # Direct connect mode\n")
+		fmt.Fprintf(os.Stderr, "  firefrp --key-file keys.txt --port 25565   # Switch to the next key as each one expires\n")
 		fmt.Fprintf(os.Stderr, "  firefrp --server https://api.example.com   # Custom server URL\n")
+		fmt.Fprintf(os.Stderr, "  firefrp --servers https://a.example.com,https://b.example.com\n")
+		fmt.Fprintf(os.Stderr, "                                             # Choose between fixed relays, no list download\n")
+		fmt.Fprintf(os.Stderr, "  firefrp --mirror-servers https://a.example.com,https://b.example.com\n")
+		fmt.Fprintf(os.Stderr, "                                             # Mirror one port through two relays\n")
+		fmt.Fprintf(os.Stderr, "  firefrp doctor --port 25565                # Check connectivity and print a report\n")
+		fmt.Fprintf(os.Stderr, "  firefrp --profile mc                       # Use the saved profile \"mc\"\n")
+		fmt.Fprintf(os.Stderr, "  firefrp profiles                           # List saved profiles\n")
 		fmt.Fprintf(os.Stderr, "  firefrp --version                          # Print version\n\n")
 		fmt.Fprintf(os.Stderr, "Flags:\n")
 		flag.PrintDefaults()
 	}
 
-	flag.Parse()
+	args := os.Args[1:]
+	if len(args) > 0 && (args[0] == "doctor" || args[0] == "profiles") {
+		cfg.Command = args[0]
+		args = args[1:]
+	}
+	flag.CommandLine.Parse(args)
+
+	if cfg.ServerListAuth == "" {
+		cfg.ServerListAuth = os.Getenv(serverListAuthEnv)
+	}
+	if cfg.Token == "" {
+		cfg.Token = os.Getenv(tokenEnv)
+	}
+
+	// --servers wins over --server-list: no list is fetched.
+	cfg.Servers = splitList(servers)
+	if len(cfg.Servers) > 0 {
+		cfg.ServerListURL = ""
+		cfg.ServerURL = cfg.Servers[0]
+	}
+
+	// With --auto-server an explicit --server is taken as the choice.
+	if cfg.AutoServer && len(cfg.Servers) == 0 && flagSet("server") {
+		cfg.ServerListURL = ""
+	}
+	cfg.AutoSingleServer = !pickServer && !flagSet("server")
+
+	cfg.addKeys(splitList(keys))
+
+	// An IPv6 --local-ip may be written bracketed, as in "[::1]"; the port
+	// always comes from --port, so "[::1]:25565" is not accepted.
+	if strings.HasPrefix(cfg.LocalIP, "[") && strings.HasSuffix(cfg.LocalIP, "]") {
+		cfg.LocalIP = cfg.LocalIP[1 : len(cfg.LocalIP)-1]
+	}
+
+	cfg.MirrorServers = splitList(mirrorServers)
+	cfg.InfoFields = splitList(infoFields)
+	if cfg.MirrorMode() {
+		cfg.ServerURL = cfg.MirrorServers[0]
+	}
+
 	return cfg
 }
+
+// LoadKeyFile reads --key-file, one key per line; blank lines and lines
+// starting with # are skipped. Its keys follow those from --keys.
+func (c *Config) LoadKeyFile() error {
+	if c.KeyFile == "" {
+		return nil
+	}
+	data, err := os.ReadFile(c.KeyFile)
+	if err != nil {
+		return fmt.Errorf("read --key-file: %w", err)
+	}
+	var keys []string
+	for _, line := range strings.Split(string(data), "\n") {
+		if line = strings.TrimSpace(line); line != "" && !strings.HasPrefix(line, "#") {
+			keys = append(keys, line)
+		}
+	}
+	if len(keys) == 0 {
+		return fmt.Errorf("--key-file %s contains no keys", c.KeyFile)
+	}
+	c.addKeys(keys)
+	return nil
+}
+
+// addKeys appends keys to Keys, dropping duplicates. A key given with
+// --key goes first, and AccessKey is set to the first key when empty.
+func (c *Config) addKeys(keys []string) {
+	if len(keys) == 0 {
+		return
+	}
+	if len(c.Keys) == 0 && c.AccessKey != "" {
+		c.Keys = []string{c.AccessKey}
+	}
+	for _, k := range keys {
+		if !slices.Contains(c.Keys, k) {
+			c.Keys = append(c.Keys, k)
+		}
+	}
+	if c.AccessKey == "" {
+		c.AccessKey = c.Keys[0]
+	}
+}
+
+// flagSet reports whether the named flag was given on the command line.
+func flagSet(name string) bool {
+	set := false
+	flag.Visit(func(f *flag.Flag) {
+		if f.Name == name {
+			set = true
+		}
+	})
+	return set
+}
+
+// splitList splits a comma-separated flag value, dropping empty entries.
+func splitList(s string) []string {
+	var out []string
+	for _, v := range strings.Split(s, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			out = append(out, v)
+		}
+	}
+	return out
+}
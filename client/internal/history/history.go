@@ -0,0 +1,133 @@
+// Package history keeps a short, persisted list of recent tunnel sessions
+// (server, local port, start time, duration) so the TUI can offer them for
+// quick reconnects. Access keys are never written to disk.
+package history
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/AerNos/firefrp-client/internal/config"
+)
+
+// MaxEntries caps the number of sessions kept; older ones are dropped.
+const MaxEntries = 20
+
+// fileName is the history file inside the config dir.
+const fileName = "history.json"
+
+// Entry describes one past tunnel session.
+type Entry struct {
+	ServerName string        `json:"server_name"`
+	ServerURL  string        `json:"server_url"`
+	LocalPort  int           `json:"local_port"`
+	StartedAt  time.Time     `json:"started_at"`
+	Duration   time.Duration `json:"duration"`
+}
+
+// path returns the history file location under the config dir.
+func path() (string, error) {
+	dir, err := config.Dir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, fileName), nil
+}
+
+// Load returns the recorded sessions, most recent first. A missing history
+// file yields an empty list.
+func Load() ([]Entry, error) {
+	p, err := path()
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(p)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read history: %w", err)
+	}
+	var entries []Entry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, fmt.Errorf("failed to parse history: %w", err)
+	}
+	return entries, nil
+}
+
+// ForServer returns the sessions recorded for serverURL, most recent
+// first. URLs differing only in a trailing slash match.
+func ForServer(entries []Entry, serverURL string) []Entry {
+	var out []Entry
+	for _, e := range entries {
+		if sameServer(e.ServerURL, serverURL) {
+			out = append(out, e)
+		}
+	}
+	return out
+}
+
+// sameServer reports whether two server URLs name the same server.
+func sameServer(a, b string) bool {
+	return strings.TrimRight(a, "/") == strings.TrimRight(b, "/")
+}
+
+// Add records a session at the front of the history, trimming it to
+// MaxEntries. An unreadable history file is replaced rather than
+// blocking new entries.
+func Add(e Entry) error {
+	entries, _ := Load()
+	entries = append([]Entry{e}, entries...)
+	if len(entries) > MaxEntries {
+		entries = entries[:MaxEntries]
+	}
+	return save(entries)
+}
+
+// ClearServer deletes the sessions recorded for serverURL, keeping those
+// of other servers.
+func ClearServer(serverURL string) error {
+	entries, err := Load()
+	if err != nil {
+		return err
+	}
+	kept := slices.DeleteFunc(entries, func(e Entry) bool {
+		return sameServer(e.ServerURL, serverURL)
+	})
+	return save(kept)
+}
+
+// save writes entries as the history file.
+func save(entries []Entry) error {
+	data, err := json.MarshalIndent(entries, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode history: %w", err)
+	}
+	p, err := path()
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(p, data, 0o600); err != nil {
+		return fmt.Errorf("failed to write history: %w", err)
+	}
+	return nil
+}
+
+// Clear deletes the recorded history.
+func Clear() error {
+	p, err := path()
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(p); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("failed to clear history: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,164 @@
+// Package httputil holds the HTTP retry policy shared by the management
+// API, server discovery and updater clients.
+package httputil
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Policy controls how DoWithRetry retries a request.
+type Policy struct {
+	// Attempts is the total number of tries, including the first. Values
+	// below 1 mean a single try.
+	Attempts int
+	// BaseDelay is the wait before the second try; it doubles per retry up
+	// to MaxDelay, with ±20% jitter.
+	BaseDelay time.Duration
+	MaxDelay  time.Duration
+}
+
+// DefaultPolicy retries transient failures twice within a few seconds.
+var DefaultPolicy = Policy{Attempts: 3, BaseDelay: 500 * time.Millisecond, MaxDelay: 4 * time.Second}
+
+// NoRetry tries once, for callers that retry at a higher level.
+var NoRetry = Policy{Attempts: 1}
+
+// DoWithRetry sends req with client, retrying transient failures according
+// to policy. Requests with a body are replayed through req.GetBody, which
+// http.NewRequest sets for in-memory bodies; without it only one try is
+// made.
+//
+// Transient failures are connection errors (refused, reset, DNS), 500,
+// 502, 503 and 504 responses, and 429 responses that don't ask to wait
+// longer than policy.MaxDelay (Retry-After) or report an exhausted rate
+// limit (X-RateLimit-Remaining: 0). For non-idempotent methods such as POST only
+// failures to connect are retried, since the server may already have acted
+// on the request otherwise. Timeouts aren't retried: the caller's time
+// budget is already spent. The last response or error is returned as is,
+// so callers see the same results as with client.Do.
+func DoWithRetry(client *http.Client, req *http.Request, policy Policy) (*http.Response, error) {
+	attempts := policy.Attempts
+	if attempts < 1 || (req.Body != nil && req.GetBody == nil) {
+		attempts = 1
+	}
+	ctx := req.Context()
+	for attempt := 1; ; attempt++ {
+		r := req
+		if attempt > 1 {
+			r = req.Clone(ctx)
+			if req.GetBody != nil {
+				body, err := req.GetBody()
+				if err != nil {
+					return nil, err
+				}
+				r.Body = body
+			}
+		}
+
+		resp, err := client.Do(r)
+		if attempt >= attempts || !transient(req.Method, resp, err) {
+			return resp, err
+		}
+		wait := backoff(policy, attempt)
+		if resp != nil && resp.StatusCode == http.StatusTooManyRequests {
+			d, ok := rateLimitWait(resp, policy)
+			if !ok {
+				return resp, err
+			}
+			wait = max(wait, d)
+		}
+		if resp != nil {
+			resp.Body.Close()
+		}
+		if !sleep(ctx, wait) {
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// transient reports whether the outcome of a try is worth retrying.
+func transient(method string, resp *http.Response, err error) bool {
+	if err != nil {
+		if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+			return false
+		}
+		var netErr net.Error
+		if errors.As(err, &netErr) && netErr.Timeout() {
+			return false
+		}
+		if !idempotent(method) {
+			var opErr *net.OpError
+			return errors.As(err, &opErr) && opErr.Op == "dial"
+		}
+		return true
+	}
+	if !idempotent(method) {
+		return false
+	}
+	switch resp.StatusCode {
+	case http.StatusInternalServerError, http.StatusBadGateway, http.StatusServiceUnavailable,
+		http.StatusGatewayTimeout, http.StatusTooManyRequests:
+		return true
+	}
+	return false
+}
+
+// rateLimitWait returns how long a 429 response asks to wait, and false if
+// that is longer than policy allows or the rate limit is used up, in which
+// case the response is handed to the caller instead.
+func rateLimitWait(resp *http.Response, policy Policy) (time.Duration, bool) {
+	if resp.Header.Get("X-RateLimit-Remaining") == "0" {
+		return 0, false
+	}
+	v := resp.Header.Get("Retry-After")
+	if v == "" {
+		return 0, true
+	}
+	secs, err := strconv.Atoi(v)
+	if err != nil || secs < 0 {
+		return 0, false
+	}
+	d := time.Duration(secs) * time.Second
+	return d, d <= policy.MaxDelay
+}
+
+// idempotent reports whether repeating a request with method is harmless.
+func idempotent(method string) bool {
+	switch method {
+	case "", http.MethodGet, http.MethodHead, http.MethodOptions:
+		return true
+	}
+	return false
+}
+
+// backoff returns the jittered wait after the given failed attempt.
+func backoff(policy Policy, attempt int) time.Duration {
+	d := policy.BaseDelay
+	for i := 1; i < attempt && d < policy.MaxDelay; i++ {
+		d *= 2
+	}
+	if policy.MaxDelay > 0 && d > policy.MaxDelay {
+		d = policy.MaxDelay
+	}
+	jitter := time.Duration(float64(d) * (rand.Float64()*0.4 - 0.2))
+	return d + jitter
+}
+
+// sleep waits for d or until ctx is done, reporting whether it waited the
+// full time.
+func sleep(ctx context.Context, d time.Duration) bool {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
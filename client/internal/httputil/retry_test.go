@@ -0,0 +1,239 @@
+package httputil
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// fastPolicy retries like DefaultPolicy without the waits.
+var fastPolicy = Policy{Attempts: 3, BaseDelay: time.Millisecond, MaxDelay: 10 * time.Millisecond}
+
+// countingServer answers every request with status and counts them.
+func countingServer(t *testing.T, status int, header http.Header) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+	var hits atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		for k, v := range header {
+			w.Header()[k] = v
+		}
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &hits
+}
+
+func TestDoWithRetryStatus(t *testing.T) {
+	tests := []struct {
+		name     string
+		method   string
+		status   int
+		header   http.Header
+		wantHits int32
+	}{
+		{"get ok", http.MethodGet, http.StatusOK, nil, 1},
+		{"get internal error", http.MethodGet, http.StatusInternalServerError, nil, 3},
+		{"get bad gateway", http.MethodGet, http.StatusBadGateway, nil, 3},
+		{"get unavailable", http.MethodGet, http.StatusServiceUnavailable, nil, 3},
+		{"get gateway timeout", http.MethodGet, http.StatusGatewayTimeout, nil, 3},
+		{"get too many requests", http.MethodGet, http.StatusTooManyRequests, nil, 3},
+		{"get too many requests, short wait", http.MethodGet, http.StatusTooManyRequests, http.Header{"Retry-After": {"0"}}, 3},
+		{"get too many requests, long wait", http.MethodGet, http.StatusTooManyRequests, http.Header{"Retry-After": {"60"}}, 1},
+		{"get rate limit used up", http.MethodGet, http.StatusTooManyRequests, http.Header{"X-Ratelimit-Remaining": {"0"}}, 1},
+		{"get bad request", http.MethodGet, http.StatusBadRequest, nil, 1},
+		{"get unauthorized", http.MethodGet, http.StatusUnauthorized, nil, 1},
+		{"get not found", http.MethodGet, http.StatusNotFound, nil, 1},
+		{"post unavailable", http.MethodPost, http.StatusServiceUnavailable, nil, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv, hits := countingServer(t, tt.status, tt.header)
+			req, err := http.NewRequest(tt.method, srv.URL, strings.NewReader("{}"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp, err := DoWithRetry(srv.Client(), req, fastPolicy)
+			if err != nil {
+				t.Fatalf("DoWithRetry() error = %v", err)
+			}
+			resp.Body.Close()
+			if resp.StatusCode != tt.status {
+				t.Errorf("status = %d, want the last response's %d", resp.StatusCode, tt.status)
+			}
+			if got := hits.Load(); got != tt.wantHits {
+				t.Errorf("server saw %d requests, want %d", got, tt.wantHits)
+			}
+		})
+	}
+}
+
+func TestDoWithRetryReplaysBody(t *testing.T) {
+	var (
+		mu     sync.Mutex
+		bodies []string
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		bodies = append(bodies, string(b))
+		mu.Unlock()
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	req, _ := http.NewRequest(http.MethodGet, srv.URL, strings.NewReader("payload"))
+	resp, err := DoWithRetry(srv.Client(), req, fastPolicy)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	mu.Lock()
+	defer mu.Unlock()
+	if len(bodies) != 3 {
+		t.Fatalf("server saw %d requests, want 3", len(bodies))
+	}
+	for i, b := range bodies {
+		if b != "payload" {
+			t.Errorf("request %d body = %q, want %q", i+1, b, "payload")
+		}
+	}
+}
+
+// refusingClient returns a client whose every dial fails with
+// ECONNREFUSED, counting the dials.
+func refusingClient(dials *atomic.Int32) *http.Client {
+	return &http.Client{Transport: &http.Transport{
+		DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+			dials.Add(1)
+			return nil, &net.OpError{Op: "dial", Net: network, Err: syscall.ECONNREFUSED}
+		},
+	}}
+}
+
+func TestDoWithRetryDialErrors(t *testing.T) {
+	for _, method := range []string{http.MethodGet, http.MethodPost} {
+		t.Run(method, func(t *testing.T) {
+			var dials atomic.Int32
+			req, _ := http.NewRequest(method, "http://relay.example.com/api", strings.NewReader("{}"))
+			_, err := DoWithRetry(refusingClient(&dials), req, fastPolicy)
+			if !errors.Is(err, syscall.ECONNREFUSED) {
+				t.Errorf("err = %v, want ECONNREFUSED", err)
+			}
+			if got := dials.Load(); got != 3 {
+				t.Errorf("dialed %d times, want 3", got)
+			}
+		})
+	}
+}
+
+func TestDoWithRetryPostNotRetriedAfterSend(t *testing.T) {
+	// The server reads the request, then drops the connection without an
+	// answer: it may have acted on the POST, so it must not be repeated.
+	var hits atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		conn, _, err := w.(http.Hijacker).Hijack()
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		conn.Close()
+	}))
+	defer srv.Close()
+
+	req, _ := http.NewRequest(http.MethodPost, srv.URL, strings.NewReader("{}"))
+	if _, err := DoWithRetry(srv.Client(), req, fastPolicy); err == nil {
+		t.Fatal("DoWithRetry() = nil error, want the dropped connection")
+	}
+	if got := hits.Load(); got != 1 {
+		t.Errorf("server saw %d requests, want 1", got)
+	}
+}
+
+func TestDoWithRetryAttempts(t *testing.T) {
+	tests := []struct {
+		name     string
+		attempts int
+		wantHits int32
+	}{
+		{"no retry", 1, 1},
+		{"zero means once", 0, 1},
+		{"five", 5, 5},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv, hits := countingServer(t, http.StatusServiceUnavailable, nil)
+			policy := fastPolicy
+			policy.Attempts = tt.attempts
+			req, _ := http.NewRequest(http.MethodGet, srv.URL, nil)
+			resp, err := DoWithRetry(srv.Client(), req, policy)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+			if got := hits.Load(); got != tt.wantHits {
+				t.Errorf("server saw %d requests, want %d", got, tt.wantHits)
+			}
+		})
+	}
+}
+
+func TestDoWithRetryContextCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var hits atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		cancel()
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	// A long backoff: only the cancellation can end the wait early.
+	policy := Policy{Attempts: 3, BaseDelay: time.Hour, MaxDelay: time.Hour}
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL, nil)
+	start := time.Now()
+	_, err := DoWithRetry(srv.Client(), req, policy)
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("err = %v, want context.Canceled", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("DoWithRetry took %v after cancellation", elapsed)
+	}
+	if got := hits.Load(); got != 1 {
+		t.Errorf("server saw %d requests, want 1", got)
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	policy := Policy{BaseDelay: 100 * time.Millisecond, MaxDelay: 300 * time.Millisecond}
+	tests := []struct {
+		attempt int
+		want    time.Duration
+	}{
+		{1, 100 * time.Millisecond},
+		{2, 200 * time.Millisecond},
+		{3, 300 * time.Millisecond},
+		{6, 300 * time.Millisecond},
+	}
+	for _, tt := range tests {
+		for range 20 {
+			got := backoff(policy, tt.attempt)
+			lo, hi := tt.want*8/10, tt.want*12/10
+			if got < lo || got > hi {
+				t.Errorf("backoff(attempt %d) = %v, want within %v..%v", tt.attempt, got, lo, hi)
+				break
+			}
+		}
+	}
+}
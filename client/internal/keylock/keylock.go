@@ -0,0 +1,97 @@
+// Package keylock provides an advisory, per-access-key lock file so that
+// two clients on the same machine don't try to use the same key at once.
+// The file is held with an OS file lock, which the system drops when the
+// owning process exits, so a crashed instance never leaves the key locked.
+package keylock
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/AerNos/firefrp-client/internal/config"
+)
+
+// ErrLocked is returned when another live process holds the lock for a key.
+var ErrLocked = errors.New("该 Key 已在本机使用中")
+
+// errHeld is returned by lockFile when another open file holds the lock.
+var errHeld = errors.New("lock held")
+
+// Lock is a held key lock. The zero value and nil are safe to Release.
+type Lock struct {
+	path string
+	f    *os.File
+}
+
+// Acquire takes the lock for key under the config dir. It returns ErrLocked
+// if another running process on this machine holds it. Other errors mean
+// the lock file couldn't be managed at all; since the lock is advisory,
+// callers may ignore them and proceed without a lock.
+func Acquire(key string) (*Lock, error) {
+	base, err := config.Dir()
+	if err != nil {
+		return nil, err
+	}
+	dir := filepath.Join(base, "locks")
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("failed to create lock dir: %w", err)
+	}
+	path := filepath.Join(dir, lockName(key))
+
+	// A holder releasing the lock removes the file, possibly between our
+	// open and lock; the lock then guards a file no longer at path, so
+	// open the new one and try again.
+	for i := 0; i < 3; i++ {
+		f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o600)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open lock file: %w", err)
+		}
+		if err := lockFile(f); err != nil {
+			f.Close()
+			if errors.Is(err, errHeld) {
+				return nil, ErrLocked
+			}
+			return nil, fmt.Errorf("failed to lock file: %w", err)
+		}
+		if !stillAt(f, path) {
+			f.Close()
+			continue
+		}
+		// The PID is only informational, for whoever looks at the file.
+		if err := f.Truncate(0); err == nil {
+			fmt.Fprintf(f, "%d\n", os.Getpid())
+		}
+		return &Lock{path: path, f: f}, nil
+	}
+	return nil, ErrLocked
+}
+
+// stillAt reports whether the open file f is the one at path.
+func stillAt(f *os.File, path string) bool {
+	open, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	cur, err := os.Stat(path)
+	return err == nil && os.SameFile(open, cur)
+}
+
+// Release removes the lock file. It is a no-op on a nil or released Lock.
+func (l *Lock) Release() {
+	if l == nil || l.f == nil {
+		return
+	}
+	releaseFile(l.f, l.path)
+	l.f = nil
+}
+
+// lockName derives the lock file name from a hash of the key so the key
+// itself never lands on disk.
+func lockName(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:8]) + ".lock"
+}
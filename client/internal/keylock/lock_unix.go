@@ -0,0 +1,26 @@
+//go:build !windows
+
+package keylock
+
+import (
+	"errors"
+	"os"
+	"syscall"
+)
+
+// lockFile takes an exclusive lock on f without waiting, returning
+// errHeld if another open file holds it.
+func lockFile(f *os.File) error {
+	err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX|syscall.LOCK_NB)
+	if errors.Is(err, syscall.EWOULDBLOCK) {
+		return errHeld
+	}
+	return err
+}
+
+// releaseFile removes the lock file while still holding the lock, so no
+// other process can lock the name in between, then drops the lock.
+func releaseFile(f *os.File, path string) {
+	os.Remove(path)
+	f.Close()
+}
@@ -0,0 +1,30 @@
+//go:build windows
+
+package keylock
+
+import (
+	"errors"
+	"os"
+
+	"golang.org/x/sys/windows"
+)
+
+// lockFile takes an exclusive lock on f without waiting, returning
+// errHeld if another open file holds it.
+func lockFile(f *os.File) error {
+	err := windows.LockFileEx(windows.Handle(f.Fd()),
+		windows.LOCKFILE_EXCLUSIVE_LOCK|windows.LOCKFILE_FAIL_IMMEDIATELY,
+		0, 1, 0, &windows.Overlapped{})
+	if errors.Is(err, windows.ERROR_LOCK_VIOLATION) {
+		return errHeld
+	}
+	return err
+}
+
+// releaseFile drops the lock and then removes the lock file. Windows
+// can't remove a file that is open, and fails the removal if another
+// process has opened it meanwhile, which leaves the file to that process.
+func releaseFile(f *os.File, path string) {
+	f.Close()
+	os.Remove(path)
+}
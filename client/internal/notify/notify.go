@@ -0,0 +1,82 @@
+// Package notify shows desktop notifications using the tools each OS
+// ships with: notify-send on Linux and BSD, osascript on macOS and a
+// PowerShell toast on Windows. Like package browser it refuses when no
+// desktop is reachable, e.g. over SSH or on a headless server.
+package notify
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// ErrUnavailable is returned when no notification can be shown.
+var ErrUnavailable = errors.New("desktop notifications unavailable")
+
+// appName is the sender shown with each notification.
+const appName = "FireFrp"
+
+// Send shows a notification with the given title and body. It returns once
+// the helper has started, without waiting for the notification.
+func Send(title, body string) error {
+	if isRemote() {
+		return ErrUnavailable
+	}
+
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "windows":
+		cmd = exec.Command("powershell", "-NoProfile", "-NonInteractive", "-Command", toastScript(title, body))
+	case "darwin":
+		script := "display notification " + appleString(body) + " with title " + appleString(title)
+		cmd = exec.Command("osascript", "-e", script)
+	default:
+		if os.Getenv("DISPLAY") == "" && os.Getenv("WAYLAND_DISPLAY") == "" {
+			return ErrUnavailable
+		}
+		cmd = exec.Command("notify-send", "--app-name="+appName, title, body)
+	}
+	if err := cmd.Start(); err != nil {
+		return ErrUnavailable
+	}
+	// Reap the helper in the background; its exit status isn't reliable.
+	go cmd.Wait()
+	return nil
+}
+
+// appleString quotes s as an AppleScript string literal.
+func appleString(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, `"`, `\"`)
+	return `"` + s + `"`
+}
+
+// toastScript builds a PowerShell script that shows a toast through the
+// WinRT notification API, available on Windows 10 and later.
+func toastScript(title, body string) string {
+	xml := "<toast><visual><binding template='ToastGeneric'><text>" + xmlEscape(title) +
+		"</text><text>" + xmlEscape(body) + "</text></binding></visual></toast>"
+	return strings.Join([]string{
+		"[Windows.UI.Notifications.ToastNotificationManager, Windows.UI.Notifications, ContentType = WindowsRuntime] > $null",
+		"[Windows.Data.Xml.Dom.XmlDocument, Windows.Data.Xml.Dom.XmlDocument, ContentType = WindowsRuntime] > $null",
+		"$xml = New-Object Windows.Data.Xml.Dom.XmlDocument",
+		"$xml.LoadXml(" + psString(xml) + ")",
+		"[Windows.UI.Notifications.ToastNotificationManager]::CreateToastNotifier(" + psString(appName) + ").Show([Windows.UI.Notifications.ToastNotification]::new($xml))",
+	}, "; ")
+}
+
+// psString quotes s as a single-quoted PowerShell string literal.
+func psString(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
+
+// xmlEscape escapes the characters that are special in XML text.
+var xmlEscape = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;", "'", "&apos;", `"`, "&quot;").Replace
+
+// isRemote reports whether we run inside an SSH session, where a
+// notification would not reach the user's desktop.
+func isRemote() bool {
+	return os.Getenv("SSH_CONNECTION") != "" || os.Getenv("SSH_TTY") != "" || os.Getenv("SSH_CLIENT") != ""
+}
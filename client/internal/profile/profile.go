@@ -0,0 +1,114 @@
+// Package profile stores named connection profiles (server, local address
+// and port, label) so users juggling several setups can switch between
+// them with --profile. Like the session history, profiles never hold the
+// access key.
+package profile
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/AerNos/firefrp-client/internal/config"
+)
+
+// fileName is the profiles file inside the config dir.
+const fileName = "profiles.json"
+
+// ErrNotFound is returned by Get when no profile has the given name.
+var ErrNotFound = errors.New("profile not found")
+
+// Profile is a named set of connection settings.
+type Profile struct {
+	Name       string `json:"name"`
+	ServerURL  string `json:"server_url"`
+	ServerName string `json:"server_name,omitempty"`
+	LocalIP    string `json:"local_ip,omitempty"`
+	LocalPort  int    `json:"local_port"`
+	Label      string `json:"label,omitempty"`
+}
+
+// path returns the profiles file location under the config dir.
+func path() (string, error) {
+	dir, err := config.Dir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, fileName), nil
+}
+
+// List returns the saved profiles sorted by name. A missing profiles file
+// yields an empty list.
+func List() ([]Profile, error) {
+	p, err := path()
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(p)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read profiles: %w", err)
+	}
+	var profiles []Profile
+	if err := json.Unmarshal(data, &profiles); err != nil {
+		return nil, fmt.Errorf("failed to parse profiles: %w", err)
+	}
+	sort.Slice(profiles, func(i, j int) bool { return profiles[i].Name < profiles[j].Name })
+	return profiles, nil
+}
+
+// Get returns the profile with the given name, or ErrNotFound.
+func Get(name string) (*Profile, error) {
+	profiles, err := List()
+	if err != nil {
+		return nil, err
+	}
+	for i := range profiles {
+		if profiles[i].Name == name {
+			return &profiles[i], nil
+		}
+	}
+	return nil, fmt.Errorf("%w: %s", ErrNotFound, name)
+}
+
+// Save stores p, replacing any profile of the same name.
+func Save(p Profile) error {
+	p.Name = strings.TrimSpace(p.Name)
+	if p.Name == "" {
+		return fmt.Errorf("profile name must not be empty")
+	}
+	profiles, err := List()
+	if err != nil {
+		return err
+	}
+	replaced := false
+	for i := range profiles {
+		if profiles[i].Name == p.Name {
+			profiles[i] = p
+			replaced = true
+		}
+	}
+	if !replaced {
+		profiles = append(profiles, p)
+	}
+
+	data, err := json.MarshalIndent(profiles, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode profiles: %w", err)
+	}
+	file, err := path()
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(file, data, 0o600); err != nil {
+		return fmt.Errorf("failed to write profiles: %w", err)
+	}
+	return nil
+}
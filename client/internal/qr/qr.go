@@ -0,0 +1,385 @@
+// Package qr is a minimal QR Code encoder for short strings such as tunnel
+// addresses. It supports byte mode at error correction level L in versions
+// 1-5 (up to 106 bytes), where every symbol is a single Reed-Solomon block,
+// which keeps the implementation small.
+package qr
+
+import (
+	"errors"
+)
+
+// ErrTooLong is returned when the text doesn't fit in a version 5 symbol.
+var ErrTooLong = errors.New("text too long for QR code")
+
+// Per-version layout at level L, indexed by version (index 0 unused).
+var (
+	dataCodewords = [...]int{0, 19, 34, 55, 80, 108}
+	ecCodewords   = [...]int{0, 7, 10, 15, 20, 26}
+	// Position of the single alignment pattern center (versions 2-5).
+	alignmentPos = [...]int{0, 0, 18, 22, 26, 30}
+)
+
+// Code is an encoded QR symbol, without quiet zone.
+type Code struct {
+	Size    int
+	modules [][]bool // [y][x], true = dark
+}
+
+// Dark reports whether the module at column x, row y is dark. Coordinates
+// outside the symbol (e.g. the quiet zone) are light.
+func (c *Code) Dark(x, y int) bool {
+	if x < 0 || y < 0 || x >= c.Size || y >= c.Size {
+		return false
+	}
+	return c.modules[y][x]
+}
+
+// Encode encodes text as the smallest QR symbol that fits.
+func Encode(text string) (*Code, error) {
+	data := []byte(text)
+	version := 0
+	for v := 1; v < len(dataCodewords); v++ {
+		// Mode indicator (4 bits) + 8-bit length + payload.
+		if 4+8+8*len(data) <= 8*dataCodewords[v] {
+			version = v
+			break
+		}
+	}
+	if version == 0 {
+		return nil, ErrTooLong
+	}
+
+	codewords := encodeData(data, dataCodewords[version])
+	codewords = append(codewords, reedSolomon(codewords, ecCodewords[version])...)
+
+	b := newBuilder(version)
+	b.drawFunctionPatterns()
+	b.drawCodewords(codewords)
+
+	// Pick the mask with the lowest penalty, as the spec recommends.
+	best, bestPenalty := 0, -1
+	for mask := 0; mask < 8; mask++ {
+		b.applyMask(mask)
+		b.drawFormatBits(mask)
+		if p := b.penalty(); bestPenalty < 0 || p < bestPenalty {
+			best, bestPenalty = mask, p
+		}
+		b.applyMask(mask) // XOR again to undo
+	}
+	b.applyMask(best)
+	b.drawFormatBits(best)
+
+	return &Code{Size: b.size, modules: b.modules}, nil
+}
+
+// encodeData builds the data codewords: byte mode header, payload,
+// terminator and padding up to capacity.
+func encodeData(data []byte, capacity int) []byte {
+	var bits bitBuffer
+	bits.append(0x4, 4)
+	bits.append(len(data), 8)
+	for _, c := range data {
+		bits.append(int(c), 8)
+	}
+	capBits := capacity * 8
+	term := capBits - bits.len()
+	if term > 4 {
+		term = 4
+	}
+	bits.append(0, term)
+	bits.append(0, (8-bits.len()%8)%8)
+	for pad := 0xEC; bits.len() < capBits; pad ^= 0xEC ^ 0x11 {
+		bits.append(pad, 8)
+	}
+	return bits.bytes()
+}
+
+// bitBuffer accumulates bits MSB first.
+type bitBuffer []bool
+
+func (b *bitBuffer) append(v, n int) {
+	for i := n - 1; i >= 0; i-- {
+		*b = append(*b, (v>>i)&1 != 0)
+	}
+}
+
+func (b bitBuffer) len() int { return len(b) }
+
+func (b bitBuffer) bytes() []byte {
+	out := make([]byte, len(b)/8)
+	for i, bit := range b {
+		if bit {
+			out[i/8] |= 0x80 >> (i % 8)
+		}
+	}
+	return out
+}
+
+// reedSolomon returns the degree error correction codewords for data.
+func reedSolomon(data []byte, degree int) []byte {
+	// Generator polynomial (x - a^0)(x - a^1)...(x - a^(degree-1)),
+	// coefficients from highest to lowest power, leading 1 omitted.
+	gen := make([]byte, degree)
+	gen[degree-1] = 1
+	root := byte(1)
+	for i := 0; i < degree; i++ {
+		for j := 0; j < degree; j++ {
+			gen[j] = gfMul(gen[j], root)
+			if j+1 < degree {
+				gen[j] ^= gen[j+1]
+			}
+		}
+		root = gfMul(root, 0x02)
+	}
+
+	rem := make([]byte, degree)
+	for _, b := range data {
+		factor := b ^ rem[0]
+		copy(rem, rem[1:])
+		rem[degree-1] = 0
+		for i := range rem {
+			rem[i] ^= gfMul(gen[i], factor)
+		}
+	}
+	return rem
+}
+
+// gfMul multiplies in GF(2^8) modulo x^8 + x^4 + x^3 + x^2 + 1.
+func gfMul(x, y byte) byte {
+	var z int
+	for i := 7; i >= 0; i-- {
+		z = (z << 1) ^ ((z >> 7) * 0x11D)
+		if (y>>i)&1 != 0 {
+			z ^= int(x)
+		}
+	}
+	return byte(z)
+}
+
+// builder lays out a symbol.
+type builder struct {
+	version  int
+	size     int
+	modules  [][]bool
+	function [][]bool // modules reserved for patterns and format info
+}
+
+func newBuilder(version int) *builder {
+	size := 17 + 4*version
+	b := &builder{version: version, size: size}
+	b.modules = make([][]bool, size)
+	b.function = make([][]bool, size)
+	for i := range b.modules {
+		b.modules[i] = make([]bool, size)
+		b.function[i] = make([]bool, size)
+	}
+	return b
+}
+
+func (b *builder) set(x, y int, dark bool) {
+	b.modules[y][x] = dark
+	b.function[y][x] = true
+}
+
+// drawFunctionPatterns draws timing, finder and alignment patterns and
+// reserves the format info areas.
+func (b *builder) drawFunctionPatterns() {
+	for i := 0; i < b.size; i++ {
+		b.set(6, i, i%2 == 0)
+		b.set(i, 6, i%2 == 0)
+	}
+	b.drawFinder(3, 3)
+	b.drawFinder(b.size-4, 3)
+	b.drawFinder(3, b.size-4)
+	if p := alignmentPos[b.version]; p > 0 {
+		for dy := -2; dy <= 2; dy++ {
+			for dx := -2; dx <= 2; dx++ {
+				b.set(p+dx, p+dy, max(abs(dx), abs(dy)) != 1)
+			}
+		}
+	}
+	b.drawFormatBits(0) // reserve; overwritten once the mask is chosen
+}
+
+// drawFinder draws a finder pattern with its separator, centered at x, y.
+func (b *builder) drawFinder(x, y int) {
+	for dy := -4; dy <= 4; dy++ {
+		for dx := -4; dx <= 4; dx++ {
+			xx, yy := x+dx, y+dy
+			if xx < 0 || yy < 0 || xx >= b.size || yy >= b.size {
+				continue
+			}
+			d := max(abs(dx), abs(dy))
+			b.set(xx, yy, d != 2 && d != 4)
+		}
+	}
+}
+
+// drawFormatBits writes both copies of the format info for level L and
+// mask, plus the always-dark module.
+func (b *builder) drawFormatBits(mask int) {
+	data := 1<<3 | mask // level L is 01
+	rem := data
+	for i := 0; i < 10; i++ {
+		rem = (rem << 1) ^ ((rem >> 9) * 0x537)
+	}
+	bits := (data<<10 | rem) ^ 0x5412
+	bit := func(i int) bool { return (bits>>i)&1 != 0 }
+
+	for i := 0; i <= 5; i++ {
+		b.set(8, i, bit(i))
+	}
+	b.set(8, 7, bit(6))
+	b.set(8, 8, bit(7))
+	b.set(7, 8, bit(8))
+	for i := 9; i < 15; i++ {
+		b.set(14-i, 8, bit(i))
+	}
+
+	for i := 0; i < 8; i++ {
+		b.set(b.size-1-i, 8, bit(i))
+	}
+	for i := 8; i < 15; i++ {
+		b.set(8, b.size-15+i, bit(i))
+	}
+	b.set(8, b.size-8, true)
+}
+
+// drawCodewords places the codeword bits in the zigzag order.
+func (b *builder) drawCodewords(codewords []byte) {
+	i := 0
+	total := len(codewords) * 8
+	for right := b.size - 1; right >= 1; right -= 2 {
+		if right == 6 {
+			right = 5 // skip the vertical timing pattern
+		}
+		upward := (right+1)&2 == 0
+		for vert := 0; vert < b.size; vert++ {
+			y := vert
+			if upward {
+				y = b.size - 1 - vert
+			}
+			for j := 0; j < 2; j++ {
+				x := right - j
+				if b.function[y][x] || i >= total {
+					continue
+				}
+				b.modules[y][x] = (codewords[i/8]>>(7-i%8))&1 != 0
+				i++
+			}
+		}
+	}
+}
+
+// applyMask XORs the data modules with the given mask pattern.
+func (b *builder) applyMask(mask int) {
+	for y := 0; y < b.size; y++ {
+		for x := 0; x < b.size; x++ {
+			if b.function[y][x] {
+				continue
+			}
+			var invert bool
+			switch mask {
+			case 0:
+				invert = (x+y)%2 == 0
+			case 1:
+				invert = y%2 == 0
+			case 2:
+				invert = x%3 == 0
+			case 3:
+				invert = (x+y)%3 == 0
+			case 4:
+				invert = (x/3+y/2)%2 == 0
+			case 5:
+				invert = x*y%2+x*y%3 == 0
+			case 6:
+				invert = (x*y%2+x*y%3)%2 == 0
+			case 7:
+				invert = ((x+y)%2+x*y%3)%2 == 0
+			}
+			if invert {
+				b.modules[y][x] = !b.modules[y][x]
+			}
+		}
+	}
+}
+
+// penalty scores the symbol by the four rules of the spec; lower is better.
+func (b *builder) penalty() int {
+	n := b.size
+	score := 0
+	at := func(x, y int, row bool) bool {
+		if row {
+			return b.modules[y][x]
+		}
+		return b.modules[x][y]
+	}
+	finderLike := [][]bool{
+		{true, false, true, true, true, false, true, false, false, false, false},
+		{false, false, false, false, true, false, true, true, true, false, true},
+	}
+
+	for _, row := range []bool{true, false} {
+		for y := 0; y < n; y++ {
+			// Rule 1: runs of five or more same-colored modules.
+			run := 1
+			for x := 1; x < n; x++ {
+				if at(x, y, row) == at(x-1, y, row) {
+					run++
+					continue
+				}
+				if run >= 5 {
+					score += run - 2
+				}
+				run = 1
+			}
+			if run >= 5 {
+				score += run - 2
+			}
+			// Rule 3: finder-like 1:1:3:1:1 patterns next to light space.
+			for x := 0; x+11 <= n; x++ {
+				for _, pat := range finderLike {
+					match := true
+					for k, dark := range pat {
+						if at(x+k, y, row) != dark {
+							match = false
+							break
+						}
+					}
+					if match {
+						score += 40
+					}
+				}
+			}
+		}
+	}
+
+	// Rule 2: 2x2 blocks of the same color.
+	dark := 0
+	for y := 0; y < n; y++ {
+		for x := 0; x < n; x++ {
+			if b.modules[y][x] {
+				dark++
+			}
+			if x+1 < n && y+1 < n {
+				c := b.modules[y][x]
+				if c == b.modules[y][x+1] && c == b.modules[y+1][x] && c == b.modules[y+1][x+1] {
+					score += 3
+				}
+			}
+		}
+	}
+
+	// Rule 4: deviation of the dark ratio from 50%.
+	total := n * n
+	k := abs(dark*20-total*10) / total
+	score += k * 10
+	return score
+}
+
+func abs(v int) int {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
@@ -0,0 +1,84 @@
+// Package tos records which version of each server's terms of service the
+// user has accepted, so the client asks again only when the terms change.
+package tos
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/AerNos/firefrp-client/internal/config"
+)
+
+// fileName is the acceptance record inside the config dir.
+const fileName = "tos.json"
+
+// path returns the acceptance record location under the config dir.
+func path() (string, error) {
+	dir, err := config.Dir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, fileName), nil
+}
+
+// load reads the accepted version per server API URL. A missing file
+// yields an empty map.
+func load() (map[string]string, error) {
+	p, err := path()
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(p)
+	if errors.Is(err, fs.ErrNotExist) {
+		return map[string]string{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read accepted terms: %w", err)
+	}
+	accepted := map[string]string{}
+	if err := json.Unmarshal(data, &accepted); err != nil {
+		return nil, fmt.Errorf("failed to parse accepted terms: %w", err)
+	}
+	return accepted, nil
+}
+
+// Accepted reports whether the user has accepted version of the terms of
+// the server at serverURL. A server without terms (empty version) needs
+// no acceptance; an unreadable record counts as not accepted.
+func Accepted(serverURL, version string) bool {
+	if version == "" {
+		return true
+	}
+	accepted, err := load()
+	if err != nil {
+		return false
+	}
+	return accepted[serverURL] == version
+}
+
+// Accept records that the user accepted version of the terms of the
+// server at serverURL, replacing any earlier version.
+func Accept(serverURL, version string) error {
+	accepted, err := load()
+	if err != nil {
+		return err
+	}
+	accepted[serverURL] = version
+
+	data, err := json.MarshalIndent(accepted, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode accepted terms: %w", err)
+	}
+	file, err := path()
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(file, data, 0o600); err != nil {
+		return fmt.Errorf("failed to write accepted terms: %w", err)
+	}
+	return nil
+}
@@ -2,13 +2,25 @@ package tui
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"net"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
 	"time"
 
 	tea "github.com/charmbracelet/bubbletea"
 
 	"github.com/AerNos/firefrp-client/internal/api"
+	"github.com/AerNos/firefrp-client/internal/clipboard"
 	"github.com/AerNos/firefrp-client/internal/config"
+	"github.com/AerNos/firefrp-client/internal/history"
+	"github.com/AerNos/firefrp-client/internal/keylock"
+	"github.com/AerNos/firefrp-client/internal/notify"
+	"github.com/AerNos/firefrp-client/internal/profile"
+	"github.com/AerNos/firefrp-client/internal/tos"
 	"github.com/AerNos/firefrp-client/internal/tui/theme"
 	"github.com/AerNos/firefrp-client/internal/tui/views"
 	"github.com/AerNos/firefrp-client/internal/tunnel"
@@ -26,24 +38,53 @@ const (
 	stateCheckUpdate                  // Checking for client update.
 	stateUpdating                     // Downloading and applying update.
 	stateInput                        // Waiting for user input.
+	stateHistory                      // Browsing recent sessions from the input view.
+	stateProfileSave                  // Naming a profile to save from the input view.
 	stateConnecting                   // Validating key / establishing tunnel.
+	stateAudit                        // Validated; showing the config for review (--audit).
+	stateTOS                          // Asking to accept the server's terms of service.
 	stateRunning                      // Tunnel is active.
-	stateError                        // An error occurred; user can retry.
+	stateSummary                      // Session ended; showing summary before quitting.
+	stateReview                       // Session ended on its own; showing its stats and logs (--stay-on-exit).
+	stateError                        // A connection or session failed; user can retry.
 )
 
 // ---------------------------------------------------------------------------
 // Internal message types exchanged between the state machine and async tasks.
 // ---------------------------------------------------------------------------
 
-// validateResultMsg carries the API validation response.
+// validateResultMsg carries the API validation response. cached is set when
+// the response was reused from validationCache instead of fetched. gen
+// identifies the validation it answers. localIP is the local address to
+// forward to (see --auto-local-ip).
 type validateResultMsg struct {
-	resp *api.ValidateResponse
+	gen     int
+	cached  bool
+	resp    *api.ValidateResponse
+	err     error
+	localIP string
+}
+
+// keyIssuedMsg carries the access key issued for the account token, for
+// the submission identified by gen.
+type keyIssuedMsg struct {
+	gen  int
+	port int
+	key  string
 	err  error
 }
 
 // tunnelStatusMsg carries a tunnel status update from the frpc goroutine.
+// ch identifies the source channel so updates from a tunnel that has since
+// been cancelled can be dropped.
 type tunnelStatusMsg struct {
 	update tunnel.StatusUpdate
+	ch     chan tunnel.StatusUpdate
+}
+
+// tunnelClosedMsg is sent when a tunnel's status channel is closed.
+type tunnelClosedMsg struct {
+	ch chan tunnel.StatusUpdate
 }
 
 // logMsg carries a frpc log entry to be displayed in the running view.
@@ -56,14 +97,104 @@ type errorMsg struct {
 	err error
 }
 
-// updateCheckMsg carries the result of an update check.
+// renewResultMsg carries the outcome of renewing (or re-validating) the
+// key after the server rejected it mid-session (see --auto-renew).
+type renewResultMsg struct {
+	resp    *api.ValidateResponse
+	err     error
+	attempt int
+	active  bool // the status endpoint reports the key still active; resp is nil
+	renewed bool // resp came from the renew endpoint, not from validation
+}
+
+// renewDueMsg triggers a proactive key renewal ahead of expiry (see
+// --renew-before). gen identifies the schedule it belongs to.
+type renewDueMsg struct {
+	gen     int
+	attempt int
+}
+
+// keyExpiredMsg fires when the key in use reaches its expiry, to move on
+// to the next of --keys. gen identifies the schedule it belongs to.
+type keyExpiredMsg struct {
+	gen int
+}
+
+// leaseRenewedMsg carries the outcome of a proactive key renewal.
+type leaseRenewedMsg struct {
+	gen     int
+	attempt int
+	resp    *api.ValidateResponse
+	err     error
+}
+
+// rateLimitTickMsg drives the countdown shown after the server rate-limited
+// validation. until identifies the rate-limit window it belongs to.
+type rateLimitTickMsg struct {
+	until time.Time
+}
+
+// cooldownTickMsg drives the countdown shown while retrying is paused after
+// too many failures. until identifies the pause it belongs to.
+type cooldownTickMsg struct {
+	until time.Time
+}
+
+// networkCheckMsg carries the network fingerprint taken by the
+// --watch-network poll. gen identifies the watch it belongs to.
+type networkCheckMsg struct {
+	gen         int
+	fingerprint string
+}
+
+// networkResolvedMsg carries the local address picked again after a
+// network change, before the tunnel restarts. gen is the network watch's.
+type networkResolvedMsg struct {
+	gen int
+	ip  string
+}
+
+// forcedUpdateMsg starts a forced update once its notice has been shown.
+type forcedUpdateMsg struct {
+	tag string
+}
+
+// summaryDoneMsg quits the program once the session summary has been shown.
+type summaryDoneMsg struct{}
+
+// localTargetCheckedMsg reports whether anything listens on the local
+// address the tunnel was just repointed at.
+type localTargetCheckedMsg struct {
+	addr      string
+	reachable bool
+}
+
+// updateCheckMsg carries the result of an update check. frpVersion is the
+// frps version from server info, when the check fetched it.
 type updateCheckMsg struct {
+	info         *updater.UpdateInfo
+	err          error
+	frpVersion   string
+	channel      string // update channel in effect; empty if unknown
+	motd         string // server's connect message; empty if none
+	tosURL       string // server's terms of service; empty if none
+	tosVersion   string
+	dashboardURL string // server's web dashboard; empty if none
+}
+
+// updateRecheckDueMsg triggers a periodic update re-check while idle on the
+// input view.
+type updateRecheckDueMsg struct{}
+
+// updateRecheckMsg carries the result of a periodic update re-check.
+type updateRecheckMsg struct {
 	info *updater.UpdateInfo
 	err  error
 }
 
 // updateApplyMsg is sent when the update binary download completes.
 type updateApplyMsg struct {
+	tag string
 	err error
 }
 
@@ -80,10 +211,33 @@ type AppModel struct {
 	inputView        views.InputModel
 	connectView      views.ConnectingModel
 	runningView      views.RunningModel
+	summaryView      views.SummaryModel
+	reviewView       views.ReviewModel
+	historyView      views.HistoryModel
+	profileSaveView  views.ProfileSaveModel
+	auditView        views.AuditModel
+	tosView          views.TOSModel
+	errorView        views.ErrorModel
+
+	// Last terminal size, handed to views created after it arrived.
+	windowSize tea.WindowSizeMsg
+
+	// Where the review view (--stay-on-exit) leads: the error view with
+	// reviewCode, or the input view, showing reviewText either way.
+	reviewText    string
+	reviewCode    string
+	reviewToError bool
 
 	// Dependencies injected via Run().
 	config    *config.Config
 	apiClient *api.APIClient
+	// Set once apiClient's API redirect has been logged.
+	redirectNoted bool
+
+	// App-level context: every tunnel context derives from it, so
+	// cancelling it on quit stops anything still running or starting.
+	ctx    context.Context
+	cancel context.CancelFunc
 
 	// Tunnel runtime state.
 	tunnelCfg   *tunnel.TunnelConfig
@@ -91,58 +245,194 @@ type AppModel struct {
 	logCh       chan tunnel.LogEntry
 	pendingLogs []tunnel.LogEntry
 	cancelFn    context.CancelFunc
+	keyLock     *keylock.Lock
+	notified    tunnel.Status // last status shown as a --notify notification
+
+	// Secondary tunnels in mirror mode (see mirror.go). mirrorGen is bumped
+	// whenever the set is torn down so late start results can be dropped.
+	mirrors   []*mirrorTunnel
+	mirrorGen int
+
+	// Last successful validation, reused for a quick cancel-then-retry of
+	// the same key and port (see cachedValidation). tunnelDone is closed
+	// once the most recent tunnel goroutine has fully exited.
+	validated  *validationCache
+	tunnelDone chan struct{}
+
+	// In-flight key validation: validateCancel aborts its request, and
+	// validateGen is bumped per validation (and on abort) so results of an
+	// abandoned one are dropped.
+	validateCancel context.CancelFunc
+	validateGen    int
+
+	// Validation result held while the user reviews it (--audit).
+	auditData *api.ValidateData
+
+	// Byte counter shared by every tunnel of the current session, so
+	// totals survive key renewals. Reset on each new submission.
+	traffic *tunnel.TrafficCounter
+
+	// Visitor IPs across the session with --show-visitors; nil otherwise.
+	sources *tunnel.ConnSources
+
+	// Local address every tunnel of the session forwards to; the running
+	// view can repoint it without reconnecting. Reset on each new submission.
+	localTarget *tunnel.LocalTarget
 
 	// Submitted values (kept for retry).
 	submittedKey  string
 	submittedPort int
 
-	// Server display name (from discovery or URL fallback).
+	// Position of submittedKey in --keys (-1 if it isn't one of them).
+	// Keys before it are used up; keyRotating is set while the next one
+	// is being validated.
+	keyIndex    int
+	keyRotating bool
+
+	// Set when the tunnel was restarted because the status endpoint still
+	// reported the key active; a further rejection then re-validates.
+	resumedByStatus bool
+
+	// Local address the current submission forwards to: --local-ip, or a
+	// LAN address found by --auto-local-ip.
+	localIP string
+
+	// Server display name (from discovery or URL fallback) and API URL.
 	serverName string
+	serverURL  string
+
+	// When the current session first reached Running; recorded in the
+	// session history on teardown. Zero when no session is running.
+	sessionStart time.Time
+
+	// Public host reported by the selected server's server-info, used as a
+	// fallback when the validation response carries no public_addr.
+	serverPublicAddr string
+
+	// Operator message from server-info, and the one shown for the current
+	// connection (the validation response's own, else serverMotd).
+	serverMotd  string
+	connectMotd string
+
+	// Web dashboard of the selected server (from server-info), opened
+	// from the running view.
+	serverDashboard string
+
+	// Terms of service of the selected server (from server-info), the
+	// version accepted this session, and the submission waiting for it.
+	tosURL      string
+	tosVersion  string
+	tosAccepted string
+	tosSubmit   views.SubmitMsg
+
+	// Warning about a possibly incompatible frps version on the selected
+	// server; empty if compatible or unknown.
+	frpWarning string
 
 	// Update info for non-forced dev update notification.
 	pendingUpdate *updater.UpdateInfo
 
+	// Update being downloaded while stateUpdating; offered again with 'u'
+	// if the download fails.
+	applyingUpdate *updater.UpdateInfo
+
+	// Set when the server requires another client version but --no-update
+	// forbids updating; connecting is refused with this message.
+	updateBlocked string
+
+	// Release tag the server required but GitHub doesn't provide; later
+	// forced updates to it are skipped instead of failing again.
+	missingRelease string
+
+	// Download progress from the updater while stateUpdating.
+	updateProgressCh chan updater.Progress
+
 	// Update channel from the server (auto/dev/stable).
 	updateChannel string
 
+	// End of the current rate-limit window after a 429 from validation;
+	// zero when not rate-limited.
+	rateLimitUntil time.Time
+
+	// Times of recent failed attempts within --breaker-window, and the end
+	// of the pause they tripped (zero when not paused). Reset on success.
+	failures      []time.Time
+	cooldownUntil time.Time
+
+	// Network fingerprint last seen by --watch-network, and the generation
+	// of the current watch; stale polls are dropped.
+	netFingerprint string
+	netWatchGen    int
+
 	// ExpiresAt from the API validation response, stored so the running view
 	// can display it once the tunnel is established.
 	expiresAt time.Time
 
+	// Bumped whenever proactive renewal is (re)scheduled, so ticks from an
+	// earlier schedule or session are dropped.
+	renewGen int
+	// Bumped whenever the switch to the next of --keys on expiry is
+	// (re)scheduled, like renewGen.
+	expiryGen int
+
 	err error
 }
 
 // newAppModel initialises the application model with the given config.
 func newAppModel(cfg *config.Config) AppModel {
+	ctx, cancel := context.WithCancel(context.Background())
 	m := AppModel{
 		inputView: views.NewInputModel(),
 		config:    cfg,
+		ctx:       ctx,
+		cancel:    cancel,
 	}
 
 	if cfg.NeedsServerSelect() {
 		// Start with server selection
 		m.state = stateServerSelect
-		m.serverSelectView = views.NewServerSelectModel(cfg.ServerListURL)
+		m.serverSelectView = views.NewServerSelectModel(cfg.ServerListURL, cfg.ServerListAuth, cfg.ProbeTimeout)
+		m.serverSelectView.SetServers(cfg.Servers)
+		m.serverSelectView.SetAutoPick(cfg.AutoServer)
+		m.serverSelectView.SetAutoSingle(cfg.AutoSingleServer)
+		m.serverSelectView.SetProbeConcurrency(cfg.ProbeConcurrency)
+		m.serverSelectView.SetProbeOptions(api.WithInsecureSkipVerify(cfg.InsecureSkipVerify))
 	} else {
 		// Skip server selection; check for updates directly.
 		m.state = stateCheckUpdate
-		m.apiClient = api.NewAPIClient(cfg.ServerURL)
+		m.apiClient = api.NewAPIClient(cfg.ServerURL, api.WithTimeout(cfg.APITimeout), api.WithProxyName(cfg.ProxyName), api.WithKeyPlacement(api.KeyPlacement(cfg.KeyIn)), api.WithInsecureSkipVerify(cfg.InsecureSkipVerify))
 		m.serverName = cfg.ServerURL
+		if cfg.ServerName != "" {
+			m.serverName = cfg.ServerName
+		}
+		m.serverURL = cfg.ServerURL
+	}
+	if cfg.LocalPort > 0 {
+		m.inputView.SetPort(cfg.LocalPort)
+	}
+	if len(cfg.Keys) > 0 {
+		m.inputView.SetKey(cfg.Keys[0])
+	}
+	if cfg.UseToken() {
+		m.inputView.SetTokenMode(true)
 	}
 
 	return m
 }
 
-// Init returns the initial command (delegate to the active sub-view).
+// Init returns the initial command (delegate to the active sub-view) and
+// schedules the periodic update re-check.
 func (m AppModel) Init() tea.Cmd {
+	var cmd tea.Cmd
 	switch m.state {
 	case stateServerSelect:
-		return m.serverSelectView.Init()
+		cmd = m.serverSelectView.Init()
 	case stateCheckUpdate:
-		return m.checkUpdateFromServer(m.config.ServerURL)
+		cmd = m.checkUpdateFromServer(m.config.ServerURL)
 	default:
-		return m.inputView.Init()
+		cmd = m.inputView.Init()
 	}
+	return tea.Batch(cmd, m.scheduleUpdateRecheck())
 }
 
 // Update implements tea.Model. It routes messages to the appropriate sub-view
@@ -153,35 +443,109 @@ func (m AppModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	// -- Global key handling -----------------------------------------------
 	case tea.KeyMsg:
 		// Ctrl+C always quits regardless of state.
-		if msg.String() == "ctrl+c" {
+		if msg.String() == "ctrl+c" && m.state != stateRunning {
 			m.cleanup()
 			return m, tea.Quit
 		}
 
+		// Leaving a running session shows its summary before quitting.
+		if m.state == stateRunning && (msg.String() == "ctrl+c" || (msg.String() == "q" && !m.runningView.EditingTarget())) {
+			return m.showSummary("用户断开")
+		}
+
 		// In input state, 'u' key triggers pending dev update.
 		if m.state == stateInput && msg.String() == "u" && m.pendingUpdate != nil {
 			m.updatingView = views.NewUpdatingModel(m.pendingUpdate.Version)
+			m.resizeViews()
 			m.state = stateUpdating
 			info := m.pendingUpdate
 			m.pendingUpdate = nil
+			m.applyingUpdate = info
 			return m, tea.Batch(m.updatingView.Init(), m.applyUpdate(info.TargetTag))
 		}
 
+		if m.state == stateInput && msg.String() == "ctrl+s" {
+			port, ok := m.inputView.Port()
+			if !ok {
+				m.inputView.SetError("请先填写有效的本地端口再保存配置")
+				return m, nil
+			}
+			m.profileSaveView = views.NewProfileSaveModel(fmt.Sprintf("%s · 端口 %d", m.serverName, port))
+			m.resizeViews()
+			m.state = stateProfileSave
+			return m, m.profileSaveView.Init()
+		}
+
+		if m.state == stateInput && msg.String() == "ctrl+r" {
+			// Only the selected server's sessions can be reconnected here.
+			entries, err := history.Load()
+			m.historyView.SetEntries(history.ForServer(entries, m.serverURL), err)
+			m.state = stateHistory
+			return m, nil
+		}
+
 	// -- Window resize -----------------------------------------------------
 	case tea.WindowSizeMsg:
-		// Forward to all sub-views so they can adapt.
-		m.serverSelectView, _ = m.serverSelectView.Update(msg)
-		m.inputView, _ = m.inputView.Update(msg)
-		m.connectView, _ = m.connectView.Update(msg)
-		m.runningView, _ = m.runningView.Update(msg)
-		m.updatingView, _ = m.updatingView.Update(msg)
+		m.windowSize = msg
+		m.resizeViews()
+		return m, nil
+
+	// -- Session history ---------------------------------------------------
+	case views.HistorySelectedMsg:
+		m.inputView.SetPort(msg.Entry.LocalPort)
+		m.state = stateInput
+		return m, m.inputView.Init()
+
+	case views.HistoryClosedMsg:
+		m.state = stateInput
+		return m, m.inputView.Init()
+
+	case views.HistoryClearMsg:
+		m.historyView.SetEntries(nil, history.ClearServer(m.serverURL))
 		return m, nil
 
+	// -- Saving a connection profile ----------------------------------------
+	case views.ProfileSaveMsg:
+		port, _ := m.inputView.Port()
+		err := profile.Save(profile.Profile{
+			Name:       msg.Name,
+			ServerURL:  m.serverURL,
+			ServerName: m.serverName,
+			LocalIP:    m.config.LocalIP,
+			LocalPort:  port,
+			Label:      m.config.Label,
+		})
+		if err != nil {
+			m.inputView.SetError("保存配置失败: " + err.Error())
+		} else {
+			m.inputView.ClearError()
+			m.inputView.SetNotice(fmt.Sprintf("已保存配置 %s，下次可用 --profile %s 启动", msg.Name, msg.Name))
+		}
+		m.state = stateInput
+		return m, m.inputView.Init()
+
+	case views.ProfileSaveClosedMsg:
+		m.state = stateInput
+		return m, m.inputView.Init()
+
 	// -- Server selected from the selection view ---------------------------
 	case views.ServerSelectedMsg:
-		m.apiClient = api.NewAPIClient(msg.APIUrl)
+		m.apiClient = api.NewAPIClient(msg.APIUrl, api.WithTimeout(m.config.APITimeout), api.WithProxyName(m.config.ProxyName), api.WithKeyPlacement(api.KeyPlacement(m.config.KeyIn)), api.WithInsecureSkipVerify(m.config.InsecureSkipVerify))
+		m.redirectNoted = false
 		m.serverName = msg.ServerName
+		m.serverURL = msg.APIUrl
+		m.serverPublicAddr = msg.PublicAddr
+		m.serverMotd = msg.Motd
+		m.serverDashboard = msg.DashboardURL
+		m.setTOS(msg.TosURL, msg.TosVersion)
+		if msg.Only {
+			m.inputView.SetNotice(fmt.Sprintf("已自动选择唯一可用的服务器 %s (%d ms)", msg.ServerName, msg.Latency.Milliseconds()))
+		} else if msg.Latency > 0 {
+			m.inputView.SetNotice(fmt.Sprintf("已自动选择最快的服务器 %s (%d ms)", msg.ServerName, msg.Latency.Milliseconds()))
+		}
 		m.updateChannel = msg.UpdateChannel
+		m.setFrpVersion(msg.FrpVersion)
+		theme.SetChannel(updater.ResolveChannel(msg.UpdateChannel, msg.ClientVersion, clientVersion))
 
 		// Check for updates using the server-reported client version.
 		if msg.ClientVersion != "" && msg.ClientVersion != "unknown" {
@@ -195,6 +559,21 @@ func (m AppModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 	// -- Update check result -----------------------------------------------
 	case updateCheckMsg:
+		if msg.frpVersion != "" {
+			m.setFrpVersion(msg.frpVersion)
+		}
+		if msg.channel != "" {
+			theme.SetChannel(msg.channel)
+		}
+		if msg.motd != "" {
+			m.serverMotd = msg.motd
+		}
+		if msg.dashboardURL != "" {
+			m.serverDashboard = msg.dashboardURL
+		}
+		if msg.tosVersion != "" {
+			m.setTOS(msg.tosURL, msg.tosVersion)
+		}
 		if msg.err != nil {
 			// Update check failed, continue to input.
 			m.state = stateInput
@@ -208,10 +587,36 @@ func (m AppModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		}
 
 		if msg.info.Force {
-			// Release version mismatch: force update.
+			if m.config.NoUpdate {
+				// Updating is disabled: explain how to upgrade and refuse
+				// to connect to this server.
+				m.updateBlocked = fmt.Sprintf("该服务器要求客户端版本 %s (当前 %s)，已禁用自动更新。请从 %s 下载新版本，或去掉 --no-update 后重新运行",
+					msg.info.Version, clientVersion, updater.ReleasePageURL(msg.info.TargetTag))
+				m.inputView.SetError(m.updateBlocked)
+				m.state = stateInput
+				return m, m.inputView.Init()
+			}
+			if msg.info.TargetTag == m.missingRelease {
+				// Already known to be missing: don't download it again.
+				m.inputView.SetError(missingReleaseText(msg.info.TargetTag))
+				m.state = stateInput
+				return m, m.inputView.Init()
+			}
+			// Release version mismatch: explain, then force the update.
 			m.updatingView = views.NewUpdatingModel(msg.info.Version)
+			m.resizeViews()
+			m.updatingView.SetReason(fmt.Sprintf("该服务器要求客户端版本 %s，正在自动升级", msg.info.Version))
 			m.state = stateUpdating
-			return m, tea.Batch(m.updatingView.Init(), m.applyUpdate(msg.info.TargetTag))
+			m.applyingUpdate = msg.info
+			tag := msg.info.TargetTag
+			return m, tea.Batch(m.updatingView.Init(), tea.Tick(forcedUpdateNotice, func(time.Time) tea.Msg {
+				return forcedUpdateMsg{tag: tag}
+			}))
+		}
+
+		if m.config.NoUpdate || msg.info.TargetTag == m.missingRelease {
+			m.state = stateInput
+			return m, m.inputView.Init()
 		}
 
 		// Dev version: optional update. Store info and show hint.
@@ -220,12 +625,50 @@ func (m AppModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.state = stateInput
 		return m, m.inputView.Init()
 
+	case forcedUpdateMsg:
+		return m, m.applyUpdate(msg.tag)
+
+	case updateRecheckDueMsg:
+		if m.state != stateInput || m.serverURL == "" {
+			// Only check while idle on the input view.
+			return m, m.scheduleUpdateRecheck()
+		}
+		return m, m.recheckUpdate(m.serverURL)
+
+	case updateRecheckMsg:
+		// Only optional updates are offered here; a required update is
+		// handled by the check that runs when a server is selected. A
+		// release already found missing isn't offered again.
+		if msg.err == nil && msg.info != nil && msg.info.Available && !msg.info.Force && msg.info.TargetTag != m.missingRelease {
+			m.pendingUpdate = msg.info
+			m.inputView.SetUpdateHint(fmt.Sprintf("新版本可用: %s  [按 u 更新]", msg.info.Version))
+		}
+		return m, m.scheduleUpdateRecheck()
+
+	// -- Update download progress ------------------------------------------
+	case views.UpdateProgressMsg:
+		m.updatingView, _ = m.updatingView.Update(msg)
+		return m, m.waitForUpdateProgress()
+
 	// -- Update applied ----------------------------------------------------
 	case updateApplyMsg:
 		if msg.err != nil {
 			m.updatingView.Update(views.UpdateErrorMsg{Err: msg.err})
 			// After a failed update, allow continuing to input.
 			m.state = stateInput
+			if errors.Is(msg.err, updater.ErrReleaseNotFound) {
+				m.missingRelease = msg.tag
+				m.inputView.SetError(missingReleaseText(msg.tag))
+				return m, m.inputView.Init()
+			}
+			// Keep the update on offer so a transient failure can be
+			// retried in place.
+			if info := m.applyingUpdate; info != nil {
+				m.pendingUpdate = info
+				m.inputView.SetUpdateHint(fmt.Sprintf("更新 %s 失败  [按 u 重试]", info.Version))
+				m.inputView.SetError("更新失败: " + msg.err.Error() + "，按 u 重试")
+				return m, m.inputView.Init()
+			}
 			m.inputView.SetError("更新失败: " + msg.err.Error())
 			return m, m.inputView.Init()
 		}
@@ -239,69 +682,452 @@ func (m AppModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 	// -- User submits key + port from the input view -----------------------
 	case views.SubmitMsg:
+		// Also reached by a retry from the error view, so refusals go
+		// back to the input form explicitly.
+		if m.updateBlocked != "" {
+			m.inputView.SetError(m.updateBlocked)
+			m.state = stateInput
+			return m, nil
+		}
+		if remaining := time.Until(m.cooldownUntil); remaining > 0 {
+			m.inputView.SetError(cooldownText(remaining))
+			m.state = stateInput
+			return m, nil
+		}
+		if m.frpWarning != "" && m.config.StrictFrpVersion {
+			m.inputView.SetError(m.frpWarning + "，已拒绝连接 (--strict-frp-version)")
+			m.state = stateInput
+			return m, nil
+		}
+		if m.tosVersion != "" && m.tosAccepted != m.tosVersion && !tos.Accepted(m.serverURL, m.tosVersion) {
+			m.tosSubmit = msg
+			m.tosView = views.NewTOSModel(m.serverName, m.tosURL, m.tosVersion)
+			m.resizeViews()
+			m.state = stateTOS
+			return m, nil
+		}
+		if msg.Key == "" && m.config.UseToken() {
+			// Have the server issue a key first, then submit again with it.
+			m.connectView = views.NewConnectingModel("", msg.Port, m.serverName)
+			m.resizeViews()
+			m.connectView.SetPhase(views.PhaseIssuingKey)
+			m.state = stateConnecting
+			return m, tea.Batch(m.connectView.Init(), m.issueKey(msg.Port))
+		}
+		// Refuse early if another local instance is already using this key.
+		// Any lock left over from a failed validation is dropped first.
+		m.keyLock.Release()
+		lock, err := keylock.Acquire(msg.Key)
+		if errors.Is(err, keylock.ErrLocked) {
+			m.inputView.SetError(err.Error())
+			m.state = stateInput
+			return m, nil
+		}
+		m.keyLock = lock
+
 		m.submittedKey = msg.Key
 		m.submittedPort = msg.Port
+		m.keyIndex = slices.Index(m.config.Keys, msg.Key)
+		m.inputView.SetNotice("")
+		m.rateLimitUntil = time.Time{}
+		// Each of these routes the tunnel through a local relay, so only
+		// set them up when needed; the local target is made on the first
+		// change of the local address.
+		m.traffic = nil
+		if views.InfoFieldsShowTraffic(m.config.InfoFields) {
+			m.traffic = &tunnel.TrafficCounter{}
+		}
+		m.localTarget = nil
+		m.sources = nil
+		if m.config.ShowVisitors {
+			m.sources = &tunnel.ConnSources{}
+		}
 
 		// Transition to Connecting (validation phase).
 		m.connectView = views.NewConnectingModel(msg.Key, msg.Port, m.serverName)
+		m.resizeViews()
 		m.state = stateConnecting
 
-		return m, tea.Batch(
-			m.connectView.Init(),
-			m.validateKey(msg.Key),
-		)
+		var validate tea.Cmd
+		if data := m.cachedValidation(msg.Key, msg.Port); data != nil {
+			// Retrying right after a cancel: the server may already see the
+			// key as in use, so reuse the previous validation.
+			m.abortValidation()
+			gen := m.validateGen
+			resolveLocal := m.localIPResolver(msg.Port)
+			validate = func() tea.Msg {
+				return validateResultMsg{gen: gen, cached: true, resp: &api.ValidateResponse{OK: true, Data: data}, localIP: resolveLocal()}
+			}
+		} else {
+			validate = m.validateKey(msg.Key)
+		}
+		return m, tea.Batch(m.connectView.Init(), validate)
+
+	// -- Access key issued for the account token ---------------------------
+	case keyIssuedMsg:
+		if msg.gen != m.validateGen || m.state != stateConnecting {
+			// The user cancelled while the key was being issued.
+			return m, nil
+		}
+		m.noteRedirect()
+		switch {
+		case errors.Is(msg.err, api.ErrTokenExpired):
+			// The token is of no further use: let the user enter a key.
+			m.inputView.SetTokenMode(false)
+			m.inputView.SetError("账户令牌已过期，请重新登录获取新令牌，或手动输入 Access Key")
+		case errors.Is(msg.err, api.ErrIssueKeyUnsupported):
+			m.inputView.SetTokenMode(false)
+			m.inputView.SetError("该服务器不支持账户令牌，请手动输入 Access Key")
+		case msg.err != nil:
+			m.inputView.SetError("获取 Access Key 失败: " + apiErrorText(msg.err))
+		default:
+			return m.Update(views.SubmitMsg{Key: msg.key, Port: msg.port})
+		}
+		m.state = stateInput
+		return m, m.inputView.Init()
 
 	// -- API validation result ---------------------------------------------
 	case validateResultMsg:
-		if msg.err != nil {
-			m.err = msg.err
-			m.inputView.SetError(msg.err.Error())
+		if msg.gen != m.validateGen || m.state != stateConnecting {
+			// The user cancelled (or resubmitted) while validating.
+			return m, nil
+		}
+		m.validateCancel = nil
+		var rateLimited *api.RateLimitedError
+		if errors.As(msg.err, &rateLimited) {
+			// Count down in the input view and retry once the window ends.
+			m.rateLimitUntil = time.Now().Add(rateLimited.RetryAfter)
+			m.inputView.SetError(rateLimitText(rateLimited.RetryAfter))
 			m.state = stateInput
-			return m, m.inputView.Init()
+			return m, tea.Batch(m.inputView.Init(), rateLimitTick(m.rateLimitUntil))
+		}
+		if errors.Is(msg.err, api.ErrNotJSON) {
+			return m.showError("服务器返回了非 JSON 响应，请检查地址是否正确", "")
+		}
+		if msg.err != nil {
+			code := ""
+			var netErr net.Error
+			if errors.As(msg.err, &netErr) {
+				code = codeNetwork
+			}
+			return m.showError(apiErrorText(msg.err), code)
 		}
 		// Check if the server returned an error in the response body.
 		if !msg.resp.OK {
-			errText := "验证失败"
+			m.validated = nil
+			errText, code := "验证失败", ""
 			if msg.resp.Error != nil {
-				errText = mapErrorCode(msg.resp.Error.Code, msg.resp.Error.Message)
+				code = msg.resp.Error.Code
+				errText = mapErrorCode(code, msg.resp.Error.Message)
 			}
-			m.inputView.SetError(errText)
-			m.state = stateInput
-			return m, m.inputView.Init()
+			return m.showError(errText, code)
+		}
+		// A misbehaving server may report success without any data.
+		if msg.resp.Data == nil {
+			m.validated = nil
+			return m.showError("验证成功但未返回连接信息", "")
+		}
+		if !msg.resp.Data.ValidRemotePort() {
+			m.validated = nil
+			return m.showError(fmt.Sprintf("服务器返回的远程端口无效 (%d)", msg.resp.Data.RemotePort), "")
+		}
+		if _, err := msg.resp.Data.Expiry(); err != nil {
+			m.validated = nil
+			return m.showError("服务器返回的到期时间无法识别: "+string(msg.resp.Data.ExpiresAt), "")
 		}
 		// Validation succeeded. Update the connecting view and start tunnel.
+		if !msg.cached {
+			m.validated = &validationCache{
+				key:  m.submittedKey,
+				port: m.submittedPort,
+				api:  m.apiClient,
+				data: msg.resp.Data,
+				at:   time.Now(),
+			}
+		}
 		m.connectView.SetPhase(views.PhaseConnecting)
 		m.connectView.SetRemotePort(msg.resp.Data.RemotePort)
+		m.connectMotd = msg.resp.Data.Motd
+		if m.connectMotd == "" {
+			m.connectMotd = m.serverMotd
+		}
 
-		// Parse and store the expiration time.
-		if t, err := time.Parse(time.RFC3339, msg.resp.Data.ExpiresAt); err == nil {
+		// Parse and store the expiration time, corrected for clock skew.
+		if t, err := msg.resp.Data.Expiry(); err == nil {
 			m.expiresAt = t
 		}
+		m.localIP = m.config.LocalIP
+		if msg.localIP != "" && msg.localIP != m.localIP {
+			m.pendingLogs = append(m.pendingLogs, tunnel.LogEntry{
+				Time:    time.Now().Format("15:04:05"),
+				Level:   "W",
+				Message: fmt.Sprintf("%s 无法连接，已改用局域网地址 %s", tunnel.JoinHostPort(m.localIP, m.submittedPort), tunnel.JoinHostPort(msg.localIP, m.submittedPort)),
+			})
+			m.localIP = msg.localIP
+		}
+		if skew, ok := msg.resp.Data.ClockSkew(); ok && (skew > api.ClockSkewWarning || skew < -api.ClockSkewWarning) {
+			m.pendingLogs = append(m.pendingLogs, tunnel.LogEntry{
+				Time:    time.Now().Format("15:04:05"),
+				Level:   "W",
+				Message: fmt.Sprintf("本机时钟与服务器相差 %s，已按服务器时间计算剩余时间", skew.Abs().Round(time.Second)),
+			})
+		}
+		m.noteRedirect()
+
+		if m.config.Audit {
+			// Let the user review what the server sent before connecting.
+			m.auditData = msg.resp.Data
+			m.auditView.SetContent(m.auditText(msg.resp.Data))
+			m.state = stateAudit
+			return m, nil
+		}
+		return m, tea.Batch(m.startTunnel(msg.resp.Data), m.startMirrors())
+
+	case views.AuditConfirmedMsg:
+		if m.state != stateAudit {
+			return m, nil
+		}
+		data := m.auditData
+		m.auditData = nil
+		m.state = stateConnecting
+		return m, tea.Batch(m.connectView.Init(), m.startTunnel(data), m.startMirrors())
+
+	// -- Key renewal after mid-session rejection ---------------------------
+	case renewResultMsg:
+		if m.state != stateRunning || m.statusCh != nil {
+			// The session ended (or was restarted) while renewing.
+			return m, nil
+		}
+		m.noteRedirect()
+		return m.handleRenewResult(msg)
+
+	case renewDueMsg:
+		if msg.gen != m.renewGen || m.state != stateRunning || m.statusCh == nil {
+			// Superseded, or a rejection-triggered renewal is under way.
+			return m, nil
+		}
+		return m, m.extendLease(msg.gen, msg.attempt)
+
+	case keyExpiredMsg:
+		if msg.gen != m.expiryGen || m.state != stateRunning {
+			return m, nil
+		}
+		if cmd, ok := m.rotateKey(); ok {
+			m.runningView.AddLog(time.Now().Format("15:04:05"), "W", "Key 已到期，切换到下一个 Key")
+			return m, cmd
+		}
+		return m, nil
+
+	case leaseRenewedMsg:
+		if msg.gen != m.renewGen || m.state != stateRunning {
+			return m, nil
+		}
+		m.noteRedirect()
+		return m.handleLeaseRenewed(msg)
+
+	case networkCheckMsg:
+		if msg.gen != m.netWatchGen || m.state != stateRunning {
+			return m, nil
+		}
+		if msg.fingerprint == "" || msg.fingerprint == m.netFingerprint || m.statusCh == nil {
+			// Unchanged, unknown, or a renewal is restarting the tunnel anyway.
+			return m, m.watchNetwork()
+		}
+		m.netFingerprint = msg.fingerprint
+		m.runningView.AddLog(time.Now().Format("15:04:05"), "W", "检测到网络变化，正在重连")
+		m.runningView.SetStatus(views.StatusReconnecting, "网络变化，正在重连...")
+		m.stopTunnel()
+		// The LAN address picked by --auto-local-ip may be gone with the
+		// old network, so pick it again before reconnecting.
+		gen, resolve := m.netWatchGen, m.localIPResolver(m.tunnelCfg.LocalPort)
+		return m, func() tea.Msg {
+			return networkResolvedMsg{gen: gen, ip: resolve()}
+		}
+
+	case networkResolvedMsg:
+		if msg.gen != m.netWatchGen || m.state != stateRunning {
+			return m, nil
+		}
+		if m.statusCh != nil {
+			// A renewal restarted the tunnel meanwhile.
+			return m, m.watchNetwork()
+		}
+		if old := m.tunnelCfg.LocalIP; msg.ip != old {
+			port := m.tunnelCfg.LocalPort
+			if m.localTarget != nil && m.localTarget.Addr() == tunnel.JoinHostPort(old, port) {
+				// Follow the new address unless the user retargeted.
+				m.localTarget.Set(msg.ip, port)
+			}
+			m.localIP = msg.ip
+			m.tunnelCfg.LocalIP = msg.ip
+			addr := tunnel.JoinHostPort(msg.ip, port)
+			if m.localTarget != nil {
+				addr = m.localTarget.Addr()
+			}
+			m.runningView.SetLocalAddr(addr)
+			m.runningView.AddLog(time.Now().Format("15:04:05"), "I", "本地地址已改为 "+addr)
+		}
+		return m, tea.Batch(m.runTunnel(m.tunnelCfg), m.watchNetwork())
+
+	case views.LocalTargetMsg:
+		if m.state != stateRunning {
+			return m, nil
+		}
+		check := func() tea.Msg {
+			return localTargetCheckedMsg{addr: tunnel.JoinHostPort(msg.IP, msg.Port), reachable: tunnel.LocalReachable(msg.IP, msg.Port)}
+		}
+		if m.localTarget == nil {
+			// The tunnel forwards straight to the old address: restart it
+			// (and the mirrors) through a relay that can follow changes.
+			m.localTarget = &tunnel.LocalTarget{}
+			m.localTarget.Set(msg.IP, msg.Port)
+			addr := m.localTarget.Addr()
+			m.runningView.SetLocalAddr(addr)
+			m.runningView.AddLog(time.Now().Format("15:04:05"), "I", "本地映射已切换为 "+addr+"，正在重连，远程地址不变")
+			cmds := []tea.Cmd{check, m.restartMirrors()}
+			m.tunnelCfg.Target = m.localTarget
+			if m.statusCh != nil {
+				m.stopTunnel()
+				m.runningView.SetStatus(views.StatusReconnecting, "正在重连...")
+				cmds = append(cmds, m.runTunnel(m.tunnelCfg))
+			}
+			return m, tea.Batch(cmds...)
+		}
+		m.localTarget.Set(msg.IP, msg.Port)
+		addr := m.localTarget.Addr()
+		m.runningView.SetLocalAddr(addr)
+		m.runningView.AddLog(time.Now().Format("15:04:05"), "I", "本地映射已切换为 "+addr+"，远程地址不变")
+		return m, check
+
+	case localTargetCheckedMsg:
+		if !msg.reachable && m.state == stateRunning && m.localTarget != nil && msg.addr == m.localTarget.Addr() {
+			m.runningView.AddLog(time.Now().Format("15:04:05"), "W", msg.addr+" 暂无服务监听，新连接将失败，请确认服务已启动")
+		}
+		return m, nil
+
+	// -- Mirror tunnels ----------------------------------------------------
+	case mirrorStartedMsg:
+		return m.handleMirrorStarted(msg)
 
-		return m, m.startTunnel(msg.resp.Data)
+	case mirrorStatusMsg:
+		return m.handleMirrorStatus(msg)
 
 	// -- Tunnel status updates ---------------------------------------------
 	case tunnelStatusMsg:
+		if msg.ch != m.statusCh {
+			// Late update from a tunnel we already tore down; ignore it.
+			return m, nil
+		}
 		return m.handleTunnelStatus(msg.update)
 
+	case tunnelClosedMsg:
+		if msg.ch != m.statusCh {
+			// The tunnel already delivered its final status (or was
+			// cancelled by the user), so the close is expected.
+			return m, nil
+		}
+		// Channel closed without a final status: the tunnel went away
+		// unexpectedly.
+		return m.showError("隧道连接已关闭", "")
+
+	// -- Error view --------------------------------------------------------
+	case views.RetryMsg:
+		if m.state != stateError || time.Now().Before(m.cooldownUntil) {
+			return m, nil
+		}
+		return m.Update(views.SubmitMsg{Key: m.submittedKey, Port: m.submittedPort})
+
+	case views.ReviewBackMsg:
+		if m.state != stateReview {
+			return m, nil
+		}
+		if m.reviewToError {
+			return m.showError(m.reviewText, m.reviewCode)
+		}
+		m.inputView.SetError(m.reviewText)
+		m.state = stateInput
+		return m, m.inputView.Init()
+
+	case views.ErrorBackMsg:
+		m.inputView.ClearError()
+		m.state = stateInput
+		return m, m.inputView.Init()
+
 	// -- Tunnel log entries ------------------------------------------------
 	case logMsg:
 		switch m.state {
 		case stateConnecting:
 			m.pendingLogs = append(m.pendingLogs, msg.entry)
 		case stateRunning:
-			m.runningView.AddLog(msg.entry.Time, msg.entry.Level, msg.entry.Message)
+			m.runningView.AddLog(msg.entry.Time, msg.entry.Level, msg.entry.Text(m.config.ShowLogSource))
 		}
 		// Always keep consuming logs as long as the channel is open.
 		return m, m.waitForLog()
 
 	// -- Cancel during connection ------------------------------------------
 	case views.CancelConnectMsg:
+		declinedTOS := m.state == stateTOS
 		m.cleanup()
 		m.state = stateInput
 		m.inputView.ClearError()
+		if declinedTOS {
+			m.inputView.SetError("需同意服务条款后才能连接此服务器")
+		}
 		return m, m.inputView.Init()
 
+	case views.TOSAcceptedMsg:
+		if m.state != stateTOS {
+			return m, nil
+		}
+		// Remember the acceptance for this session even if it can't be
+		// saved; the user is asked again next time in that case.
+		m.tosAccepted = m.tosVersion
+		if err := tos.Accept(m.serverURL, m.tosVersion); err != nil {
+			m.pendingLogs = append(m.pendingLogs, tunnel.LogEntry{
+				Time:    time.Now().Format("15:04:05"),
+				Level:   "W",
+				Message: "无法保存服务条款同意记录: " + err.Error(),
+			})
+		}
+		return m.Update(m.tosSubmit)
+
+	case cooldownTickMsg:
+		if !msg.until.Equal(m.cooldownUntil) {
+			return m, nil
+		}
+		remaining := time.Until(msg.until)
+		if remaining <= 0 {
+			m.cooldownUntil = time.Time{}
+			remaining = 0
+		}
+		if m.state == stateError {
+			m.errorView.SetCooldown(remaining)
+		}
+		if remaining > 0 {
+			return m, cooldownTick(msg.until)
+		}
+		return m, nil
+
+	case rateLimitTickMsg:
+		if !msg.until.Equal(m.rateLimitUntil) || m.state != stateInput {
+			// The user resubmitted or moved on; this countdown is stale.
+			return m, nil
+		}
+		if remaining := time.Until(msg.until); remaining > 0 {
+			m.inputView.SetError(rateLimitText(remaining))
+			return m, rateLimitTick(msg.until)
+		}
+		// Window elapsed: retry the same submission automatically.
+		m.rateLimitUntil = time.Time{}
+		m.inputView.ClearError()
+		m.connectView = views.NewConnectingModel(m.submittedKey, m.submittedPort, m.serverName)
+		m.resizeViews()
+		m.state = stateConnecting
+		return m, tea.Batch(m.connectView.Init(), m.validateKey(m.submittedKey))
+
+	case summaryDoneMsg:
+		return m, tea.Quit
+
 	// -- Generic error -----------------------------------------------------
 	case errorMsg:
 		m.err = msg.err
@@ -319,16 +1145,51 @@ func (m AppModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.updatingView, cmd = m.updatingView.Update(msg)
 	case stateInput:
 		m.inputView, cmd = m.inputView.Update(msg)
+	case stateError:
+		m.errorView, cmd = m.errorView.Update(msg)
+	case stateHistory:
+		m.historyView, cmd = m.historyView.Update(msg)
+	case stateProfileSave:
+		m.profileSaveView, cmd = m.profileSaveView.Update(msg)
+	case stateAudit:
+		m.auditView, cmd = m.auditView.Update(msg)
+	case stateTOS:
+		m.tosView, cmd = m.tosView.Update(msg)
 	case stateConnecting:
 		m.connectView, cmd = m.connectView.Update(msg)
 	case stateRunning:
 		m.runningView, cmd = m.runningView.Update(msg)
+	case stateSummary:
+		m.summaryView, cmd = m.summaryView.Update(msg)
+	case stateReview:
+		m.reviewView, cmd = m.reviewView.Update(msg)
 	}
 	return m, cmd
 }
 
-// View implements tea.Model. It renders the active sub-view.
+// View implements tea.Model. It renders the active sub-view, below a
+// warning banner while TLS certificate verification is disabled.
 func (m AppModel) View() string {
+	view := m.stateView()
+	if m.insecureTLS() {
+		banner := theme.InsecureBannerStyle.Render(theme.GlyphWarning + " 证书校验已禁用 (--insecure-skip-verify)")
+		return banner + "\n" + view
+	}
+	return view
+}
+
+// insecureTLS reports whether the management API is reached without TLS
+// certificate verification. It follows the API client in use, falling back
+// to the flag while no server is selected yet.
+func (m AppModel) insecureTLS() bool {
+	if m.apiClient != nil {
+		return m.apiClient.InsecureSkipVerify()
+	}
+	return m.config.InsecureSkipVerify
+}
+
+// stateView renders the sub-view of the current state.
+func (m AppModel) stateView() string {
 	switch m.state {
 	case stateServerSelect:
 		return m.serverSelectView.View()
@@ -336,12 +1197,26 @@ func (m AppModel) View() string {
 		return m.serverSelectView.View() // Show server list while checking
 	case stateUpdating:
 		return m.updatingView.View()
-	case stateInput, stateError:
+	case stateInput:
 		return m.inputView.View()
+	case stateError:
+		return m.errorView.View()
+	case stateHistory:
+		return m.historyView.View()
+	case stateProfileSave:
+		return m.profileSaveView.View()
+	case stateAudit:
+		return m.auditView.View()
+	case stateTOS:
+		return m.tosView.View()
 	case stateConnecting:
 		return m.connectView.View()
 	case stateRunning:
 		return m.runningView.View()
+	case stateSummary:
+		return m.summaryView.View()
+	case stateReview:
+		return m.reviewView.View()
 	default:
 		return ""
 	}
@@ -361,64 +1236,300 @@ func (m *AppModel) checkUpdate(serverVersion, channel string) tea.Cmd {
 
 // checkUpdateFromServer fetches server info first, then checks for updates.
 func (m *AppModel) checkUpdateFromServer(serverURL string) tea.Cmd {
+	timeout := m.config.APITimeout
+	insecure := m.config.InsecureSkipVerify
 	return func() tea.Msg {
-		client := api.NewAPIClient(serverURL)
+		client := api.NewAPIClient(serverURL, api.WithTimeout(timeout), api.WithInsecureSkipVerify(insecure))
 		info, err := client.FetchServerInfo()
-		if err != nil || info.ClientVersion == "" || info.ClientVersion == "unknown" {
+		if err != nil {
 			// Can't check update, skip.
 			return updateCheckMsg{info: &updater.UpdateInfo{Available: false}, err: nil}
 		}
+		channel := updater.ResolveChannel(info.UpdateChannel, info.ClientVersion, clientVersion)
+		if info.ClientVersion == "" || info.ClientVersion == "unknown" {
+			return updateCheckMsg{info: &updater.UpdateInfo{Available: false}, frpVersion: info.FrpVersion, channel: channel, motd: info.Motd, tosURL: info.TosURL, tosVersion: info.TosVersion, dashboardURL: info.DashboardURL}
+		}
+		result, err := updater.CheckUpdate(info.ClientVersion, clientVersion, info.UpdateChannel)
+		return updateCheckMsg{info: result, err: err, frpVersion: info.FrpVersion, channel: channel, motd: info.Motd, tosURL: info.TosURL, tosVersion: info.TosVersion, dashboardURL: info.DashboardURL}
+	}
+}
+
+// scheduleUpdateRecheck returns a tea.Cmd that triggers the next periodic
+// update re-check, or nil with --no-update.
+func (m *AppModel) scheduleUpdateRecheck() tea.Cmd {
+	if m.config.NoUpdate {
+		return nil
+	}
+	return tea.Tick(updateRecheckInterval, func(time.Time) tea.Msg {
+		return updateRecheckDueMsg{}
+	})
+}
+
+// recheckUpdate returns a tea.Cmd that re-reads the server's client version
+// and checks it for an update, like checkUpdateFromServer but without
+// driving the state machine.
+func (m *AppModel) recheckUpdate(serverURL string) tea.Cmd {
+	timeout := m.config.APITimeout
+	insecure := m.config.InsecureSkipVerify
+	return func() tea.Msg {
+		client := api.NewAPIClient(serverURL, api.WithTimeout(timeout), api.WithInsecureSkipVerify(insecure))
+		info, err := client.FetchServerInfo()
+		if err != nil {
+			return updateRecheckMsg{err: err}
+		}
+		if info.ClientVersion == "" || info.ClientVersion == "unknown" {
+			return updateRecheckMsg{}
+		}
 		result, err := updater.CheckUpdate(info.ClientVersion, clientVersion, info.UpdateChannel)
-		return updateCheckMsg{info: result, err: err}
+		return updateRecheckMsg{info: result, err: err}
 	}
 }
 
-// applyUpdate returns a tea.Cmd that downloads and applies the update.
+// setFrpVersion records the selected server's frps version and warns on the
+// input view if it may not work with the embedded frp client.
+func (m *AppModel) setFrpVersion(v string) {
+	m.frpWarning = tunnel.CheckFrpVersion(v)
+	m.inputView.SetWarning(m.frpWarning)
+}
+
+// missingReleaseText explains that the server requires a release that
+// doesn't exist, and that the current binary can still be tried.
+func missingReleaseText(tag string) string {
+	return fmt.Sprintf("服务器要求的版本不存在，请联系管理员 (%s)。可继续使用当前版本连接 (服务器可能拒绝)，或按 Esc 退出", tag)
+}
+
+// resizeViews forwards the last terminal size to all sub-views so they can
+// adapt. Views are recreated on every visit, so this also runs after each
+// is built.
+func (m *AppModel) resizeViews() {
+	msg := m.windowSize
+	m.serverSelectView, _ = m.serverSelectView.Update(msg)
+	m.inputView, _ = m.inputView.Update(msg)
+	m.connectView, _ = m.connectView.Update(msg)
+	m.runningView, _ = m.runningView.Update(msg)
+	m.updatingView, _ = m.updatingView.Update(msg)
+	m.historyView, _ = m.historyView.Update(msg)
+	m.auditView, _ = m.auditView.Update(msg)
+	m.reviewView, _ = m.reviewView.Update(msg)
+	m.summaryView, _ = m.summaryView.Update(msg)
+	m.profileSaveView, _ = m.profileSaveView.Update(msg)
+	m.tosView, _ = m.tosView.Update(msg)
+	m.errorView, _ = m.errorView.Update(msg)
+}
+
+// applyUpdate returns a tea.Cmd that downloads and applies the update,
+// feeding download progress back into the event loop.
 func (m *AppModel) applyUpdate(tag string) tea.Cmd {
+	progressCh := make(chan updater.Progress, 16)
+	m.updateProgressCh = progressCh
+	assetTemplate := m.config.UpdateAsset
+
+	apply := func() tea.Msg {
+		err := updater.DoUpdate(tag, assetTemplate, func(p updater.Progress) {
+			select {
+			case progressCh <- p:
+			default:
+				// Drop intermediate progress rather than stall the download.
+			}
+		})
+		close(progressCh)
+		return updateApplyMsg{tag: tag, err: err}
+	}
+	return tea.Batch(apply, m.waitForUpdateProgress())
+}
+
+// waitForUpdateProgress returns a tea.Cmd that reads the next download
+// progress notification and wraps it into a views.UpdateProgressMsg.
+func (m *AppModel) waitForUpdateProgress() tea.Cmd {
+	ch := m.updateProgressCh
+	if ch == nil {
+		return nil
+	}
 	return func() tea.Msg {
-		err := updater.DoUpdate(tag)
-		return updateApplyMsg{err: err}
+		p, ok := <-ch
+		if !ok {
+			return nil
+		}
+		return views.UpdateProgressMsg{
+			Downloaded: p.Downloaded,
+			Total:      p.Total,
+			Attempt:    p.Attempt,
+			Retrying:   p.Err != nil,
+		}
 	}
 }
 
-// validateKey returns a tea.Cmd that calls the API to validate the access key.
+// validateKey returns a tea.Cmd that calls the API to validate the access
+// key. The request is tied to a context that abortValidation cancels, so
+// backing out of the connecting view stops it.
 func (m *AppModel) validateKey(key string) tea.Cmd {
+	m.abortValidation()
+	ctx, cancel := context.WithCancel(m.ctx)
+	m.validateCancel = cancel
+	gen := m.validateGen
 	c := m.apiClient
+	resolveLocal := m.localIPResolver(m.submittedPort)
 	return func() tea.Msg {
-		resp, err := c.Validate(key)
-		return validateResultMsg{resp: resp, err: err}
+		defer cancel()
+		resp, err := c.ValidateContext(ctx, key)
+		if err != nil {
+			return validateResultMsg{gen: gen, err: err}
+		}
+		return validateResultMsg{gen: gen, resp: resp, localIP: resolveLocal()}
 	}
 }
 
-// startTunnel returns a tea.Cmd that starts the frpc tunnel in a background
-// goroutine and feeds status updates back into the Bubble Tea event loop.
-func (m *AppModel) startTunnel(data *api.ValidateData) tea.Cmd {
-	cfg := &tunnel.TunnelConfig{
+// issueKey returns a tea.Cmd that has the server issue an access key for
+// the account token (--token). It shares validateGen with validateKey, so
+// a cancel discards the result.
+func (m *AppModel) issueKey(port int) tea.Cmd {
+	m.abortValidation()
+	gen := m.validateGen
+	c := m.apiClient
+	token := m.config.Token
+	return func() tea.Msg {
+		key, err := c.IssueKey(token, port)
+		return keyIssuedMsg{gen: gen, port: port, key: key, err: err}
+	}
+}
+
+// localIPResolver returns a function, safe to call from a tea.Cmd, that
+// picks the local address to forward port to: --local-ip, or with
+// --auto-local-ip a LAN address if nothing answers on the default one.
+func (m *AppModel) localIPResolver(port int) func() string {
+	ip, auto := m.config.LocalIP, m.config.AutoLocalIP
+	return func() string {
+		if auto {
+			ip, _ = tunnel.ResolveLocalIP(ip, port)
+		}
+		return ip
+	}
+}
+
+// abortValidation cancels the in-flight key validation, if any, and makes
+// sure its result is ignored.
+func (m *AppModel) abortValidation() {
+	if m.validateCancel != nil {
+		m.validateCancel()
+		m.validateCancel = nil
+	}
+	m.validateGen++
+}
+
+// validationCacheTTL bounds how long a successful validation is reused.
+const validationCacheTTL = 30 * time.Second
+
+// validationCache remembers the last successful validation.
+type validationCache struct {
+	key  string
+	port int
+	api  *api.APIClient // server the key was validated against
+	data *api.ValidateData
+	at   time.Time
+}
+
+// cachedValidation returns the cached validation data for key and port if
+// it is still fresh, came from the current server, the key hasn't expired,
+// and the previous tunnel has been fully released. Otherwise nil.
+func (m *AppModel) cachedValidation(key string, port int) *api.ValidateData {
+	c := m.validated
+	if c == nil || c.key != key || c.port != port || c.api != m.apiClient {
+		return nil
+	}
+	if time.Since(c.at) > validationCacheTTL {
+		return nil
+	}
+	if t, err := c.data.Expiry(); err == nil && !time.Now().Before(t) {
+		return nil
+	}
+	if m.tunnelDone != nil {
+		select {
+		case <-m.tunnelDone:
+		default:
+			// The old tunnel is still shutting down; validate afresh.
+			return nil
+		}
+	}
+	return c.data
+}
+
+// newTunnelConfig builds the primary tunnel's configuration from a
+// validation response and the submitted values.
+func (m *AppModel) newTunnelConfig(data *api.ValidateData) *tunnel.TunnelConfig {
+	publicAddr := data.PublicAddr
+	if publicAddr == "" {
+		publicAddr = m.serverPublicAddr
+	}
+	return &tunnel.TunnelConfig{
 		ServerAddr: data.FrpsAddr,
 		ServerPort: data.FrpsPort,
+		Fallbacks:  data.FallbackEndpoints(),
+		PublicAddr: publicAddr,
 		Token:      data.Token,
+		AuthMethod: data.AuthMethod,
+		OIDC:       (*tunnel.OIDCConfig)(data.OIDC),
 		ProxyName:  data.ProxyName,
-		LocalIP:    m.config.LocalIP,
+		LocalIP:    m.localIP,
 		LocalPort:  m.submittedPort,
 		RemotePort: data.RemotePort,
 		AccessKey:  m.submittedKey,
+
+		Metadata:    tunnel.ClientMetadata(clientVersion, m.config.Label, m.config.Metadata),
+		IdleTimeout: m.config.IdleTimeout,
+		Traffic:     m.traffic,
+		Sources:     m.sources,
+		Target:      m.localTarget,
+		ProxyURL:    m.config.TunnelProxy,
 	}
+}
+
+// auditText formats what --audit shows before connecting: the server's
+// response and the frpc config derived from it.
+func (m *AppModel) auditText(data *api.ValidateData) string {
+	cfg := m.newTunnelConfig(data)
+	return "服务器返回:\n" + data.AuditJSON(m.config.ShowToken) +
+		"\n\nfrpc 配置:\n" + cfg.FrpConfigJSON(m.config.ShowToken)
+}
+
+// startTunnel returns a tea.Cmd that starts the frpc tunnel in a background
+// goroutine and feeds status updates back into the Bubble Tea event loop.
+func (m *AppModel) startTunnel(data *api.ValidateData) tea.Cmd {
+	cfg := m.newTunnelConfig(data)
 	m.tunnelCfg = cfg
 
+	// Surface port warnings in the log panel once the tunnel is running.
+	if !m.config.NoPortWarnings && m.state == stateConnecting {
+		now := time.Now().Format("15:04:05")
+		for _, w := range cfg.Warnings() {
+			m.pendingLogs = append(m.pendingLogs, tunnel.LogEntry{Time: now, Level: "W", Message: w})
+		}
+	}
+
+	return m.runTunnel(cfg)
+}
+
+// runTunnel starts the tunnel described by cfg in a background goroutine
+// and feeds status updates back into the Bubble Tea event loop. The
+// caller must have stopped any previous tunnel.
+func (m *AppModel) runTunnel(cfg *tunnel.TunnelConfig) tea.Cmd {
 	statusCh := make(chan tunnel.StatusUpdate, 16)
 	m.statusCh = statusCh
 
 	logCh := make(chan tunnel.LogEntry, 64)
 	m.logCh = logCh
 
-	ctx, cancel := context.WithCancel(context.Background())
+	ctx, cancel := context.WithCancel(m.ctx)
 	m.cancelFn = cancel
 
+	done := make(chan struct{})
+	m.tunnelDone = done
+
 	// Start tunnel in background goroutine.
 	go func() {
 		_ = tunnel.StartTunnel(ctx, *cfg, statusCh, logCh)
 		close(statusCh)
 		close(logCh)
+		close(done)
 	}()
 
 	// Return commands that wait for both status updates and log entries.
@@ -435,9 +1546,9 @@ func (m *AppModel) waitForStatus() tea.Cmd {
 	return func() tea.Msg {
 		update, ok := <-ch
 		if !ok {
-			return errorMsg{err: fmt.Errorf("隧道连接已关闭")}
+			return tunnelClosedMsg{ch: ch}
 		}
-		return tunnelStatusMsg{update: update}
+		return tunnelStatusMsg{update: update, ch: ch}
 	}
 }
 
@@ -460,51 +1571,123 @@ func (m *AppModel) waitForLog() tea.Cmd {
 // handleTunnelStatus processes a tunnel status update and transitions state.
 func (m AppModel) handleTunnelStatus(u tunnel.StatusUpdate) (tea.Model, tea.Cmd) {
 	switch u.Status {
+	case tunnel.StatusConnecting:
+		// Intermediate progress while establishing the tunnel.
+		if m.state == stateConnecting {
+			m.connectView.SetDetail(u.Message)
+		}
+		return m, m.waitForStatus()
+
 	case tunnel.StatusConnected:
+		if u.Endpoint != "" {
+			m.tunnelCfg.UseEndpoint(u.Endpoint)
+		}
+		remoteAddr := m.tunnelCfg.RemoteAddr()
+		notifyCmd := m.notify(u.Status, "隧道已连接", remoteAddr)
+		if m.state == stateRunning {
+			// Reconnected after a renewal: keep the running view (logs,
+			// uptime, size) and only refresh its status.
+			m.runningView.SetStatus(views.StatusConnected, "已连接")
+			m.runningView.SetEndpoint(u.Endpoint, remoteAddr)
+			m.writeEndpoint()
+			m.failures = nil
+			m.resumedByStatus = false
+			return m, tea.Batch(notifyCmd, m.waitForStatus())
+		}
 		// Build the running view with connection details.
-		remoteAddr := fmt.Sprintf("%s:%d", m.tunnelCfg.ServerAddr, m.tunnelCfg.RemotePort)
-		localAddr := fmt.Sprintf("%s:%d", m.tunnelCfg.LocalIP, m.tunnelCfg.LocalPort)
-		m.runningView = views.NewRunningModel(m.serverName, remoteAddr, localAddr, m.expiresAt)
+		m.runningView = views.NewRunningModel(m.serverName, remoteAddr, m.tunnelCfg.LocalAddr(), m.expiresAt)
+		m.resizeViews()
+		m.runningView.SetWrapLogs(m.config.WrapLogs)
+		if unknown := m.runningView.SetInfoFields(m.config.InfoFields); len(unknown) > 0 {
+			m.pendingLogs = append(m.pendingLogs, tunnel.LogEntry{
+				Time:    time.Now().Format("15:04:05"),
+				Level:   "W",
+				Message: "--info-fields 中的未知字段已忽略: " + strings.Join(unknown, ", "),
+			})
+		}
+		m.runningView.SetCompact(m.config.Compact)
+		if m.insecureTLS() {
+			m.runningView.SetBannerRows(1)
+		}
+		if m.traffic != nil {
+			m.runningView.SetTrafficSource(m.traffic.Bytes)
+		}
+		if m.sources != nil {
+			sources := m.sources
+			m.runningView.SetSources(func() []views.Visitor {
+				recent := sources.Recent()
+				visitors := make([]views.Visitor, len(recent))
+				for i, s := range recent {
+					visitors[i] = views.Visitor(s)
+				}
+				return visitors
+			})
+		}
+		m.runningView.SetMirrors(m.mirrorViews())
+		m.runningView.SetMotd(m.connectMotd)
+		m.runningView.SetDashboardURL(m.serverDashboard)
+		m.runningView.SetAutoRenew(m.config.AutoRenew, m.config.RenewBefore)
+		m.runningView.SetEndpoint(u.Endpoint, remoteAddr)
 		// Flush any log entries buffered during the connecting phase.
 		for _, entry := range m.pendingLogs {
-			m.runningView.AddLog(entry.Time, entry.Level, entry.Message)
+			m.runningView.AddLog(entry.Time, entry.Level, entry.Text(m.config.ShowLogSource))
 		}
 		m.pendingLogs = nil
+		m.writeEndpoint()
+		m.sessionStart = time.Now()
+		m.failures = nil
 		m.state = stateRunning
-		return m, tea.Batch(m.runningView.Init(), m.waitForStatus())
+		return m, tea.Batch(notifyCmd, m.runningView.Init(), m.waitForStatus(), m.scheduleRenew(), m.scheduleExpiry(), m.startNetworkWatch())
 
 	case tunnel.StatusReconnecting:
 		if m.state == stateRunning {
 			m.runningView.SetStatus(views.StatusReconnecting, "正在重连...")
+			m.runningView.SetReconnect(u.Attempt)
 		}
 		return m, m.waitForStatus()
 
 	case tunnel.StatusError:
-		if m.state == stateRunning {
+		notifyCmd := m.notify(u.Status, "隧道连接异常", m.tunnelCfg.RemoteAddr()+"\n"+u.Message)
+		if m.state == stateRunning && !u.Final {
 			m.runningView.SetStatus(views.StatusError, u.Message)
-			return m, m.waitForStatus()
+			return m, tea.Batch(notifyCmd, m.waitForStatus())
 		}
-		// If we haven't reached Running yet, fall back to input.
-		m.err = fmt.Errorf("%s", u.Message)
-		m.inputView.SetError(u.Message)
-		m.state = stateInput
-		m.cleanup()
-		return m, m.inputView.Init()
+		// The tunnel has stopped (or never reached Running).
+		model, cmd := m.showError(u.Message, "")
+		return model, tea.Batch(notifyCmd, cmd)
 
 	case tunnel.StatusRejected:
-		m.cleanup()
+		notifyCmd := m.notify(u.Status, "隧道被服务器拒绝", m.tunnelCfg.RemoteAddr()+"\n"+u.Message)
+		m.validated = nil
+		if m.state == stateRunning && m.config.AutoRenew {
+			// Rejected mid-session, most likely because the key expired.
+			// Try to renew it before giving up on the session.
+			m.stopTunnel()
+			m.runningView.SetStatus(views.StatusReconnecting, "Key 已失效，正在自动续期...")
+			return m, tea.Batch(notifyCmd, m.renewKey(1))
+		}
+		if cmd, ok := m.rotateKey(); ok {
+			return m, tea.Batch(notifyCmd, cmd)
+		}
 		errMsg := "连接被服务器拒绝"
 		if u.Message != "" {
 			errMsg = u.Message
 		}
-		m.err = fmt.Errorf("%s", errMsg)
-		m.inputView.SetError(errMsg)
-		m.state = stateInput
-		return m, m.inputView.Init()
+		model, cmd := m.showError(errMsg, "")
+		return model, tea.Batch(notifyCmd, cmd)
 
 	case tunnel.StatusClosed:
+		reason := "隧道已断开"
+		if errors.Is(u.Error, tunnel.ErrIdleTimeout) {
+			reason = u.Message
+		}
+		if m.config.StayOnExit && m.state == stateRunning {
+			return m.showReview(reason, "", false)
+		}
+		// The tunnel ended cleanly, so this is news rather than an error;
+		// unexpected closes arrive as tunnelClosedMsg instead.
 		m.cleanup()
-		m.inputView.SetError("隧道已断开")
+		m.inputView.SetNotice(reason)
 		m.state = stateInput
 		return m, m.inputView.Init()
 	}
@@ -513,17 +1696,494 @@ func (m AppModel) handleTunnelStatus(u tunnel.StatusUpdate) (tea.Model, tea.Cmd)
 	return m, m.waitForStatus()
 }
 
-// cleanup cancels the tunnel context. The tunnel goroutine is responsible for
-// closing the status and log channels after it exits.
-func (m *AppModel) cleanup() {
+// notify returns a tea.Cmd that shows a desktop notification with
+// --notify, off the event loop since notifiers may spawn a process. Only a
+// change of status notifies, so a tunnel that keeps reconnecting announces
+// its first connect rather than every one. Where no notification can be
+// shown (no desktop, SSH session) nothing happens.
+func (m *AppModel) notify(status tunnel.Status, title, body string) tea.Cmd {
+	if !m.config.Notify || status == m.notified {
+		return nil
+	}
+	m.notified = status
+	return func() tea.Msg {
+		_ = notify.Send("FireFrp: "+title, strings.TrimSpace(body))
+		return nil
+	}
+}
+
+// maxRenewAttempts bounds how often a rejected key is re-validated before
+// the session is given up; renewRetryDelay spaces out the attempts so the
+// server has time to roll the key over.
+const (
+	maxRenewAttempts = 3
+	renewRetryDelay  = 5 * time.Second
+)
+
+// renewKey returns a tea.Cmd that renews the submitted key through the
+// renew endpoint, re-validating it only on servers without one or when it
+// is a key of --keys switched to just now. Attempts after the first are
+// delayed by renewRetryDelay. The first attempt asks the status endpoint
+// first, and skips renewal if the key is still active there.
+func (m *AppModel) renewKey(attempt int) tea.Cmd {
+	c := m.apiClient
+	key := m.submittedKey
+	rotating := m.keyRotating
+	checkStatus := attempt == 1 && !rotating && !m.resumedByStatus
+	validate := func() tea.Msg {
+		if checkStatus {
+			if st, err := c.Status(key); err == nil && st.Active {
+				return renewResultMsg{active: true, attempt: attempt}
+			}
+		}
+		if !rotating {
+			resp, err := c.Renew(key)
+			if !errors.Is(err, api.ErrRenewUnsupported) {
+				return renewResultMsg{resp: resp, err: err, attempt: attempt, renewed: true}
+			}
+		}
+		resp, err := c.Validate(key)
+		return renewResultMsg{resp: resp, err: err, attempt: attempt}
+	}
+	if attempt == 1 {
+		return validate
+	}
+	return tea.Tick(renewRetryDelay, func(time.Time) tea.Msg {
+		return validate()
+	})
+}
+
+// handleRenewResult restarts the tunnel if the key could be renewed, retries
+// on transient failures, and ends the session once renewal is impossible.
+func (m AppModel) handleRenewResult(msg renewResultMsg) (tea.Model, tea.Cmd) {
+	var errText, code string
+	switch {
+	case msg.active:
+		m.resumedByStatus = true
+		m.runningView.SetStatus(views.StatusReconnecting, "Key 仍有效，正在重连...")
+		return m, m.runTunnel(m.tunnelCfg)
+	case msg.err != nil:
+		errText = apiErrorText(msg.err)
+	case !msg.resp.OK:
+		errText = "验证失败"
+		if msg.resp.Error != nil {
+			code = msg.resp.Error.Code
+			errText = mapErrorCode(code, msg.resp.Error.Message)
+			if msg.resp.Error.Permanent() {
+				// Revoked or gone: no point retrying.
+				return m.endRenewal(errText, code)
+			}
+		}
+	case msg.renewed:
+		// The allocation is unchanged, so reconnect with the same config.
+		if msg.resp.Data != nil {
+			if t, err := msg.resp.Data.Expiry(); err == nil {
+				m.expiresAt = t
+				m.runningView.SetExpiresAt(t)
+			}
+		}
+		m.runningView.SetStatus(views.StatusReconnecting, "续期成功，正在重连...")
+		return m, tea.Batch(m.runTunnel(m.tunnelCfg), m.scheduleRenew(), m.scheduleExpiry())
+	case msg.resp.Data == nil:
+		errText = "验证成功但未返回连接信息"
+	case !msg.resp.Data.ValidRemotePort():
+		errText = fmt.Sprintf("服务器返回的远程端口无效 (%d)", msg.resp.Data.RemotePort)
+	default:
+		if t, err := msg.resp.Data.Expiry(); err == nil {
+			m.expiresAt = t
+			m.runningView.SetExpiresAt(t)
+		}
+		status := "续期成功，正在重连..."
+		if m.keyRotating {
+			status = "已切换到下一个 Key，正在重连..."
+			m.keyRotating = false
+		}
+		m.runningView.SetStatus(views.StatusReconnecting, status)
+		return m, tea.Batch(m.startTunnel(msg.resp.Data), m.scheduleRenew(), m.scheduleExpiry())
+	}
+
+	if msg.attempt >= maxRenewAttempts {
+		// Only a permanent rejection moves on to the next of --keys; the
+		// key may still be fine after transient failures.
+		return m.showError(errText, code)
+	}
+	m.runningView.SetStatus(views.StatusReconnecting,
+		fmt.Sprintf("续期失败 (%s)，稍后重试 (%d/%d)...", errText, msg.attempt, maxRenewAttempts))
+	return m, m.renewKey(msg.attempt + 1)
+}
+
+// endRenewal gives up on the key being renewed or switched to: the next
+// of --keys is tried if there is one, else the session ends with errText.
+func (m AppModel) endRenewal(errText, code string) (tea.Model, tea.Cmd) {
+	if cmd, ok := m.rotateKey(); ok {
+		return m, cmd
+	}
+	return m.showError(errText, code)
+}
+
+// rotateKey switches the running session to the next unused key of --keys
+// after the current one expired or was rejected. The new key is validated
+// through the renewal path, so the running view stays up and the tunnel
+// restarts without asking. It reports false once every key is used up.
+func (m *AppModel) rotateKey() (tea.Cmd, bool) {
+	if m.state != stateRunning {
+		return nil, false
+	}
+	// keyIndex is -1 when the submitted key isn't one of --keys; the
+	// rotation then starts with the first of them.
+	for i := max(m.keyIndex+1, 0); i < len(m.config.Keys); i++ {
+		m.keyIndex = i
+		key := m.config.Keys[i]
+		if key == m.submittedKey {
+			// The key that just failed, listed again.
+			continue
+		}
+		m.keyLock.Release()
+		lock, err := keylock.Acquire(key)
+		if errors.Is(err, keylock.ErrLocked) {
+			// Another local instance is using it: skip it as used.
+			m.keyLock = nil
+			continue
+		}
+		m.keyLock = lock
+		m.stopTunnel()
+		m.submittedKey = key
+		m.keyRotating = true
+		m.runningView.SetStatus(views.StatusReconnecting,
+			fmt.Sprintf("Key 已失效，切换到下一个 Key (%d/%d)...", m.keyIndex+1, len(m.config.Keys)))
+		return m.renewKey(1), true
+	}
+	return nil, false
+}
+
+// scheduleExpiry arranges the switch to the next of --keys once the
+// current key expires, superseding any earlier schedule. It returns nil
+// when there is no further key or the expiry is unknown.
+func (m *AppModel) scheduleExpiry() tea.Cmd {
+	m.expiryGen++
+	if m.keyIndex+1 >= len(m.config.Keys) || m.expiresAt.IsZero() {
+		return nil
+	}
+	gen := m.expiryGen
+	return tea.Tick(max(time.Until(m.expiresAt), 0), func(time.Time) tea.Msg {
+		return keyExpiredMsg{gen: gen}
+	})
+}
+
+// scheduleRenew arranges a proactive renewal --renew-before ahead of the
+// current expiry, superseding any earlier schedule. It returns nil when
+// auto-renew is off or the expiry is unknown.
+func (m *AppModel) scheduleRenew() tea.Cmd {
+	m.renewGen++
+	if !m.config.AutoRenew || m.expiresAt.IsZero() {
+		return nil
+	}
+	gen := m.renewGen
+	delay := time.Until(m.expiresAt.Add(-m.config.RenewBefore))
+	if delay < 0 {
+		delay = 0
+	}
+	return tea.Tick(delay, func(time.Time) tea.Msg {
+		return renewDueMsg{gen: gen, attempt: 1}
+	})
+}
+
+// extendLease asks the server to extend the running key. Retries are
+// delayed like rejection-triggered renewals.
+func (m *AppModel) extendLease(gen, attempt int) tea.Cmd {
+	c := m.apiClient
+	key := m.submittedKey
+	renew := func() tea.Msg {
+		resp, err := c.Renew(key)
+		return leaseRenewedMsg{gen: gen, attempt: attempt, resp: resp, err: err}
+	}
+	if attempt == 1 {
+		return renew
+	}
+	return tea.Tick(renewRetryDelay, func(time.Time) tea.Msg {
+		return renew()
+	})
+}
+
+// handleLeaseRenewed applies a proactive renewal and schedules the next
+// one. Failures are shown on the running view's auto-renew line; the
+// tunnel keeps running until the key actually expires.
+func (m AppModel) handleLeaseRenewed(msg leaseRenewedMsg) (tea.Model, tea.Cmd) {
+	var errText string
+	switch {
+	case errors.Is(msg.err, api.ErrRenewUnsupported):
+		m.runningView.SetRenewProblem("服务器不支持续期")
+		return m, nil
+	case msg.err != nil:
+		errText = apiErrorText(msg.err)
+	case !msg.resp.OK:
+		errText = "续期被拒绝"
+		if msg.resp.Error != nil {
+			errText = mapErrorCode(msg.resp.Error.Code, msg.resp.Error.Message)
+		}
+	case msg.resp.Data == nil:
+		errText = "未返回新的到期时间"
+	default:
+		t, err := msg.resp.Data.Expiry()
+		if err != nil || !t.After(m.expiresAt) || time.Until(t) <= m.config.RenewBefore {
+			// Not extended past the renewal window: rescheduling would
+			// only ask again right away.
+			m.runningView.SetRenewProblem("服务器未延长有效期")
+			return m, nil
+		}
+		m.expiresAt = t
+		m.runningView.NotifyRenewed(t)
+		m.writeEndpoint()
+		return m, tea.Batch(m.scheduleRenew(), m.scheduleExpiry())
+	}
+
+	if msg.attempt >= maxRenewAttempts {
+		m.runningView.SetRenewProblem("续期失败: " + errText)
+		return m, nil
+	}
+	return m, m.extendLease(msg.gen, msg.attempt+1)
+}
+
+// rateLimitTick schedules the next rate-limit countdown update.
+func rateLimitTick(until time.Time) tea.Cmd {
+	return tea.Tick(time.Second, func(time.Time) tea.Msg {
+		return rateLimitTickMsg{until: until}
+	})
+}
+
+// apiErrorText describes a failed API request for display.
+func apiErrorText(err error) string {
+	var cross *api.CrossOriginRedirectError
+	if errors.As(err, &cross) {
+		return fmt.Sprintf("服务器将 API 迁移到了其他主机 %s，为安全起见未发送凭据；如信任该地址，请改用它作为服务器地址", api.RedactURL(cross.To))
+	}
+	return err.Error()
+}
+
+// noteRedirect logs, once per API client, that the server moved its API.
+// Before the tunnel is up the entry waits in pendingLogs.
+func (m *AppModel) noteRedirect() {
+	from, ok := m.apiClient.Redirected()
+	if !ok || m.redirectNoted {
+		return
+	}
+	m.redirectNoted = true
+	now := time.Now().Format("15:04:05")
+	text := fmt.Sprintf("服务器 API 已从 %s 迁移至 %s", api.RedactURL(from), api.RedactURL(m.apiClient.BaseURL()))
+	if m.state == stateRunning {
+		m.runningView.AddLog(now, "I", text)
+		return
+	}
+	m.pendingLogs = append(m.pendingLogs, tunnel.LogEntry{Time: now, Level: "I", Message: text})
+}
+
+// rateLimitText formats the rate-limit notice for the remaining wait.
+func rateLimitText(remaining time.Duration) string {
+	secs := int((remaining + time.Second - 1) / time.Second)
+	return fmt.Sprintf("请求过于频繁，请 %d 秒后重试", secs)
+}
+
+// updateRecheckInterval is how often the client re-checks for an optional
+// update while left on the input view. Hours apart, it stays well within
+// GitHub's rate limit for unauthenticated API calls.
+const updateRecheckInterval = 4 * time.Hour
+
+// forcedUpdateNotice is how long the reason for a forced update is shown
+// before the download starts.
+const forcedUpdateNotice = 2 * time.Second
+
+// summaryDuration is how long the session summary stays up before quitting.
+const summaryDuration = 3 * time.Second
+
+// showSummary stops the session and shows its summary briefly before the
+// program quits.
+func (m AppModel) showSummary(reason string) (tea.Model, tea.Cmd) {
+	m.cleanup()
+	m.summaryView = views.NewSummaryModel(m.runningView.Summary(reason))
+	m.resizeViews()
+	m.state = stateSummary
+	return m, tea.Tick(summaryDuration, func(time.Time) tea.Msg {
+		return summaryDoneMsg{}
+	})
+}
+
+// showReview tears the running session down and keeps its stats and
+// logs on screen (--stay-on-exit). Leaving the review continues to the
+// error view (toError, with code) or the input view, showing reason.
+func (m AppModel) showReview(reason, code string, toError bool) (tea.Model, tea.Cmd) {
+	m.cleanup()
+	m.reviewView = views.NewReviewModel(m.runningView, reason)
+	m.reviewText, m.reviewCode, m.reviewToError = reason, code, toError
+	m.state = stateReview
+	return m, nil
+}
+
+// showError tears the session down and shows errText in the error view,
+// with a next step suggested from the server error code (empty if the
+// failure didn't come from the server). The submitted key and port are kept
+// for a one-key retry.
+func (m AppModel) showError(errText, code string) (tea.Model, tea.Cmd) {
+	if m.config.StayOnExit && m.state == stateRunning {
+		return m.showReview(errText, code, true)
+	}
+	m.cleanup()
+	m.err = fmt.Errorf("%s", errText)
+	hint := suggestAction(code)
+	if code == "" && m.frpWarning != "" {
+		// A likely cause of otherwise cryptic login failures.
+		hint = m.frpWarning
+	}
+	m.errorView = views.NewErrorModel(errText, hint)
+	m.resizeViews()
+	m.state = stateError
+	if !m.recordFailure() {
+		return m, nil
+	}
+	m.errorView.SetCooldown(time.Until(m.cooldownUntil))
+	return m, cooldownTick(m.cooldownUntil)
+}
+
+// networkPollInterval is how often --watch-network checks the network
+// interfaces for changes.
+const networkPollInterval = 3 * time.Second
+
+// startNetworkWatch begins polling for network changes if --watch-network
+// is set, superseding any earlier watch.
+func (m *AppModel) startNetworkWatch() tea.Cmd {
+	m.netWatchGen++
+	if !m.config.WatchNetwork {
+		return nil
+	}
+	m.netFingerprint = tunnel.NetworkFingerprint()
+	return m.watchNetwork()
+}
+
+// watchNetwork schedules the next network poll of the current watch.
+func (m *AppModel) watchNetwork() tea.Cmd {
+	gen := m.netWatchGen
+	return tea.Tick(networkPollInterval, func(time.Time) tea.Msg {
+		return networkCheckMsg{gen: gen, fingerprint: tunnel.NetworkFingerprint()}
+	})
+}
+
+// setTOS records the selected server's terms of service, forgetting any
+// acceptance from this session.
+func (m *AppModel) setTOS(url, version string) {
+	m.tosAccepted = ""
+	m.tosURL = url
+	m.tosVersion = version
+}
+
+// breakerCooldown is how long retrying is paused once --breaker-failures
+// attempts failed within --breaker-window.
+const breakerCooldown = time.Minute
+
+// recordFailure counts a failed attempt and reports whether it tripped the
+// pause, in which case cooldownUntil is set and the count starts over.
+func (m *AppModel) recordFailure() bool {
+	if m.config.BreakerFailures <= 0 {
+		return false
+	}
+	now := time.Now()
+	recent := m.failures[:0]
+	for _, t := range m.failures {
+		if now.Sub(t) < m.config.BreakerWindow {
+			recent = append(recent, t)
+		}
+	}
+	m.failures = append(recent, now)
+	if len(m.failures) < m.config.BreakerFailures {
+		return false
+	}
+	m.failures = nil
+	m.cooldownUntil = now.Add(breakerCooldown)
+	return true
+}
+
+// cooldownTick schedules the next countdown update for the pause ending at
+// until.
+func cooldownTick(until time.Time) tea.Cmd {
+	return tea.Tick(time.Second, func(time.Time) tea.Msg {
+		return cooldownTickMsg{until: until}
+	})
+}
+
+// cooldownText formats the notice shown when submitting during the pause.
+func cooldownText(remaining time.Duration) string {
+	secs := int((remaining + time.Second - 1) / time.Second)
+	return fmt.Sprintf("连续失败次数过多，请 %d 秒后再试", secs)
+}
+
+// stopTunnel cancels the primary tunnel's context. The tunnel goroutine is
+// responsible for closing the status and log channels after it exits;
+// anything it still sends is recognised as stale because statusCh no longer
+// matches.
+func (m *AppModel) stopTunnel() {
 	if m.cancelFn != nil {
 		m.cancelFn()
 		m.cancelFn = nil
 	}
+	m.statusCh = nil
 	m.logCh = nil
 	m.pendingLogs = nil
 }
 
+// cleanup stops all tunnels, records the session in the history and
+// releases the local key lock.
+func (m *AppModel) cleanup() {
+	m.abortValidation()
+	m.auditData = nil
+	m.stopTunnel()
+	m.stopMirrors()
+	m.recordSession()
+	if m.config.EndpointFile != "" {
+		tunnel.RemoveEndpointFile(m.config.EndpointFile)
+	}
+	m.keyLock.Release()
+	m.keyLock = nil
+}
+
+// writeEndpoint publishes the primary tunnel's address and expiry to
+// --write-endpoint-file. Failures are shown in the log panel.
+func (m *AppModel) writeEndpoint() {
+	if m.config.EndpointFile == "" || m.tunnelCfg == nil {
+		return
+	}
+	addr := m.tunnelCfg.RemoteAddr()
+	if err := tunnel.WriteEndpointFile(m.config.EndpointFile, addr, m.expiresAt); err != nil {
+		m.runningView.AddLog(time.Now().Format("15:04:05"), "W", err.Error())
+	}
+}
+
+// recordSession adds the session that is ending to the on-disk history.
+// History is a convenience, so failures to write it are ignored.
+func (m *AppModel) recordSession() {
+	if m.sessionStart.IsZero() {
+		return
+	}
+	_ = history.Add(history.Entry{
+		ServerName: m.serverName,
+		ServerURL:  m.serverURL,
+		LocalPort:  m.sessionLocalPort(),
+		StartedAt:  m.sessionStart,
+		Duration:   time.Since(m.sessionStart),
+	})
+	m.sessionStart = time.Time{}
+}
+
+// sessionLocalPort returns the local port the session ends on: the one
+// the running view last retargeted to, if any, else the submitted one.
+func (m *AppModel) sessionLocalPort() int {
+	if m.localTarget != nil {
+		if _, p, err := net.SplitHostPort(m.localTarget.Addr()); err == nil {
+			if port, err := strconv.Atoi(p); err == nil {
+				return port
+			}
+		}
+	}
+	return m.submittedPort
+}
+
 // mapErrorCode translates a server error code into a user-friendly Chinese
 // message. Falls back to the raw message if the code is unrecognized.
 func mapErrorCode(code, message string) string {
@@ -538,6 +2198,8 @@ func mapErrorCode(code, message string) string {
 		return "Access Key 已被撤销"
 	case "KEY_DISCONNECTED":
 		return "Access Key 对应的隧道已断开，请重新获取"
+	case "PROXY_NAME_TAKEN":
+		return "指定的代理名称已被占用，请更换 --proxy-name"
 	default:
 		if message != "" {
 			return message
@@ -546,6 +2208,31 @@ func mapErrorCode(code, message string) string {
 	}
 }
 
+// codeNetwork stands in for a server error code when the server couldn't
+// be reached at all; servers never send it.
+const codeNetwork = "network"
+
+// suggestAction returns the next step to suggest for a server error code,
+// or codeNetwork. Other failures (an empty code) get no suggestion.
+func suggestAction(code string) string {
+	switch code {
+	case codeNetwork:
+		return "检查网络连接和服务器地址后重试"
+	case "KEY_NOT_FOUND":
+		return "确认 Key 已完整输入，或向管理员核实"
+	case "KEY_EXPIRED", "KEY_DISCONNECTED":
+		return "向管理员重新申请一个 Key"
+	case "KEY_ALREADY_USED":
+		return "关闭其他正在使用该 Key 的客户端，稍后重试"
+	case "KEY_REVOKED":
+		return "该 Key 已无法使用，请联系管理员"
+	case "PROXY_NAME_TAKEN":
+		return "更换 --proxy-name，或去掉该参数由服务器分配"
+	default:
+		return ""
+	}
+}
+
 // ---------------------------------------------------------------------------
 // Public entry point
 // ---------------------------------------------------------------------------
@@ -554,8 +2241,27 @@ func mapErrorCode(code, message string) string {
 func Run(cfg *config.Config, version string) error {
 	clientVersion = version
 	theme.SetVersion(version)
+	theme.SetChannel(updater.ResolveChannel("", "", version))
+	theme.SetASCII(cfg.UseASCII())
+	theme.SetSpinner(cfg.SpinnerStyle, cfg.SpinnerInterval)
 	model := newAppModel(cfg)
-	p := tea.NewProgram(model, tea.WithAltScreen())
-	_, err := p.Run()
+	// Cancelling the app context is the last word on shutdown: it stops
+	// tunnels that are still starting (e.g. mirrors mid-validation) too.
+	defer model.cancel()
+	// Clipboard escapes share the renderer's writer, so they can't land
+	// in the middle of a frame.
+	out := &syncOutput{File: os.Stdout}
+	clipboard.SetTerminal(out)
+	opts := []tea.ProgramOption{tea.WithOutput(out)}
+	if cfg.UseAltScreen() {
+		opts = append(opts, tea.WithAltScreen())
+	}
+	p := tea.NewProgram(model, opts...)
+	final, err := p.Run()
+	// Quitting from a sub-view bypasses cleanup; make sure the tunnel is
+	// stopped and the key lock released on the way out.
+	if fm, ok := final.(AppModel); ok {
+		fm.cleanup()
+	}
 	return err
 }
@@ -0,0 +1,202 @@
+package tui
+
+import (
+	"net"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/AerNos/firefrp-client/internal/api"
+	"github.com/AerNos/firefrp-client/internal/config"
+	"github.com/AerNos/firefrp-client/internal/tunnel"
+)
+
+// connectingModel returns an app that submitted a key and is waiting for
+// the validate answer.
+func connectingModel(t *testing.T) AppModel {
+	t.Helper()
+	m := newAppModel(&config.Config{
+		ServerURL:  "http://relay.example.com",
+		APITimeout: 15 * time.Second,
+	})
+	t.Cleanup(m.cancel)
+	m.state = stateConnecting
+	m.submittedKey = "ff-key"
+	m.submittedPort = 25565
+	return m
+}
+
+func TestValidateResult(t *testing.T) {
+	tests := []struct {
+		name      string
+		resp      *api.ValidateResponse
+		wantState appState
+		wantErr   string
+	}{
+		{
+			name:      "ok without data",
+			resp:      &api.ValidateResponse{OK: true},
+			wantState: stateError,
+			wantErr:   "验证成功但未返回连接信息",
+		},
+		{
+			name: "zero remote port",
+			resp: &api.ValidateResponse{OK: true, Data: &api.ValidateData{
+				FrpsAddr: "frps.example.com", FrpsPort: 7000, RemotePort: 0,
+				ExpiresAt: "2026-10-16T12:00:00Z",
+			}},
+			wantState: stateError,
+			wantErr:   "服务器返回的远程端口无效 (0)",
+		},
+		{
+			name: "out of range remote port",
+			resp: &api.ValidateResponse{OK: true, Data: &api.ValidateData{
+				FrpsAddr: "frps.example.com", FrpsPort: 7000, RemotePort: 70000,
+				ExpiresAt: "2026-10-16T12:00:00Z",
+			}},
+			wantState: stateError,
+			wantErr:   "服务器返回的远程端口无效 (70000)",
+		},
+		{
+			name: "not ok",
+			resp: &api.ValidateResponse{
+				OK:    false,
+				Error: &api.ErrorInfo{Code: "KEY_EXPIRED"},
+			},
+			wantState: stateError,
+			wantErr:   "Access Key 已过期",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := connectingModel(t)
+			next, _ := m.Update(validateResultMsg{gen: m.validateGen, resp: tt.resp})
+			got := next.(AppModel)
+			if got.state != tt.wantState {
+				t.Fatalf("state = %v, want %v", got.state, tt.wantState)
+			}
+			if got.validated != nil {
+				t.Error("a failed validation was cached")
+			}
+			if tt.wantErr != "" && (got.err == nil || !strings.Contains(got.err.Error(), tt.wantErr)) {
+				t.Errorf("err = %v, want %q", got.err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestTunnelClose(t *testing.T) {
+	tests := []struct {
+		name      string
+		updates   []tunnel.StatusUpdate
+		wantState appState
+		wantText  string
+	}{
+		{
+			name:      "clean close",
+			updates:   []tunnel.StatusUpdate{{Status: tunnel.StatusClosed, Final: true}},
+			wantState: stateInput,
+			wantText:  "隧道已断开",
+		},
+		{
+			name:      "unexpected close",
+			wantState: stateError,
+			wantText:  "隧道连接已关闭",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := connectingModel(t)
+			m.state = stateRunning
+			ch := make(chan tunnel.StatusUpdate, 1)
+			m.statusCh = ch
+
+			var model tea.Model = m
+			for _, u := range tt.updates {
+				model, _ = model.Update(tunnelStatusMsg{update: u, ch: ch})
+			}
+			model, _ = model.Update(tunnelClosedMsg{ch: ch})
+			got := model.(AppModel)
+
+			if got.state != tt.wantState {
+				t.Fatalf("state = %v, want %v", got.state, tt.wantState)
+			}
+			switch tt.wantState {
+			case stateInput:
+				if got.err != nil {
+					t.Errorf("err = %v, want none", got.err)
+				}
+				if view := got.inputView.View(); !strings.Contains(view, tt.wantText) {
+					t.Errorf("input view lacks notice %q:\n%s", tt.wantText, view)
+				}
+			case stateError:
+				if got.err == nil || !strings.Contains(got.err.Error(), tt.wantText) {
+					t.Errorf("err = %v, want %q", got.err, tt.wantText)
+				}
+			}
+		})
+	}
+}
+
+func TestRunTunnelStopDoesNotLeak(t *testing.T) {
+	// A port nothing listens on, so every tunnel fails to log in.
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	port := ln.Addr().(*net.TCPAddr).Port
+	ln.Close()
+
+	cfg := &tunnel.TunnelConfig{
+		ServerAddr: "127.0.0.1",
+		ServerPort: port,
+		Token:      "secret",
+		AccessKey:  "ff-key",
+		ProxyName:  "ff-1-mc",
+		LocalIP:    "127.0.0.1",
+		LocalPort:  25565,
+		RemotePort: 30001,
+	}
+	m := connectingModel(t)
+	cycle := func() {
+		m.runTunnel(cfg)
+		done := m.tunnelDone
+		m.stopTunnel()
+		select {
+		case <-done:
+		case <-time.After(10 * time.Second):
+			t.Fatal("tunnel did not stop after stopTunnel")
+		}
+	}
+
+	// The first tunnel may start process-wide helpers; count from there.
+	cycle()
+	baseline := settledGoroutines(0)
+
+	const cycles = 20
+	for range cycles {
+		cycle()
+	}
+	if n := settledGoroutines(baseline); n > baseline {
+		t.Errorf("goroutines = %d after %d start/stop cycles, want at most %d", n, cycles, baseline)
+	}
+}
+
+// settledGoroutines waits up to a few seconds for the goroutine count to
+// drop to at most want (any count if want is 0) and stop changing, and
+// returns it.
+func settledGoroutines(want int) int {
+	n := runtime.NumGoroutine()
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); {
+		time.Sleep(50 * time.Millisecond)
+		next := runtime.NumGoroutine()
+		if next == n && (want == 0 || n <= want) {
+			break
+		}
+		n = next
+	}
+	return n
+}
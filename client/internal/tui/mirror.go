@@ -0,0 +1,276 @@
+package tui
+
+import (
+	"context"
+	"fmt"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/AerNos/firefrp-client/internal/api"
+	"github.com/AerNos/firefrp-client/internal/tui/views"
+	"github.com/AerNos/firefrp-client/internal/tunnel"
+)
+
+// mirrorTunnel is a secondary tunnel kept on another relay in mirror mode
+// (--mirror-servers). The primary tunnel drives the state machine as usual;
+// mirrors run alongside it and only feed the running view's mirror block,
+// so one of them failing never takes the session down.
+type mirrorTunnel struct {
+	serverURL  string
+	remoteAddr string
+	tunnelCfg  tunnel.TunnelConfig
+	statusCh   chan tunnel.StatusUpdate
+	cancel     context.CancelFunc
+	status     views.ConnectionStatus
+	text       string
+}
+
+// mirrorStartedMsg carries the outcome of validating the key on a mirror
+// server and starting its tunnel.
+type mirrorStartedMsg struct {
+	gen        int
+	idx        int
+	remoteAddr string
+	tunnelCfg  tunnel.TunnelConfig
+	statusCh   chan tunnel.StatusUpdate
+	cancel     context.CancelFunc
+	err        error
+}
+
+// mirrorStatusMsg carries a status update from a mirror tunnel.
+type mirrorStatusMsg struct {
+	idx    int
+	update tunnel.StatusUpdate
+	ch     chan tunnel.StatusUpdate
+}
+
+// startMirrors validates the submitted key on every secondary mirror server
+// and starts a tunnel on each. Returns nil outside mirror mode.
+func (m *AppModel) startMirrors() tea.Cmd {
+	if !m.config.MirrorMode() {
+		return nil
+	}
+	m.mirrorGen++
+	m.mirrors = nil
+
+	var cmds []tea.Cmd
+	for i, serverURL := range m.config.MirrorServers[1:] {
+		m.mirrors = append(m.mirrors, &mirrorTunnel{
+			serverURL: serverURL,
+			status:    views.StatusReconnecting,
+			text:      "连接中...",
+		})
+		cmds = append(cmds, m.startMirror(i, serverURL))
+	}
+	return tea.Batch(cmds...)
+}
+
+// startMirror returns a tea.Cmd that validates the key on one mirror server
+// and, on success, starts its tunnel in a background goroutine.
+func (m *AppModel) startMirror(idx int, serverURL string) tea.Cmd {
+	gen := m.mirrorGen
+	appCtx := m.ctx
+	key := m.submittedKey
+	localIP := m.localIP
+	localPort := m.submittedPort
+	timeout := m.config.APITimeout
+	proxyName := m.config.ProxyName
+	insecure := m.config.InsecureSkipVerify
+	keyIn := api.KeyPlacement(m.config.KeyIn)
+	idleTimeout := m.config.IdleTimeout
+	target := m.localTarget
+	tunnelProxy := m.config.TunnelProxy
+	metadata := tunnel.ClientMetadata(clientVersion, m.config.Label, m.config.Metadata)
+
+	return func() tea.Msg {
+		client := api.NewAPIClient(serverURL, api.WithTimeout(timeout), api.WithProxyName(proxyName), api.WithKeyPlacement(keyIn), api.WithInsecureSkipVerify(insecure))
+		resp, err := client.Validate(key)
+		if err != nil {
+			return mirrorStartedMsg{gen: gen, idx: idx, err: err}
+		}
+		if !resp.OK {
+			errText := "验证失败"
+			if resp.Error != nil {
+				errText = mapErrorCode(resp.Error.Code, resp.Error.Message)
+			}
+			return mirrorStartedMsg{gen: gen, idx: idx, err: fmt.Errorf("%s", errText)}
+		}
+		if resp.Data == nil {
+			return mirrorStartedMsg{gen: gen, idx: idx, err: fmt.Errorf("验证成功但未返回连接信息")}
+		}
+		if !resp.Data.ValidRemotePort() {
+			return mirrorStartedMsg{gen: gen, idx: idx, err: fmt.Errorf("服务器返回的远程端口无效 (%d)", resp.Data.RemotePort)}
+		}
+
+		data := resp.Data
+		cfg := tunnel.TunnelConfig{
+			ServerAddr: data.FrpsAddr,
+			ServerPort: data.FrpsPort,
+			Fallbacks:  data.FallbackEndpoints(),
+			PublicAddr: data.PublicAddr,
+			Token:      data.Token,
+			AuthMethod: data.AuthMethod,
+			OIDC:       (*tunnel.OIDCConfig)(data.OIDC),
+			ProxyName:  data.ProxyName,
+			LocalIP:    localIP,
+			LocalPort:  localPort,
+			RemotePort: data.RemotePort,
+			AccessKey:  key,
+
+			Metadata:    metadata,
+			IdleTimeout: idleTimeout,
+			Target:      target,
+			ProxyURL:    tunnelProxy,
+		}
+
+		statusCh, cancel := runMirrorTunnel(appCtx, cfg)
+		return mirrorStartedMsg{
+			gen:        gen,
+			idx:        idx,
+			remoteAddr: cfg.RemoteAddr(),
+			tunnelCfg:  cfg,
+			statusCh:   statusCh,
+			cancel:     cancel,
+		}
+	}
+}
+
+// runMirrorTunnel starts a mirror's tunnel in a background goroutine.
+func runMirrorTunnel(appCtx context.Context, cfg tunnel.TunnelConfig) (chan tunnel.StatusUpdate, context.CancelFunc) {
+	statusCh := make(chan tunnel.StatusUpdate, 16)
+	logCh := make(chan tunnel.LogEntry, 64)
+	ctx, cancel := context.WithCancel(appCtx)
+	go func() {
+		_ = tunnel.StartTunnel(ctx, cfg, statusCh, logCh)
+		close(statusCh)
+		close(logCh)
+	}()
+	// Mirror logs aren't shown; drain them so frpc never blocks.
+	go func() {
+		for range logCh {
+		}
+	}()
+	return statusCh, cancel
+}
+
+// restartMirrors restarts the running mirror tunnels so they forward
+// through m.localTarget, after the local address changed for the first
+// time. Mirrors still being validated are restarted once they start.
+func (m *AppModel) restartMirrors() tea.Cmd {
+	var cmds []tea.Cmd
+	for i, mt := range m.mirrors {
+		if mt.cancel == nil {
+			continue
+		}
+		mt.cancel()
+		mt.tunnelCfg.Target = m.localTarget
+		mt.statusCh, mt.cancel = runMirrorTunnel(m.ctx, mt.tunnelCfg)
+		mt.status, mt.text = views.StatusReconnecting, "正在重连..."
+		cmds = append(cmds, waitForMirrorStatus(i, mt.statusCh))
+	}
+	m.runningView.SetMirrors(m.mirrorViews())
+	return tea.Batch(cmds...)
+}
+
+// handleMirrorStarted records a mirror's start result.
+func (m AppModel) handleMirrorStarted(msg mirrorStartedMsg) (tea.Model, tea.Cmd) {
+	if msg.gen != m.mirrorGen || msg.idx >= len(m.mirrors) {
+		// The session this mirror belonged to is gone.
+		if msg.cancel != nil {
+			msg.cancel()
+		}
+		return m, nil
+	}
+
+	mt := m.mirrors[msg.idx]
+	if msg.err != nil {
+		mt.status = views.StatusError
+		mt.text = msg.err.Error()
+		m.runningView.SetMirrors(m.mirrorViews())
+		return m, nil
+	}
+	mt.remoteAddr = msg.remoteAddr
+	mt.tunnelCfg = msg.tunnelCfg
+	mt.statusCh = msg.statusCh
+	mt.cancel = msg.cancel
+	if m.localTarget != nil && mt.tunnelCfg.Target != m.localTarget {
+		// The local address changed while this mirror was validated.
+		mt.cancel()
+		mt.tunnelCfg.Target = m.localTarget
+		mt.statusCh, mt.cancel = runMirrorTunnel(m.ctx, mt.tunnelCfg)
+	}
+	m.runningView.SetMirrors(m.mirrorViews())
+	return m, waitForMirrorStatus(msg.idx, mt.statusCh)
+}
+
+// handleMirrorStatus applies a mirror tunnel status update.
+func (m AppModel) handleMirrorStatus(msg mirrorStatusMsg) (tea.Model, tea.Cmd) {
+	if msg.idx >= len(m.mirrors) || m.mirrors[msg.idx].statusCh != msg.ch {
+		return m, nil
+	}
+
+	mt := m.mirrors[msg.idx]
+	u := msg.update
+	switch u.Status {
+	case tunnel.StatusConnecting:
+		mt.status, mt.text = views.StatusReconnecting, "连接中..."
+	case tunnel.StatusConnected:
+		mt.status, mt.text = views.StatusConnected, "已连接"
+		if u.Endpoint != "" {
+			mt.tunnelCfg.UseEndpoint(u.Endpoint)
+			mt.remoteAddr = mt.tunnelCfg.RemoteAddr()
+		}
+	case tunnel.StatusReconnecting:
+		mt.status, mt.text = views.StatusReconnecting, "正在重连..."
+	case tunnel.StatusRejected, tunnel.StatusError:
+		mt.status, mt.text = views.StatusError, u.Message
+	case tunnel.StatusClosed:
+		mt.status, mt.text = views.StatusError, "已断开"
+	}
+	m.runningView.SetMirrors(m.mirrorViews())
+
+	if u.Final {
+		return m, nil
+	}
+	return m, waitForMirrorStatus(msg.idx, msg.ch)
+}
+
+// waitForMirrorStatus returns a tea.Cmd that reads the next status update
+// from a mirror tunnel.
+func waitForMirrorStatus(idx int, ch chan tunnel.StatusUpdate) tea.Cmd {
+	return func() tea.Msg {
+		update, ok := <-ch
+		if !ok {
+			return nil
+		}
+		return mirrorStatusMsg{idx: idx, update: update, ch: ch}
+	}
+}
+
+// mirrorViews converts mirror state into the running view's display form.
+func (m AppModel) mirrorViews() []views.MirrorStatus {
+	if len(m.mirrors) == 0 {
+		return nil
+	}
+	out := make([]views.MirrorStatus, len(m.mirrors))
+	for i, mt := range m.mirrors {
+		out[i] = views.MirrorStatus{
+			Server:     mt.serverURL,
+			RemoteAddr: mt.remoteAddr,
+			Status:     mt.status,
+			Text:       mt.text,
+		}
+	}
+	return out
+}
+
+// stopMirrors cancels every mirror tunnel and invalidates in-flight starts.
+func (m *AppModel) stopMirrors() {
+	for _, mt := range m.mirrors {
+		if mt.cancel != nil {
+			mt.cancel()
+		}
+	}
+	m.mirrors = nil
+	m.mirrorGen++
+}
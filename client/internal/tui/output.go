@@ -0,0 +1,29 @@
+package tui
+
+import (
+	"os"
+	"sync"
+)
+
+// syncOutput is the terminal the TUI renders to. Writes are serialized,
+// so output from outside the renderer (OSC 52 clipboard escapes) goes out
+// between frames, never inside one: the renderer writes each frame with a
+// single Write. It embeds the *os.File so Bubble Tea still detects a
+// terminal and its size.
+type syncOutput struct {
+	*os.File
+	mu sync.Mutex
+}
+
+// Write writes p to the terminal, after any write in progress.
+func (o *syncOutput) Write(p []byte) (int, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return o.File.Write(p)
+}
+
+// WriteString is Write for strings; it overrides the embedded file's, which
+// io.WriteString would otherwise call without the lock.
+func (o *syncOutput) WriteString(s string) (int, error) {
+	return o.Write([]byte(s))
+}
@@ -1,6 +1,10 @@
 package theme
 
 import (
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/bubbles/spinner"
 	"github.com/charmbracelet/lipgloss"
 )
 
@@ -18,6 +22,45 @@ var (
 	ColorBorderFoc = lipgloss.Color("#FF6B35") // Focused border (primary)
 )
 
+// Glyphs used across views. SetASCII swaps them for plain ASCII stand-ins
+// on terminals that can't render them.
+var (
+	GlyphDot     = "●"
+	GlyphCursor  = "▸"
+	GlyphCross   = "✗"
+	GlyphCheck   = "✓"
+	GlyphPencil  = "✎"
+	GlyphUp      = "↑"
+	GlyphUpDown  = "↑/↓"
+	GlyphMapping = "→"
+	GlyphWarning = "⚠"
+	GlyphRule    = "──"
+)
+
+// SpinnerType is the spinner animation used by all views.
+var SpinnerType = spinner.Dot
+
+// SpinnerStyles maps the names accepted by --spinner to animations.
+var SpinnerStyles = map[string]spinner.Spinner{
+	"dot":    spinner.Dot,
+	"line":   spinner.Line,
+	"points": spinner.Points,
+}
+
+// SetSpinner overrides the spinner animation and its frame interval. An
+// unknown or empty name keeps the current animation; a non-positive
+// interval keeps the animation's default rate. In ASCII mode only line is
+// drawn with ASCII characters, so the other names keep the ASCII spinner.
+// It must be called after SetASCII and before any view is created.
+func SetSpinner(name string, interval time.Duration) {
+	if s, ok := SpinnerStyles[name]; ok && !ASCII {
+		SpinnerType = s
+	}
+	if interval > 0 {
+		SpinnerType.FPS = interval
+	}
+}
+
 // TitleStyle renders the application title in bold primary color.
 var TitleStyle = lipgloss.NewStyle().
 	Bold(true).
@@ -101,19 +144,19 @@ var AppBoxStyle = lipgloss.NewStyle().
 var DotConnected = lipgloss.NewStyle().
 	Foreground(ColorSuccess).
 	Bold(true).
-	Render("●")
+	Render(GlyphDot)
 
 // DotReconnecting renders the yellow "reconnecting" indicator dot.
 var DotReconnecting = lipgloss.NewStyle().
 	Foreground(ColorWarning).
 	Bold(true).
-	Render("●")
+	Render(GlyphDot)
 
 // DotError renders the red "error" indicator dot.
 var DotError = lipgloss.NewStyle().
 	Foreground(ColorError).
 	Bold(true).
-	Render("●")
+	Render(GlyphDot)
 
 // LabelStyle renders key-value labels inside info boxes.
 var LabelStyle = lipgloss.NewStyle().
@@ -131,6 +174,22 @@ var LogBoxStyle = lipgloss.NewStyle().
 	Padding(0, 1).
 	MarginTop(1)
 
+// MotdBoxStyle renders the server's connect message, set apart from the
+// other panels by its border colour.
+var MotdBoxStyle = lipgloss.NewStyle().
+	BorderStyle(lipgloss.RoundedBorder()).
+	BorderForeground(ColorWarning).
+	Padding(0, 1).
+	MarginTop(1)
+
+// InsecureBannerStyle renders the banner shown above every view while TLS
+// certificate verification is disabled (--insecure-skip-verify).
+var InsecureBannerStyle = lipgloss.NewStyle().
+	Foreground(lipgloss.Color("#FFFFFF")).
+	Background(ColorError).
+	Bold(true).
+	Padding(0, 1)
+
 // LogTimeStyle renders the timestamp portion of a log entry.
 var LogTimeStyle = lipgloss.NewStyle().
 	Foreground(ColorTextDim)
@@ -139,11 +198,55 @@ var LogTimeStyle = lipgloss.NewStyle().
 var LogLevelWarn = lipgloss.NewStyle().
 	Foreground(ColorWarning)
 
+// LogDividerStyle renders the disconnect/reconnect markers in the log.
+var LogDividerStyle = lipgloss.NewStyle().
+	Foreground(ColorPrimary).
+	Faint(true)
+
 // LogLevelError renders error-level log indicators (bold).
 var LogLevelError = lipgloss.NewStyle().
 	Foreground(ColorError).
 	Bold(true)
 
+// ASCII is true once SetASCII enabled ASCII-only rendering, for views that
+// have no ASCII stand-in (e.g. the QR code).
+var ASCII bool
+
+// SetASCII switches every glyph, border and the spinner to plain ASCII so
+// the UI renders on serial consoles and terminals without Unicode fonts.
+// It must be called before any view is created.
+func SetASCII(enabled bool) {
+	if !enabled {
+		return
+	}
+	ASCII = true
+
+	GlyphDot = "*"
+	GlyphCursor = ">"
+	GlyphCross = "x"
+	GlyphCheck = "+"
+	GlyphPencil = "~"
+	GlyphUp = "^"
+	GlyphUpDown = "Up/Down"
+	GlyphMapping = "->"
+	GlyphWarning = "!"
+	GlyphRule = "--"
+
+	SpinnerType = spinner.Line
+
+	border := lipgloss.ASCIIBorder()
+	InputStyle = InputStyle.BorderStyle(border)
+	FocusedInputStyle = FocusedInputStyle.BorderStyle(border)
+	BoxStyle = BoxStyle.BorderStyle(border)
+	AppBoxStyle = AppBoxStyle.BorderStyle(border)
+	LogBoxStyle = LogBoxStyle.BorderStyle(border)
+	MotdBoxStyle = MotdBoxStyle.BorderStyle(border)
+
+	DotConnected = lipgloss.NewStyle().Foreground(ColorSuccess).Bold(true).Render(GlyphDot)
+	DotReconnecting = lipgloss.NewStyle().Foreground(ColorWarning).Bold(true).Render(GlyphDot)
+	DotError = lipgloss.NewStyle().Foreground(ColorError).Bold(true).Render(GlyphDot)
+}
+
 // clientVersion holds the build version, set via SetVersion().
 var clientVersion string
 
@@ -152,6 +255,21 @@ func SetVersion(v string) {
 	clientVersion = v
 }
 
+// Version returns the client version stored by SetVersion.
+func Version() string {
+	return clientVersion
+}
+
+// updateChannel is the update channel shown next to the version, set via
+// SetChannel().
+var updateChannel string
+
+// SetChannel stores the update channel in effect ("dev" or "stable") for
+// display in BrandText, so users can tell which track they follow.
+func SetChannel(ch string) {
+	updateChannel = ch
+}
+
 // formatVersion returns the version string for display.
 // Release versions (e.g. "1.0.0") get a "v" prefix.
 // Dev versions (e.g. "dev-6-20260220-abc") are returned as-is.
@@ -169,13 +287,23 @@ func formatVersion(v string) string {
 var VersionStyle = lipgloss.NewStyle().
 	Foreground(ColorTextDim)
 
-// BrandText returns the styled FireFrp header block (title + version + subtitle).
-func BrandText() string {
+// BrandText returns the styled FireFrp header block (title + version +
+// subtitle) for a terminal width columns wide. Until the width is known
+// (zero, before the first WindowSizeMsg) the lines are simply stacked
+// left-aligned, so the first frame doesn't shift once the size arrives.
+func BrandText(width int) string {
 	title := TitleStyle.Render("FireFrp Client")
 	lines := []string{title}
 	if clientVersion != "" {
-		lines = append(lines, VersionStyle.Render(formatVersion(clientVersion)))
+		v := formatVersion(clientVersion)
+		if updateChannel != "" {
+			v += " · " + updateChannel
+		}
+		lines = append(lines, VersionStyle.Render(v))
 	}
 	lines = append(lines, SubtitleStyle.Render("临时隧道，一键开服"))
+	if width <= 0 {
+		return strings.Join(lines, "\n")
+	}
 	return lipgloss.JoinVertical(lipgloss.Center, lines...)
 }
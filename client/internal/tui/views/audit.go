@@ -0,0 +1,114 @@
+package views
+
+import (
+	"fmt"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/AerNos/firefrp-client/internal/tui/theme"
+)
+
+// AuditConfirmedMsg is emitted when the user approves the audited config
+// and wants to connect. Declining emits CancelConnectMsg.
+type AuditConfirmedMsg struct{}
+
+// auditChromeRows is the height of everything around the audit text: the
+// brand header, title, scroll hint, help line and AppBoxStyle chrome.
+const auditChromeRows = 14
+
+// AuditModel is the Bubble Tea model for the --audit checkpoint: it shows
+// what the server returned and the frpc config derived from it, and waits
+// for the user to connect or cancel. The zero value is empty.
+type AuditModel struct {
+	lines  []string
+	offset int
+	width  int
+	height int
+}
+
+// SetContent replaces the audited text and scrolls back to the top.
+func (m *AuditModel) SetContent(text string) {
+	m.lines = strings.Split(text, "\n")
+	m.offset = 0
+}
+
+// Init implements tea.Model; the view has nothing to start.
+func (m AuditModel) Init() tea.Cmd {
+	return nil
+}
+
+// Update handles messages for the audit view.
+func (m AuditModel) Update(msg tea.Msg) (AuditModel, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.width = msg.Width
+		m.height = msg.Height
+		m.offset = min(m.offset, m.maxOffset())
+		return m, nil
+
+	case tea.KeyMsg:
+		switch msg.String() {
+		case "ctrl+c":
+			return m, tea.Quit
+		case "esc", "n":
+			return m, func() tea.Msg { return CancelConnectMsg{} }
+		case "enter", "y":
+			return m, func() tea.Msg { return AuditConfirmedMsg{} }
+		case "up", "k":
+			m.offset = max(m.offset-1, 0)
+		case "down", "j":
+			m.offset = min(m.offset+1, m.maxOffset())
+		case "pgup":
+			m.offset = max(m.offset-m.visibleRows(), 0)
+		case "pgdown", " ":
+			m.offset = min(m.offset+m.visibleRows(), m.maxOffset())
+		}
+	}
+	return m, nil
+}
+
+// visibleRows returns how many lines of the audit text fit the terminal.
+func (m AuditModel) visibleRows() int {
+	if m.height <= 0 {
+		return 20
+	}
+	return max(m.height-auditChromeRows, 5)
+}
+
+// maxOffset returns the largest scroll offset that still fills the view.
+func (m AuditModel) maxOffset() int {
+	return max(len(m.lines)-m.visibleRows(), 0)
+}
+
+// View renders the audited config.
+func (m AuditModel) View() string {
+	var b strings.Builder
+
+	b.WriteString(theme.BrandText(m.width))
+	b.WriteString("\n\n")
+	b.WriteString(theme.InputLabelStyle.Render("连接前审计:"))
+	b.WriteString("\n")
+
+	// Widen the box to the terminal like the running view, so JSON lines
+	// aren't wrapped. AppBoxStyle adds 8 chars of chrome.
+	contentWidth := 62
+	if m.width > 0 {
+		contentWidth = min(max(m.width-8, 40), 92)
+	}
+
+	end := min(m.offset+m.visibleRows(), len(m.lines))
+	for _, line := range m.lines[m.offset:end] {
+		b.WriteString("  " + theme.ValueStyle.Render(truncateWidth(line, contentWidth-2)))
+		b.WriteString("\n")
+	}
+	if len(m.lines) > m.visibleRows() {
+		b.WriteString(theme.LogTimeStyle.Render(fmt.Sprintf("  第 %d-%d 行，共 %d 行", m.offset+1, end, len(m.lines))))
+		b.WriteString("\n")
+	}
+
+	b.WriteString("\n")
+	b.WriteString(theme.HelpStyle.Render("[" + theme.GlyphUpDown + "] 滚动  [Enter] 连接  [Esc] 取消"))
+
+	return theme.AppBoxStyle.Copy().Width(contentWidth + 8).Render(b.String())
+}
@@ -16,6 +16,7 @@ type ConnectPhase int
 const (
 	PhaseValidating ConnectPhase = iota // Validating the access key with the API.
 	PhaseConnecting                     // Establishing the frpc tunnel.
+	PhaseIssuingKey                     // Getting an access key for the account token.
 )
 
 // CancelConnectMsg is emitted when the user cancels during connection.
@@ -25,6 +26,7 @@ type CancelConnectMsg struct{}
 type ConnectingModel struct {
 	spinner    spinner.Model
 	phase      ConnectPhase
+	detail     string // latest sub-status reported by the tunnel
 	key        string // Access key (will be partially masked).
 	localPort  int
 	remotePort int
@@ -36,7 +38,7 @@ type ConnectingModel struct {
 // NewConnectingModel creates a ConnectingModel for the given key and ports.
 func NewConnectingModel(key string, localPort int, serverName string) ConnectingModel {
 	s := spinner.New()
-	s.Spinner = spinner.Dot
+	s.Spinner = theme.SpinnerType
 	s.Style = theme.SpinnerStyle
 
 	return ConnectingModel{
@@ -58,6 +60,12 @@ func (m *ConnectingModel) SetPhase(p ConnectPhase) {
 	m.phase = p
 }
 
+// SetDetail replaces the phase text with a finer-grained progress message
+// (e.g. "注册代理中...") while in PhaseConnecting.
+func (m *ConnectingModel) SetDetail(text string) {
+	m.detail = text
+}
+
 // SetRemotePort stores the remote port once known from the API response.
 func (m *ConnectingModel) SetRemotePort(port int) {
 	m.remotePort = port
@@ -93,16 +101,21 @@ func (m ConnectingModel) View() string {
 	var b strings.Builder
 
 	// Brand header.
-	b.WriteString(theme.BrandText())
+	b.WriteString(theme.BrandText(m.width))
 	b.WriteString("\n\n")
 
 	// Spinner + phase message.
 	var phaseText string
 	switch m.phase {
+	case PhaseIssuingKey:
+		phaseText = "正在获取 Access Key..."
 	case PhaseValidating:
 		phaseText = "正在验证 Access Key..."
 	case PhaseConnecting:
 		phaseText = "正在建立隧道连接..."
+		if m.detail != "" {
+			phaseText = m.detail
+		}
 	}
 	b.WriteString("  " + m.spinner.View() + " " + phaseText)
 	b.WriteString("\n\n")
@@ -110,7 +123,11 @@ func (m ConnectingModel) View() string {
 	// Connection details.
 	b.WriteString("  " + theme.LabelStyle.Render("服务器:") + " " + theme.ValueStyle.Render(m.serverName))
 	b.WriteString("\n")
-	b.WriteString("  " + theme.LabelStyle.Render("Key:") + " " + theme.ValueStyle.Render(MaskKey(m.key)))
+	key := MaskKey(m.key)
+	if m.key == "" {
+		key = "(账户令牌)"
+	}
+	b.WriteString("  " + theme.LabelStyle.Render("Key:") + " " + theme.ValueStyle.Render(key))
 	b.WriteString("\n")
 	if m.remotePort > 0 {
 		b.WriteString("  " + theme.LabelStyle.Render("端口:") + " " + theme.ValueStyle.Render(FmtPort(m.localPort, m.remotePort)))
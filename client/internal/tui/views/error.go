@@ -0,0 +1,101 @@
+package views
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/AerNos/firefrp-client/internal/tui/theme"
+)
+
+// RetryMsg is emitted when the user asks to retry the failed connection.
+type RetryMsg struct{}
+
+// ErrorBackMsg is emitted when the user leaves the error view for the
+// input form.
+type ErrorBackMsg struct{}
+
+// ErrorModel is the Bubble Tea model for the view shown after a connection
+// attempt or session failed for good.
+type ErrorModel struct {
+	message string
+	hint    string
+
+	// cooldown is the remaining pause after too many failures in a row;
+	// retrying is refused while it is positive.
+	cooldown time.Duration
+
+	width int
+}
+
+// NewErrorModel creates an ErrorModel showing message and, if non-empty, a
+// suggested next step.
+func NewErrorModel(message, hint string) ErrorModel {
+	return ErrorModel{message: message, hint: hint}
+}
+
+// SetCooldown sets the remaining pause before another attempt is allowed.
+// Zero re-enables retrying.
+func (m *ErrorModel) SetCooldown(remaining time.Duration) {
+	m.cooldown = remaining
+}
+
+// Init implements tea.Model; the error view has nothing to start.
+func (m ErrorModel) Init() tea.Cmd {
+	return nil
+}
+
+// Update handles messages for the error view.
+func (m ErrorModel) Update(msg tea.Msg) (ErrorModel, tea.Cmd) {
+	if msg, ok := msg.(tea.WindowSizeMsg); ok {
+		m.width = msg.Width
+		return m, nil
+	}
+	if msg, ok := msg.(tea.KeyMsg); ok {
+		switch msg.String() {
+		case "enter":
+			if m.cooldown > 0 {
+				return m, nil
+			}
+			return m, func() tea.Msg { return RetryMsg{} }
+		case "esc":
+			return m, func() tea.Msg { return ErrorBackMsg{} }
+		}
+	}
+	return m, nil
+}
+
+// View renders the failure, the suggested action and the retry help.
+func (m ErrorModel) View() string {
+	var b strings.Builder
+
+	b.WriteString(theme.BrandText(m.width))
+	b.WriteString("\n\n")
+	if m.cooldown > 0 {
+		b.WriteString("  " + theme.DotReconnecting + " " + theme.WarningStyle.Render("稍后再试"))
+	} else {
+		b.WriteString("  " + theme.DotError + " " + theme.ErrorStyle.Render("连接失败"))
+	}
+	b.WriteString("\n")
+
+	info := theme.LabelStyle.Render("原因:") + " " + theme.ValueStyle.Render(m.message)
+	if m.hint != "" {
+		info += "\n" + theme.LabelStyle.Render("建议:") + " " + theme.ValueStyle.Render(m.hint)
+	}
+	if m.cooldown > 0 {
+		secs := int((m.cooldown + time.Second - 1) / time.Second)
+		info += "\n" + theme.LabelStyle.Render("等待:") + " " +
+			theme.WarningStyle.Render(fmt.Sprintf("连续失败次数过多，%d 秒后可重试", secs))
+	}
+	b.WriteString(theme.BoxStyle.Render(info))
+	b.WriteString("\n")
+	if m.cooldown > 0 {
+		b.WriteString(theme.HelpStyle.Render("[Esc] 返回"))
+	} else {
+		b.WriteString(theme.HelpStyle.Render("[Enter] 重试  [Esc] 返回"))
+	}
+
+	return theme.AppBoxStyle.Render(b.String())
+}
@@ -0,0 +1,135 @@
+package views
+
+import (
+	"fmt"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+
+	"github.com/AerNos/firefrp-client/internal/history"
+	"github.com/AerNos/firefrp-client/internal/tui/theme"
+)
+
+// HistorySelectedMsg is emitted when the user picks a past session.
+type HistorySelectedMsg struct {
+	Entry history.Entry
+}
+
+// HistoryClosedMsg is emitted when the user leaves the history view
+// without picking a session.
+type HistoryClosedMsg struct{}
+
+// HistoryClearMsg is emitted when the user asks to clear the history.
+type HistoryClearMsg struct{}
+
+// HistoryModel is the Bubble Tea model for the recent-sessions list shown
+// from the input view. The zero value is an empty list.
+type HistoryModel struct {
+	entries []history.Entry
+	cursor  int
+	err     string
+	width   int
+}
+
+// Init implements tea.Model; the list has nothing to start.
+func (m HistoryModel) Init() tea.Cmd {
+	return nil
+}
+
+// SetEntries replaces the listed sessions (most recent first). err, if
+// non-nil, is shown instead of the list.
+func (m *HistoryModel) SetEntries(entries []history.Entry, err error) {
+	m.entries = entries
+	m.cursor = 0
+	m.err = ""
+	if err != nil {
+		m.err = err.Error()
+	}
+}
+
+// Update handles messages for the history view.
+func (m HistoryModel) Update(msg tea.Msg) (HistoryModel, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.width = msg.Width
+		return m, nil
+
+	case tea.KeyMsg:
+		switch msg.String() {
+		case "esc", "ctrl+r":
+			return m, func() tea.Msg { return HistoryClosedMsg{} }
+		case "up", "k":
+			if m.cursor > 0 {
+				m.cursor--
+			}
+		case "down", "j":
+			if m.cursor < len(m.entries)-1 {
+				m.cursor++
+			}
+		case "d":
+			if len(m.entries) > 0 {
+				return m, func() tea.Msg { return HistoryClearMsg{} }
+			}
+		case "enter":
+			if m.cursor < len(m.entries) {
+				entry := m.entries[m.cursor]
+				return m, func() tea.Msg { return HistorySelectedMsg{Entry: entry} }
+			}
+		}
+	}
+	return m, nil
+}
+
+// View renders the recent-sessions list.
+func (m HistoryModel) View() string {
+	var b strings.Builder
+
+	b.WriteString(theme.BrandText(m.width))
+	b.WriteString("\n\n")
+	b.WriteString(theme.InputLabelStyle.Render("最近会话（当前服务器）:"))
+	b.WriteString("\n\n")
+
+	dim := lipgloss.NewStyle().Foreground(theme.ColorTextDim)
+	switch {
+	case m.err != "":
+		b.WriteString(theme.ErrorStyle.Render("  " + theme.GlyphCross + " 读取历史记录失败: " + m.err))
+		b.WriteString("\n")
+	case len(m.entries) == 0:
+		b.WriteString(dim.Render("  暂无历史记录"))
+		b.WriteString("\n")
+	default:
+		for i, e := range m.entries {
+			detail := fmt.Sprintf(" %s  %s", e.StartedAt.Local().Format("01-02 15:04"), formatDuration(e.Duration))
+			text := truncateWidth(fmt.Sprintf("%-5d %s", e.LocalPort, e.ServerName)+detail, m.textWidth())
+			line := "  " + text
+			if i == m.cursor {
+				line = lipgloss.NewStyle().Foreground(theme.ColorPrimary).Bold(true).Render(theme.GlyphCursor) + " " + text
+			}
+			b.WriteString(line)
+			b.WriteString("\n")
+		}
+	}
+
+	if len(m.entries) > 0 {
+		b.WriteString(theme.HelpStyle.Render("[" + theme.GlyphUpDown + "] 选择  [Enter] 填入  [D] 清空  [Esc] 返回"))
+	} else {
+		b.WriteString(theme.HelpStyle.Render("[Esc] 返回"))
+	}
+
+	return theme.AppBoxStyle.Render(b.String())
+}
+
+// textWidth returns the display cells available for one history row, or 0
+// (no limit) while the terminal size is unknown.
+func (m HistoryModel) textWidth() int {
+	if m.width <= 0 {
+		return 0
+	}
+	// AppBoxStyle chrome (8) plus the cursor and indent (2).
+	w := m.width - 10
+	if w < 10 {
+		w = 10
+	}
+	return w
+}
@@ -12,7 +12,8 @@ import (
 	"github.com/AerNos/firefrp-client/internal/tui/theme"
 )
 
-// SubmitMsg is emitted when the user submits valid key + port values.
+// SubmitMsg is emitted when the user submits valid key + port values. Key
+// is empty in token mode, where the key is issued for an account token.
 type SubmitMsg struct {
 	Key  string
 	Port int
@@ -22,9 +23,13 @@ type SubmitMsg struct {
 type InputModel struct {
 	keyInput   textinput.Model
 	portInput  textinput.Model
-	focusIndex int // 0 = key, 1 = port
+	focusIndex int  // 0 = key, 1 = port
+	revealKey  bool // show the whole key instead of masking it
+	tokenMode  bool // keys come from an account token; no key field
 	err        string
 	updateHint string // non-empty when a dev update is available
+	warning    string // non-blocking warning about the selected server
+	notice     string // confirmation of a completed action (e.g. profile saved)
 	width      int
 	height     int
 }
@@ -73,8 +78,15 @@ func (m InputModel) Update(msg tea.Msg) (InputModel, tea.Cmd) {
 		case "ctrl+c", "esc":
 			return m, tea.Quit
 
+		case "ctrl+t":
+			m.revealKey = !m.revealKey
+			return m, nil
+
 		case "tab", "shift+tab":
 			m.err = ""
+			if m.tokenMode {
+				return m, nil
+			}
 			if m.focusIndex == 0 {
 				m.focusIndex = 1
 				m.keyInput.Blur()
@@ -91,14 +103,16 @@ func (m InputModel) Update(msg tea.Msg) (InputModel, tea.Cmd) {
 			key := strings.TrimSpace(m.keyInput.Value())
 			portStr := strings.TrimSpace(m.portInput.Value())
 
-			if key == "" {
+			if m.tokenMode {
+				key = ""
+			} else if key == "" {
 				m.err = "Access Key 不能为空"
 				m.focusIndex = 0
 				m.portInput.Blur()
 				m.keyInput.Focus()
 				return m, nil
 			}
-			if !strings.HasPrefix(key, "ff-") {
+			if !m.tokenMode && !strings.HasPrefix(key, "ff-") {
 				m.err = "Access Key 格式不正确，应以 ff- 开头"
 				m.focusIndex = 0
 				m.portInput.Blur()
@@ -143,16 +157,18 @@ func (m InputModel) View() string {
 	var b strings.Builder
 
 	// Brand header.
-	b.WriteString(theme.BrandText())
+	b.WriteString(theme.BrandText(m.width))
 	b.WriteString("\n\n")
 
 	// Key input.
 	b.WriteString(theme.InputLabelStyle.Render("Access Key:"))
 	b.WriteString("\n")
-	if m.focusIndex == 0 {
-		b.WriteString(theme.FocusedInputStyle.Render(m.keyInput.View()))
+	if m.tokenMode {
+		b.WriteString(theme.InputStyle.Render(theme.HelpStyle.Render("由账户令牌自动获取 (--token)")))
+	} else if m.focusIndex == 0 {
+		b.WriteString(theme.FocusedInputStyle.Render(m.keyView()))
 	} else {
-		b.WriteString(theme.InputStyle.Render(m.keyInput.View()))
+		b.WriteString(theme.InputStyle.Render(m.keyView()))
 	}
 	b.WriteString("\n\n")
 
@@ -168,18 +184,38 @@ func (m InputModel) View() string {
 	// Error message.
 	if m.err != "" {
 		b.WriteString("\n\n")
-		b.WriteString(theme.ErrorStyle.Render("  ✗ " + m.err))
+		b.WriteString(theme.ErrorStyle.Render(wrapMessage("  "+theme.GlyphCross+" ", m.err, appBoxContentWidth(m.width))))
+	}
+
+	// Confirmation (e.g. profile saved).
+	if m.notice != "" && m.err == "" {
+		b.WriteString("\n\n")
+		b.WriteString(theme.SuccessStyle.Render(wrapMessage("  "+theme.GlyphCheck+" ", m.notice, appBoxContentWidth(m.width))))
+	}
+
+	// Server warning (e.g. frp version mismatch).
+	if m.warning != "" {
+		b.WriteString("\n\n")
+		b.WriteString(theme.WarningStyle.Render(wrapMessage("  ", m.warning, appBoxContentWidth(m.width))))
 	}
 
 	// Update hint (shown for optional dev updates).
 	if m.updateHint != "" && m.err == "" {
 		b.WriteString("\n\n")
-		b.WriteString(theme.WarningStyle.Render("  ↑ " + m.updateHint))
+		b.WriteString(theme.WarningStyle.Render(wrapMessage("  "+theme.GlyphUp+" ", m.updateHint, appBoxContentWidth(m.width))))
 	}
 
 	// Help bar.
 	b.WriteString("\n")
-	help := theme.HelpStyle.Render("[Tab] 切换  [Enter] 连接  [Esc] 退出")
+	reveal := "[Ctrl+T] 显示 Key"
+	if m.revealKey {
+		reveal = "[Ctrl+T] 隐藏 Key"
+	}
+	keys := "[Tab] 切换  [Enter] 连接  " + reveal
+	if m.tokenMode {
+		keys = "[Enter] 连接"
+	}
+	help := theme.HelpStyle.Render(keys + "  [Ctrl+R] 历史  [Ctrl+S] 保存配置  [Esc] 退出")
 	b.WriteString(help)
 
 	// Wrap in the application box.
@@ -187,6 +223,33 @@ func (m InputModel) View() string {
 	return theme.AppBoxStyle.Render(content)
 }
 
+// keyView renders the key input. Unless revealed, everything after the
+// first keyVisiblePrefix characters is shown as '*', one per character so
+// the cursor stays in place.
+func (m InputModel) keyView() string {
+	value := []rune(m.keyInput.Value())
+	if m.revealKey || len(value) <= keyVisiblePrefix {
+		return m.keyInput.View()
+	}
+	masked := string(value[:keyVisiblePrefix]) + strings.Repeat("*", len(value)-keyVisiblePrefix)
+	ki := m.keyInput
+	ki.SetValue(masked)
+	ki.SetCursor(m.keyInput.Position())
+	return ki.View()
+}
+
+// appBoxContentWidth returns the text width inside AppBoxStyle: its width
+// minus horizontal padding, or less when the terminal (termWidth cells,
+// 0 if unknown) is narrower than the box.
+func appBoxContentWidth(termWidth int) int {
+	box := theme.AppBoxStyle
+	w := box.GetWidth() - box.GetHorizontalPadding()
+	if termWidth > 0 {
+		w = min(w, termWidth-box.GetHorizontalFrameSize())
+	}
+	return max(w, 10)
+}
+
 // SetError sets an external error message on the input view (e.g. from API).
 func (m *InputModel) SetError(err string) {
 	m.err = err
@@ -197,17 +260,74 @@ func (m *InputModel) ClearError() {
 	m.err = ""
 }
 
+// SetPort pre-fills the port field and moves focus to the key field, e.g.
+// when reusing a past session.
+func (m *InputModel) SetPort(port int) {
+	m.portInput.SetValue(strconv.Itoa(port))
+	m.err = ""
+	if m.tokenMode {
+		return
+	}
+	m.focusIndex = 0
+	m.portInput.Blur()
+	m.keyInput.Focus()
+}
+
+// SetTokenMode switches between entering a key by hand and having it
+// issued for an account token (--token), in which case only the port is
+// asked for.
+func (m *InputModel) SetTokenMode(on bool) {
+	m.tokenMode = on
+	if on {
+		m.focusIndex = 1
+		m.keyInput.Blur()
+		m.portInput.Focus()
+	} else {
+		m.focusIndex = 0
+		m.portInput.Blur()
+		m.keyInput.Focus()
+	}
+}
+
+// SetKey pre-fills the key field, e.g. with the first of --keys.
+func (m *InputModel) SetKey(key string) {
+	m.keyInput.SetValue(key)
+}
+
+// Port returns the entered local port, and false if it isn't a valid one.
+func (m InputModel) Port() (int, bool) {
+	port, err := strconv.Atoi(strings.TrimSpace(m.portInput.Value()))
+	if err != nil || port < 1 || port > 65535 {
+		return 0, false
+	}
+	return port, true
+}
+
+// SetNotice shows a confirmation below the inputs; empty clears it.
+func (m *InputModel) SetNotice(text string) {
+	m.notice = text
+}
+
+// SetWarning sets a non-blocking warning about the selected server.
+func (m *InputModel) SetWarning(text string) {
+	m.warning = text
+}
+
 // SetUpdateHint sets a notification about an available optional update.
 func (m *InputModel) SetUpdateHint(hint string) {
 	m.updateHint = hint
 }
 
+// keyVisiblePrefix is how many leading characters of a key stay readable
+// when it is masked ("ff-" plus four more).
+const keyVisiblePrefix = 7
+
 // maskKey returns a partially masked key for display, e.g. "ff-a1b2***".
 func maskKey(key string) string {
-	if len(key) <= 7 {
+	if len(key) <= keyVisiblePrefix {
 		return key
 	}
-	return key[:7] + "***"
+	return key[:keyVisiblePrefix] + "***"
 }
 
 // MaskKey is the exported version of maskKey for use by other packages.
@@ -217,5 +337,5 @@ func MaskKey(key string) string {
 
 // FmtPort formats a port mapping string, e.g. "25565 → 10001".
 func FmtPort(local, remote int) string {
-	return fmt.Sprintf("%d → %d", local, remote)
+	return fmt.Sprintf("%d %s %d", local, theme.GlyphMapping, remote)
 }
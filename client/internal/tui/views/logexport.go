@@ -0,0 +1,73 @@
+package views
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/AerNos/firefrp-client/internal/api"
+	"github.com/AerNos/firefrp-client/internal/tui/theme"
+)
+
+// logsExportedMsg reports where the log buffer was written, or why it
+// couldn't be.
+type logsExportedMsg struct {
+	path string
+	err  error
+}
+
+// logExportName returns the file name for logs exported at t, e.g.
+// "firefrp-log-20260101-140000.txt".
+func logExportName(t time.Time) string {
+	return "firefrp-log-" + t.Format("20060102-150405") + ".txt"
+}
+
+// exportLogs returns a tea.Cmd that writes the whole log buffer, below a
+// header with the connection details, to a timestamped file in the
+// working directory (or the temp directory if that isn't writable). No
+// key or token is part of the view, so none ends up in the file.
+func (m RunningModel) exportLogs() tea.Cmd {
+	now := time.Now()
+	var b strings.Builder
+	fmt.Fprintf(&b, "FireFrp Client %s\n", theme.Version())
+	fmt.Fprintf(&b, "导出时间: %s\n", now.Format(time.RFC3339))
+	fmt.Fprintf(&b, "服务器:   %s\n", api.RedactURL(m.serverName))
+	fmt.Fprintf(&b, "远程地址: %s\n", m.remoteAddr)
+	if m.endpoint != "" {
+		fmt.Fprintf(&b, "接入点:   %s\n", m.endpoint)
+	}
+	fmt.Fprintf(&b, "本地地址: %s\n", m.localAddr)
+	fmt.Fprintf(&b, "状态:     %s (重连 %d 次)\n", m.statusText, m.reconnects)
+	fmt.Fprintf(&b, "会话时长: %s\n\n", formatDuration(m.sessionUptime()))
+	b.WriteString(formatLogs(m.logEntries))
+	text := b.String()
+	name := logExportName(now)
+
+	return func() tea.Msg {
+		path, err := writeLogExport(name, text)
+		return logsExportedMsg{path: path, err: err}
+	}
+}
+
+// writeLogExport writes text to name in the working directory, falling
+// back to the temp directory, and returns the absolute path written.
+func writeLogExport(name, text string) (string, error) {
+	var firstErr error
+	for _, dir := range []string{".", os.TempDir()} {
+		path, err := filepath.Abs(filepath.Join(dir, name))
+		if err == nil {
+			err = os.WriteFile(path, []byte(text), 0o600)
+		}
+		if err == nil {
+			return path, nil
+		}
+		if firstErr == nil {
+			firstErr = err
+		}
+	}
+	return "", firstErr
+}
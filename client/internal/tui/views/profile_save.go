@@ -0,0 +1,94 @@
+package views
+
+import (
+	"strings"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+
+	"github.com/AerNos/firefrp-client/internal/tui/theme"
+)
+
+// ProfileSaveMsg is emitted when the user confirms a profile name.
+type ProfileSaveMsg struct {
+	Name string
+}
+
+// ProfileSaveClosedMsg is emitted when the user leaves without saving.
+type ProfileSaveClosedMsg struct{}
+
+// ProfileSaveModel is the Bubble Tea model for naming a connection profile
+// saved from the input view.
+type ProfileSaveModel struct {
+	nameInput textinput.Model
+	summary   string // what will be saved, e.g. "服务器 A · 端口 25565"
+	err       string
+	width     int
+}
+
+// NewProfileSaveModel creates a ProfileSaveModel describing the settings
+// about to be saved.
+func NewProfileSaveModel(summary string) ProfileSaveModel {
+	ni := textinput.New()
+	ni.Placeholder = "配置名称 (如 mc)"
+	ni.CharLimit = 32
+	ni.Width = 36
+	ni.PromptStyle = lipgloss.NewStyle().Foreground(theme.ColorPrimary)
+	ni.TextStyle = lipgloss.NewStyle().Foreground(theme.ColorText)
+	ni.PlaceholderStyle = lipgloss.NewStyle().Foreground(theme.ColorTextDim)
+	ni.Focus()
+	return ProfileSaveModel{nameInput: ni, summary: summary}
+}
+
+// Init starts the cursor blink.
+func (m ProfileSaveModel) Init() tea.Cmd {
+	return textinput.Blink
+}
+
+// Update handles messages for the profile-save view.
+func (m ProfileSaveModel) Update(msg tea.Msg) (ProfileSaveModel, tea.Cmd) {
+	if msg, ok := msg.(tea.WindowSizeMsg); ok {
+		m.width = msg.Width
+		return m, nil
+	}
+	if msg, ok := msg.(tea.KeyMsg); ok {
+		switch msg.String() {
+		case "esc":
+			return m, func() tea.Msg { return ProfileSaveClosedMsg{} }
+		case "enter":
+			name := strings.TrimSpace(m.nameInput.Value())
+			if name == "" {
+				m.err = "名称不能为空"
+				return m, nil
+			}
+			return m, func() tea.Msg { return ProfileSaveMsg{Name: name} }
+		}
+	}
+	var cmd tea.Cmd
+	m.nameInput, cmd = m.nameInput.Update(msg)
+	return m, cmd
+}
+
+// View renders the profile name prompt.
+func (m ProfileSaveModel) View() string {
+	var b strings.Builder
+
+	b.WriteString(theme.BrandText(m.width))
+	b.WriteString("\n\n")
+	b.WriteString(theme.InputLabelStyle.Render("保存为配置:"))
+	b.WriteString("\n")
+	b.WriteString(lipgloss.NewStyle().Foreground(theme.ColorTextDim).Render("  " + m.summary))
+	b.WriteString("\n\n")
+	b.WriteString(theme.FocusedInputStyle.Render(m.nameInput.View()))
+
+	if m.err != "" {
+		b.WriteString("\n\n")
+		b.WriteString(theme.ErrorStyle.Render("  " + theme.GlyphCross + " " + m.err))
+	}
+
+	b.WriteString("\n")
+	b.WriteString(theme.HelpStyle.Render("[Enter] 保存  [Esc] 返回"))
+
+	return theme.AppBoxStyle.Render(b.String())
+}
@@ -0,0 +1,118 @@
+package views
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+
+	"github.com/AerNos/firefrp-client/internal/tui/theme"
+)
+
+// LocalTargetMsg is emitted when the user repoints the tunnel at another
+// local address from the running view.
+type LocalTargetMsg struct {
+	IP   string
+	Port int
+}
+
+// openRetarget shows the prompt for a new local address, prefilled with
+// the current one.
+func (m *RunningModel) openRetarget() tea.Cmd {
+	ti := textinput.New()
+	ti.Placeholder = "端口 或 IP:端口 (如 25566)"
+	ti.CharLimit = 47
+	ti.Width = 36
+	ti.PromptStyle = lipgloss.NewStyle().Foreground(theme.ColorPrimary)
+	ti.TextStyle = lipgloss.NewStyle().Foreground(theme.ColorText)
+	ti.PlaceholderStyle = lipgloss.NewStyle().Foreground(theme.ColorTextDim)
+	ti.Focus()
+	m.targetInput = ti
+	m.targetErr = ""
+	m.retargeting = true
+	return textinput.Blink
+}
+
+// updateRetarget handles messages while the local address prompt is open.
+func (m RunningModel) updateRetarget(msg tea.Msg) (RunningModel, tea.Cmd) {
+	if msg, ok := msg.(tea.KeyMsg); ok {
+		switch msg.String() {
+		case "esc":
+			m.retargeting = false
+			return m, nil
+		case "enter":
+			ip, port, err := parseLocalTarget(m.targetInput.Value(), m.localAddr)
+			if err != nil {
+				m.targetErr = err.Error()
+				return m, nil
+			}
+			m.retargeting = false
+			return m, func() tea.Msg { return LocalTargetMsg{IP: ip, Port: port} }
+		}
+	}
+	var cmd tea.Cmd
+	m.targetInput, cmd = m.targetInput.Update(msg)
+	return m, cmd
+}
+
+// parseLocalTarget reads "port" or "ip:port". A bare port keeps the IP of
+// current, the "ip:port" in use.
+func parseLocalTarget(s, current string) (string, int, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return "", 0, fmt.Errorf("请输入端口或 IP:端口")
+	}
+	ip, portStr, err := net.SplitHostPort(s)
+	if err != nil {
+		ip, _, _ = net.SplitHostPort(current)
+		portStr = s
+	} else if net.ParseIP(ip) == nil {
+		return "", 0, fmt.Errorf("无效的 IP 地址: %s", ip)
+	}
+	port, err := strconv.Atoi(portStr)
+	if err != nil || port < 1 || port > 65535 {
+		return "", 0, fmt.Errorf("端口必须是 1-65535 之间的数字")
+	}
+	if net.JoinHostPort(ip, portStr) == current {
+		return "", 0, fmt.Errorf("与当前本地映射相同")
+	}
+	return ip, port, nil
+}
+
+// EditingTarget reports whether the local address prompt is open, so keys
+// like "q" are typed into it rather than acted on.
+func (m RunningModel) EditingTarget() bool {
+	return m.retargeting
+}
+
+// SetLocalAddr updates the "本地映射" row after the tunnel was repointed.
+func (m *RunningModel) SetLocalAddr(addr string) {
+	m.localAddr = addr
+}
+
+// retargetView renders the local address prompt.
+func (m RunningModel) retargetView() string {
+	var b strings.Builder
+
+	b.WriteString(theme.BrandText(m.width))
+	b.WriteString("\n\n")
+	b.WriteString(theme.InputLabelStyle.Render("更改本地映射:"))
+	b.WriteString("\n")
+	b.WriteString(lipgloss.NewStyle().Foreground(theme.ColorTextDim).Render("  当前: " + m.localAddr + "，远程地址不变"))
+	b.WriteString("\n\n")
+	b.WriteString(theme.FocusedInputStyle.Render(m.targetInput.View()))
+
+	if m.targetErr != "" {
+		b.WriteString("\n\n")
+		b.WriteString(theme.ErrorStyle.Render("  " + theme.GlyphCross + " " + m.targetErr))
+	}
+
+	b.WriteString("\n")
+	b.WriteString(theme.HelpStyle.Render("[Enter] 切换  [Esc] 返回"))
+
+	return theme.AppBoxStyle.Render(b.String())
+}
@@ -0,0 +1,96 @@
+package views
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/AerNos/firefrp-client/internal/tui/theme"
+)
+
+// ReviewBackMsg is emitted when the user leaves the review view to carry
+// on (--stay-on-exit).
+type ReviewBackMsg struct{}
+
+// reviewInfoRows is the number of rows in the review view's summary box.
+const reviewInfoRows = 6
+
+// ReviewModel is the Bubble Tea model for the view shown when a session
+// ends on its own with --stay-on-exit: the final stats and the last log
+// lines of the running view, kept until the user moves on.
+type ReviewModel struct {
+	running RunningModel // the ended session's view, for its logs
+	reason  string
+	uptime  time.Duration // session length when it ended
+	bytes   int64         // forwarded bytes; -1 if not counted
+}
+
+// NewReviewModel creates a ReviewModel from the running view of the
+// session that just ended and why it ended.
+func NewReviewModel(running RunningModel, reason string) ReviewModel {
+	running.retargeting = false
+	running.sharing = false
+	bytes := int64(-1)
+	if running.traffic != nil {
+		bytes = running.sessionBytes()
+	}
+	return ReviewModel{running: running, reason: reason, uptime: running.Uptime(), bytes: bytes}
+}
+
+// Init implements tea.Model; the review has nothing to start.
+func (m ReviewModel) Init() tea.Cmd {
+	return nil
+}
+
+// Update handles messages for the review view.
+func (m ReviewModel) Update(msg tea.Msg) (ReviewModel, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.running.width = msg.Width
+		m.running.height = msg.Height
+	case tea.KeyMsg:
+		switch msg.String() {
+		case "enter", "esc":
+			return m, func() tea.Msg { return ReviewBackMsg{} }
+		case "q":
+			return m, tea.Quit
+		case "w":
+			m.running.wrapLogs = !m.running.wrapLogs
+		}
+	}
+	return m, nil
+}
+
+// View renders the final stats above the last log lines.
+func (m ReviewModel) View() string {
+	r := m.running
+	contentWidth := r.contentWidth()
+	var b strings.Builder
+
+	b.WriteString(theme.BrandText(m.running.width))
+	b.WriteString("\n\n")
+	b.WriteString("  " + theme.DotError + " " + theme.ErrorStyle.Render("隧道已断开"))
+	b.WriteString("\n")
+
+	traffic := "未统计"
+	if m.bytes >= 0 {
+		traffic = formatBytes(m.bytes)
+	}
+	info := strings.Join([]string{
+		theme.LabelStyle.Render("服务器:") + "  " + theme.ValueStyle.Render(r.serverName),
+		theme.LabelStyle.Render("远程地址:") + " " + theme.ValueStyle.Render(r.remoteAddr),
+		theme.LabelStyle.Render("运行时长:") + " " + theme.ValueStyle.Render(formatDuration(m.uptime)),
+		theme.LabelStyle.Render("累计流量:") + " " + theme.ValueStyle.Render(traffic),
+		theme.LabelStyle.Render("重连次数:") + " " + theme.ValueStyle.Render(fmt.Sprintf("%d", r.reconnects)),
+		theme.LabelStyle.Render("结束原因:") + " " + theme.ErrorStyle.Render(m.reason),
+	}, "\n")
+	b.WriteString(theme.BoxStyle.Render(theme.BoxTitleStyle.Render("会话摘要") + "\n" + info))
+	b.WriteString("\n")
+	b.WriteString(r.renderLogPanel(contentWidth, r.logRowsBelow(11+reviewInfoRows)))
+	b.WriteString("\n")
+	b.WriteString("  " + theme.HelpStyle.Render("[Enter] 返回  [W] 换行  [Q] 退出"))
+
+	return theme.AppBoxStyle.Copy().Width(contentWidth + appChromeWidth).Render(b.String())
+}
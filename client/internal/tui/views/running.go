@@ -5,9 +5,13 @@ import (
 	"strings"
 	"time"
 
-	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/bubbles/textinput"
 	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
 
+	"github.com/AerNos/firefrp-client/internal/api"
+	"github.com/AerNos/firefrp-client/internal/browser"
+	"github.com/AerNos/firefrp-client/internal/clipboard"
 	"github.com/AerNos/firefrp-client/internal/tui/theme"
 )
 
@@ -20,6 +24,79 @@ const (
 	StatusError                                // Tunnel encountered an error.
 )
 
+// MirrorStatus describes one mirror tunnel (--mirror-servers) shown in the
+// running view alongside the primary connection.
+type MirrorStatus struct {
+	Server     string
+	RemoteAddr string // empty until the mirror's key validation succeeds
+	Status     ConnectionStatus
+	Text       string
+}
+
+// Visitor summarises the connections seen from one remote IP, for the
+// "连接来源" panel (--show-visitors).
+type Visitor struct {
+	IP    string
+	Count int
+	Last  time.Time
+}
+
+// maxVisitorRows bounds the rows of the "连接来源" panel.
+const maxVisitorRows = 5
+
+// DefaultInfoFields are the connection info rows shown unless
+// --info-fields picks others.
+var DefaultInfoFields = []string{
+	"server", "remote", "endpoint", "local", "expiry", "remaining",
+	"renew", "connection", "session",
+}
+
+// infoFieldNames lists every row name --info-fields accepts.
+var infoFieldNames = map[string]bool{
+	"server": true, "remote": true, "endpoint": true, "local": true,
+	"expiry": true, "remaining": true, "renew": true, "uptime": true,
+	"traffic": true, "connection": true, "session": true,
+	"connected": true, "reconnected": true,
+}
+
+// InfoFieldsShowTraffic reports whether the info rows that SetInfoFields
+// would pick from names include a traffic figure, so the tunnel only
+// needs to count traffic then.
+func InfoFieldsShowTraffic(names []string) bool {
+	fields := DefaultInfoFields
+	var known []string
+	for _, name := range names {
+		if name = strings.ToLower(name); infoFieldNames[name] {
+			known = append(known, name)
+		}
+	}
+	if len(known) > 0 {
+		fields = known
+	}
+	for _, field := range fields {
+		switch field {
+		case "traffic", "connection", "session":
+			return true
+		}
+	}
+	return false
+}
+
+// logsCopiedMsg reports the outcome of copying logs to the clipboard.
+type logsCopiedMsg struct {
+	method clipboard.Method
+	err    error
+}
+
+// dashboardOpenedMsg reports whether the server's dashboard could be
+// opened in a browser.
+type dashboardOpenedMsg struct {
+	err error
+}
+
+// noticeDuration is how long a transient notice stays in the status line.
+const noticeDuration = 2 * time.Second
+
 // tickMsg is sent periodically to update the uptime counter.
 type tickMsg time.Time
 
@@ -28,34 +105,74 @@ type logEntry struct {
 	time    string
 	level   string
 	message string
+	divider bool // synthetic session boundary, rendered as a rule
 }
 
 // RunningModel is the Bubble Tea model for the "tunnel running" view.
 type RunningModel struct {
 	serverName string
 	remoteAddr string
+	endpoint   string // frps "host:port" the tunnel connected through
 	localAddr  string
 	expiresAt  time.Time
 	startedAt  time.Time
 	status     ConnectionStatus
 	statusText string
+	reconnects int // times the tunnel dropped and started reconnecting
 	width      int
 	height     int
 	logEntries []logEntry
 	maxLogs    int
+	wrapLogs   bool // soft-wrap long log lines instead of truncating
+	compact    bool // render a single status line instead of the full view
+	sharing    bool // show the share panel instead of the full view
+	mirrors    []MirrorStatus
+	infoFields []string  // rows of the info box, in order
+	notice     string    // transient confirmation shown in the status line
+	attempt    int       // reconnect attempt while reconnecting
+	noticeEnd  time.Time // when notice disappears
+
+	autoRenew    bool          // proactive renewal enabled (--auto-renew)
+	renewBefore  time.Duration // how long before expiry renewal happens
+	renewProblem string        // why renewal isn't working; empty if fine
+
+	// Per-connection vs session-wide figures. The session spans reconnects
+	// (and key renewals); it only resets with a new RunningModel.
+	connectedAt   time.Time     // start of the current connection; zero while down
+	connectedPrev time.Duration // connected time of earlier connections
+	lastConnect   time.Time     // when the latest connection was established
+	lastReconnect time.Time     // when the tunnel last dropped and began reconnecting; zero if never
+	connBytesBase int64         // session bytes when the current connection began
+	traffic       func() int64  // session byte count; nil if not counted
+
+	visitors func() []Visitor // recent visitors, newest first; nil if not shown
+	motd     string           // server's connect message; empty if none
+
+	dashboardURL  string // server's web dashboard; empty if none
+	showDashboard bool   // no browser could be opened: list the URL instead
+
+	bannerRows int // rows the app renders above this view (e.g. the insecure-TLS banner)
+
+	retargeting bool            // the local address prompt is open
+	targetInput textinput.Model // new local address
+	targetErr   string          // why the entered address was refused
 }
 
 // NewRunningModel creates a RunningModel with the supplied connection info.
 func NewRunningModel(serverName, remoteAddr, localAddr string, expiresAt time.Time) RunningModel {
+	now := time.Now()
 	return RunningModel{
-		serverName: serverName,
-		remoteAddr: remoteAddr,
-		localAddr:  localAddr,
-		expiresAt:  expiresAt,
-		startedAt:  time.Now(),
-		status:     StatusConnected,
-		statusText: "已连接",
-		maxLogs:    100,
+		serverName:  serverName,
+		remoteAddr:  remoteAddr,
+		localAddr:   localAddr,
+		expiresAt:   expiresAt,
+		startedAt:   now,
+		status:      StatusConnected,
+		statusText:  "已连接",
+		maxLogs:     100,
+		infoFields:  DefaultInfoFields,
+		connectedAt: now,
+		lastConnect: now,
 	}
 }
 
@@ -68,13 +185,197 @@ func (m RunningModel) Init() tea.Cmd {
 
 // SetStatus updates the displayed connection status.
 func (m *RunningModel) SetStatus(s ConnectionStatus, text string) {
+	switch {
+	case m.status == StatusConnected && s != StatusConnected:
+		// Connection lost: bank its connected time.
+		if s == StatusReconnecting {
+			m.reconnects++
+			m.lastReconnect = time.Now()
+		}
+		m.connectedPrev += time.Since(m.connectedAt)
+		m.connectedAt = time.Time{}
+		m.addDivider("断开", time.Now())
+	case m.status != StatusConnected && s == StatusConnected:
+		// A new connection begins.
+		m.connectedAt = time.Now()
+		m.lastConnect = m.connectedAt
+		m.connBytesBase = m.sessionBytes()
+		m.addDivider("重连", m.connectedAt)
+	}
 	m.status = s
 	m.statusText = text
+	if s != StatusReconnecting {
+		m.attempt = 0
+	}
+}
+
+// SetReconnect records the reconnect attempt number. frpc doesn't log its
+// backoff delay, so no countdown to the next attempt is shown.
+func (m *RunningModel) SetReconnect(attempt int) {
+	m.attempt = attempt
+}
+
+// reconnectText describes reconnect progress, e.g. "重连中 (第 2 次)".
+func (m RunningModel) reconnectText() string {
+	text := "重连中..."
+	if m.statusText != "" {
+		text = m.statusText
+	}
+	if m.attempt > 0 {
+		text = fmt.Sprintf("重连中 (第 %d 次)", m.attempt)
+	}
+	return text
+}
+
+// SetEndpoint records the frps endpoint the tunnel connected through and
+// the public address that results from it. An empty endpoint is ignored.
+func (m *RunningModel) SetEndpoint(endpoint, remoteAddr string) {
+	if endpoint == "" {
+		return
+	}
+	m.endpoint = endpoint
+	m.remoteAddr = remoteAddr
+}
+
+// SetExpiresAt updates the displayed key expiry (e.g. after a renewal).
+func (m *RunningModel) SetExpiresAt(t time.Time) {
+	m.expiresAt = t
+}
+
+// SetAutoRenew records whether the session renews its key ahead of expiry,
+// and how far ahead, for the auto-renew line.
+func (m *RunningModel) SetAutoRenew(enabled bool, before time.Duration) {
+	m.autoRenew = enabled
+	m.renewBefore = before
+}
+
+// SetRenewProblem shows why auto-renew isn't working (e.g. the server
+// doesn't support it) on the auto-renew line. Empty clears it.
+func (m *RunningModel) SetRenewProblem(text string) {
+	m.renewProblem = text
+}
+
+// NotifyRenewed records a successful renewal: the new expiry is shown and
+// a transient confirmation appears in the status line.
+func (m *RunningModel) NotifyRenewed(until time.Time) {
+	m.expiresAt = until
+	m.renewProblem = ""
+	m.setNotice("已续期至 " + until.Local().Format("15:04"))
+}
+
+// renewText formats the auto-renew line of the info box.
+func (m RunningModel) renewText() string {
+	if !m.autoRenew {
+		return theme.ValueStyle.Render("未开启")
+	}
+	text := fmt.Sprintf("已开启 (提前 %s)", formatLead(m.renewBefore))
+	if m.renewProblem != "" {
+		return theme.WarningStyle.Render(text + "，" + m.renewProblem)
+	}
+	return theme.ValueStyle.Render(text)
+}
+
+// formatLead formats a renewal lead time, e.g. "5 分钟" or "30 秒".
+func formatLead(d time.Duration) string {
+	switch {
+	case d >= time.Minute && d%time.Minute == 0:
+		return fmt.Sprintf("%d 分钟", int(d/time.Minute))
+	case d < time.Minute && d%time.Second == 0:
+		return fmt.Sprintf("%d 秒", int(d/time.Second))
+	default:
+		return d.String()
+	}
+}
+
+// SetInfoFields selects which rows the info box shows, and in which order
+// (--info-fields). Unknown names are skipped and returned so the caller can
+// warn about them; if nothing valid is left the default rows are kept.
+func (m *RunningModel) SetInfoFields(names []string) (unknown []string) {
+	var fields []string
+	for _, name := range names {
+		name = strings.ToLower(name)
+		if !infoFieldNames[name] {
+			unknown = append(unknown, name)
+			continue
+		}
+		fields = append(fields, name)
+	}
+	if len(fields) > 0 {
+		m.infoFields = fields
+	}
+	return unknown
+}
+
+// SetWrapLogs chooses between soft-wrapping and truncating long log lines.
+func (m *RunningModel) SetWrapLogs(wrap bool) {
+	m.wrapLogs = wrap
+}
+
+// SetTrafficSource sets the function reporting the session's total
+// forwarded bytes. Without one, traffic figures are omitted.
+func (m *RunningModel) SetTrafficSource(bytes func() int64) {
+	m.traffic = bytes
+	m.connBytesBase = m.sessionBytes()
+}
+
+// SetSources sets the function listing recent visitors, newest first, and
+// shows the "连接来源" panel. Without one the panel is hidden.
+func (m *RunningModel) SetSources(visitors func() []Visitor) {
+	m.visitors = visitors
+}
+
+// sessionBytes returns the session byte count, or 0 if not counted.
+func (m RunningModel) sessionBytes() int64 {
+	if m.traffic == nil {
+		return 0
+	}
+	return m.traffic()
+}
+
+// connectionUptime returns how long the current connection has been up.
+func (m RunningModel) connectionUptime() time.Duration {
+	if m.connectedAt.IsZero() {
+		return 0
+	}
+	return time.Since(m.connectedAt)
+}
+
+// sessionUptime returns the connected time summed over all connections.
+func (m RunningModel) sessionUptime() time.Duration {
+	return m.connectedPrev + m.connectionUptime()
+}
+
+// SetCompact chooses between the single-line status and the full view.
+func (m *RunningModel) SetCompact(compact bool) {
+	m.compact = compact
+}
+
+// SetBannerRows reserves rows the app renders above the view, so the log
+// panel still fits the terminal.
+func (m *RunningModel) SetBannerRows(n int) {
+	m.bannerRows = n
+}
+
+// SetMirrors replaces the mirror tunnels shown below the connection info.
+func (m *RunningModel) SetMirrors(mirrors []MirrorStatus) {
+	m.mirrors = mirrors
 }
 
 // AddLog appends a log entry and trims to maxLogs.
 func (m *RunningModel) AddLog(t, level, msg string) {
-	m.logEntries = append(m.logEntries, logEntry{time: t, level: level, message: msg})
+	m.appendLog(logEntry{time: t, level: level, message: msg})
+}
+
+// addDivider marks a disconnect or reconnect in the log, so session
+// boundaries stay visible in the scrollback.
+func (m *RunningModel) addDivider(what string, at time.Time) {
+	text := fmt.Sprintf("%s %s于 %s %s", theme.GlyphRule, what, at.Format("15:04:05"), theme.GlyphRule)
+	m.appendLog(logEntry{time: at.Format("15:04:05"), message: text, divider: true})
+}
+
+// appendLog appends e and trims the log to maxLogs.
+func (m *RunningModel) appendLog(e logEntry) {
+	m.logEntries = append(m.logEntries, e)
 	if len(m.logEntries) > m.maxLogs {
 		m.logEntries = m.logEntries[len(m.logEntries)-m.maxLogs:]
 	}
@@ -89,11 +390,73 @@ func (m RunningModel) Update(msg tea.Msg) (RunningModel, tea.Cmd) {
 		return m, nil
 
 	case tea.KeyMsg:
+		if m.sharing {
+			return m.updateShare(msg)
+		}
+		if m.retargeting {
+			return m.updateRetarget(msg)
+		}
 		switch msg.String() {
 		case "q", "ctrl+c":
 			return m, tea.Quit
+		case "h":
+			m.sharing = true
+			return m, nil
+		case "w":
+			m.wrapLogs = !m.wrapLogs
+			return m, nil
+		case "c":
+			m.compact = !m.compact
+			return m, nil
+		case "y":
+			if len(m.logEntries) == 0 {
+				m.setNotice("暂无日志可复制")
+				return m, nil
+			}
+			return m, m.copyLogs()
+		case "e":
+			if len(m.logEntries) == 0 {
+				m.setNotice("暂无日志可导出")
+				return m, nil
+			}
+			return m, m.exportLogs()
+		case "d":
+			if m.dashboardURL == "" {
+				return m, nil
+			}
+			return m, m.openDashboard()
+		case "l":
+			return m, m.openRetarget()
 		}
 
+	case addressCopiedMsg:
+		m.setNotice(copyNotice("地址", msg.method, msg.err))
+		return m, nil
+
+	case logsCopiedMsg:
+		m.setNotice(copyNotice("日志", msg.method, msg.err))
+		return m, nil
+
+	case logsExportedMsg:
+		if msg.err != nil {
+			m.setNotice("导出日志失败")
+			m.AddLog(time.Now().Format("15:04:05"), "E", "导出日志失败: "+msg.err.Error())
+			return m, nil
+		}
+		// The notice is brief, so the path also stays in the log.
+		m.setNotice("日志已导出")
+		m.AddLog(time.Now().Format("15:04:05"), "I", "日志已导出到 "+msg.path)
+		return m, nil
+
+	case dashboardOpenedMsg:
+		if msg.err != nil {
+			m.showDashboard = true
+			m.setNotice("无法打开浏览器，控制台地址见连接信息")
+			return m, nil
+		}
+		m.setNotice("已在浏览器中打开控制台")
+		return m, nil
+
 	case tickMsg:
 		// Re-schedule the next tick.
 		return m, tea.Tick(time.Second, func(t time.Time) tea.Msg {
@@ -101,31 +464,54 @@ func (m RunningModel) Update(msg tea.Msg) (RunningModel, tea.Cmd) {
 		})
 	}
 
+	if m.retargeting {
+		// Cursor blink.
+		var cmd tea.Cmd
+		m.targetInput, cmd = m.targetInput.Update(msg)
+		return m, cmd
+	}
 	return m, nil
 }
 
+// appChromeWidth is the horizontal chrome AppBoxStyle adds: border (2) +
+// padding (3*2=6).
+const appChromeWidth = 8
+
+// contentWidth returns the width available inside the app box, following
+// the terminal width within sensible bounds.
+func (m RunningModel) contentWidth() int {
+	if m.width <= 0 {
+		return 62 // default
+	}
+	w := m.width - appChromeWidth
+	if w < 40 {
+		w = 40
+	}
+	if w > 92 {
+		w = 92
+	}
+	return w
+}
+
 // View renders the running tunnel status view.
 func (m RunningModel) View() string {
-	// Determine dynamic content width.
-	// AppBoxStyle adds border (2) + padding (3*2=6) = 8 chars of chrome.
-	const chromeWidth = 8
-	contentWidth := 62 // default
-	if m.width > 0 {
-		w := m.width - chromeWidth
-		if w < 40 {
-			w = 40
-		}
-		if w > 92 {
-			w = 92
-		}
-		contentWidth = w
+	if m.retargeting {
+		return m.retargetView()
+	}
+	if m.compact {
+		return m.compactView()
 	}
-	boxWidth := contentWidth + chromeWidth
+	if m.sharing {
+		return m.shareView()
+	}
+
+	contentWidth := m.contentWidth()
+	boxWidth := contentWidth + appChromeWidth
 
 	var b strings.Builder
 
 	// Brand header.
-	b.WriteString(theme.BrandText())
+	b.WriteString(theme.BrandText(m.width))
 	b.WriteString("\n\n")
 
 	// Status indicator line.
@@ -142,81 +528,233 @@ func (m RunningModel) View() string {
 	// Connection info box.
 	infoTitle := theme.BoxTitleStyle.Render("连接信息")
 
-	// Calculate remaining time until expiry.
-	remaining := time.Until(m.expiresAt)
-	var remainingText string
-	if remaining <= 0 {
-		remainingText = theme.ErrorStyle.Render("已过期")
-	} else {
-		remainingText = formatDuration(remaining)
+	rows := make([]string, len(m.infoFields))
+	for i, field := range m.infoFields {
+		rows[i] = m.infoRow(field)
 	}
-
-	info := strings.Join([]string{
-		theme.LabelStyle.Render("服务器:") + "  " + theme.ValueStyle.Render(m.serverName),
-		theme.LabelStyle.Render("远程地址:") + " " + theme.ValueStyle.Render(m.remoteAddr),
-		theme.LabelStyle.Render("本地映射:") + " " + theme.ValueStyle.Render(m.localAddr),
-		theme.LabelStyle.Render("到期时间:") + " " + theme.ValueStyle.Render(m.expiresAt.Format("2006-01-02 15:04:05")),
-		theme.LabelStyle.Render("剩余时间:") + " " + theme.ValueStyle.Render(remainingText),
-		theme.LabelStyle.Render("运行时长:") + " " + theme.ValueStyle.Render(formatDuration(time.Since(m.startedAt))),
-	}, "\n")
+	if m.showDashboard {
+		rows = append(rows, theme.LabelStyle.Render("控制台:")+"  "+theme.ValueStyle.Render(m.dashboardURL))
+	}
+	info := strings.Join(rows, "\n")
 	boxContent := infoTitle + "\n" + info
 	box := theme.BoxStyle.Render(boxContent)
 	b.WriteString(box)
 
+	if m.motd != "" {
+		b.WriteString("\n")
+		b.WriteString(m.renderMotd(contentWidth))
+	}
+	if len(m.mirrors) > 0 {
+		b.WriteString("\n")
+		b.WriteString(m.renderMirrors(contentWidth))
+	}
+	if m.visitors != nil {
+		b.WriteString("\n")
+		b.WriteString(m.renderVisitors(contentWidth))
+	}
+
 	// Log panel.
 	b.WriteString("\n")
-	b.WriteString(m.renderLogPanel(contentWidth))
+	b.WriteString(m.renderLogPanel(contentWidth, m.visibleLogRows()))
 
 	// Status + help on a single line at the bottom.
 	b.WriteString("\n")
 	var statusLine string
 	switch m.status {
 	case StatusConnected:
-		statusLine = "状态: " + theme.SuccessStyle.Render("已连接 ✓")
+		statusLine = "状态: " + theme.SuccessStyle.Render("已连接 "+theme.GlyphCheck)
 	case StatusReconnecting:
-		statusLine = "状态: " + theme.WarningStyle.Render("重连中...")
+		statusLine = "状态: " + theme.WarningStyle.Render(m.reconnectText())
 	case StatusError:
 		statusLine = "状态: " + theme.ErrorStyle.Render(m.statusText)
 	}
-	helpText := theme.HelpStyle.Render("[Q] 断开并退出")
+	if m.notice != "" && time.Now().Before(m.noticeEnd) {
+		statusLine += "  " + theme.SuccessStyle.Render(m.notice)
+	}
+	help := "[H] 分享  [L] 本地映射  [W] 换行  [C] 精简  [Y] 复制日志  [E] 导出日志"
+	if m.dashboardURL != "" {
+		help += "  [D] 控制台"
+	}
+	helpText := theme.HelpStyle.Render(help + "  [Q] 断开并退出")
 	b.WriteString("  " + statusLine + "  " + helpText)
 
 	content := b.String()
 	return theme.AppBoxStyle.Copy().Width(boxWidth).Render(content)
 }
 
-// renderLogPanel builds the log display box.
-func (m RunningModel) renderLogPanel(contentWidth int) string {
-	// Calculate visible log lines based on terminal height.
-	visibleLogs := 8
-	if m.height > 0 {
-		// Reserve space for header (~4), info box (~8), status line (1), AppBox chrome (4).
-		available := m.height - 17
-		if available < 3 {
-			available = 3
+// infoRow renders one row of the connection info box.
+func (m RunningModel) infoRow(field string) string {
+	switch field {
+	case "server":
+		return theme.LabelStyle.Render("服务器:") + "  " + theme.ValueStyle.Render(m.serverName)
+	case "remote":
+		return theme.LabelStyle.Render("远程地址:") + " " + theme.ValueStyle.Render(m.remoteAddr)
+	case "endpoint":
+		return theme.LabelStyle.Render("中继节点:") + " " + theme.ValueStyle.Render(m.endpoint)
+	case "local":
+		return theme.LabelStyle.Render("本地映射:") + " " + theme.ValueStyle.Render(m.localAddr)
+	case "expiry":
+		return theme.LabelStyle.Render("到期时间:") + " " + theme.ValueStyle.Render(m.expiresAt.Format("2006-01-02 15:04:05"))
+	case "remaining":
+		return theme.LabelStyle.Render("剩余时间:") + " " + m.remainingView(time.Now())
+	case "renew":
+		return theme.LabelStyle.Render("自动续期:") + " " + m.renewText()
+	case "uptime":
+		return theme.LabelStyle.Render("运行时长:") + " " + theme.ValueStyle.Render(formatDuration(m.sessionUptime()))
+	case "traffic":
+		traffic := "未统计"
+		if m.traffic != nil {
+			traffic = formatBytes(m.sessionBytes())
 		}
-		if available > 16 {
-			available = 16
+		return theme.LabelStyle.Render("累计流量:") + " " + theme.ValueStyle.Render(traffic)
+	case "connection":
+		return theme.LabelStyle.Render("本次连接:") + " " + theme.ValueStyle.Render(m.connectionStats())
+	case "session":
+		return theme.LabelStyle.Render("会话累计:") + " " + theme.ValueStyle.Render(m.sessionStats())
+	case "connected":
+		return theme.LabelStyle.Render("最近连接:") + " " + theme.ValueStyle.Render(m.lastConnect.Format("15:04:05"))
+	case "reconnected":
+		reconnected := "无"
+		if !m.lastReconnect.IsZero() {
+			reconnected = m.lastReconnect.Format("15:04:05")
 		}
-		visibleLogs = available
+		return theme.LabelStyle.Render("最近重连:") + " " + theme.ValueStyle.Render(reconnected)
 	}
+	return ""
+}
 
-	// LogBoxStyle adds border (2) + padding (1*2=2) = 4 chars of horizontal chrome.
-	const logChromeWidth = 4
-	logContentWidth := contentWidth - logChromeWidth
-	if logContentWidth < 20 {
-		logContentWidth = 20
+// connectionStats formats uptime (and traffic) of the current connection.
+func (m RunningModel) connectionStats() string {
+	text := formatDuration(m.connectionUptime())
+	if m.traffic != nil {
+		text += "  " + formatBytes(m.sessionBytes()-m.connBytesBase)
+	}
+	return text
+}
+
+// sessionStats formats uptime, traffic and reconnects across the session.
+func (m RunningModel) sessionStats() string {
+	text := formatDuration(m.sessionUptime())
+	if m.traffic != nil {
+		text += "  " + formatBytes(m.sessionBytes())
+	}
+	return text + fmt.Sprintf("  重连 %d 次", m.reconnects)
+}
+
+// compactView renders the essentials on one line: status dot, remote
+// address, uptime and remaining time. Suited to small tmux panes.
+func (m RunningModel) compactView() string {
+	remaining := time.Until(m.expiresAt)
+	remainingText := "已过期"
+	if remaining > 0 {
+		remainingText = formatRemaining(remaining)
+	}
+	line := fmt.Sprintf("%s 运行 %s  剩余 %s  [C] 展开 [Q] 退出",
+		m.remoteAddr, formatDuration(time.Since(m.startedAt)), remainingText)
+	if m.width > 0 {
+		// Leave room for the status dot and its trailing space.
+		line = truncateWidth(line, m.width-2)
 	}
+	return StatusDot(m.status) + " " + line
+}
+
+// setNotice shows a transient message in the status line.
+func (m *RunningModel) setNotice(text string) {
+	m.notice = text
+	m.noticeEnd = time.Now().Add(noticeDuration)
+}
+
+// copyLogs returns a tea.Cmd that copies the log lines currently on screen
+// to the system clipboard, as plain text.
+func (m RunningModel) copyLogs() tea.Cmd {
+	text := formatLogs(m.logEntries[m.firstVisibleLog(m.visibleLogRows()):])
+
+	return func() tea.Msg {
+		method, err := clipboard.Write(text)
+		return logsCopiedMsg{method: method, err: err}
+	}
+}
+
+// formatLogs renders log entries as plain text, one per line.
+func formatLogs(entries []logEntry) string {
+	var b strings.Builder
+	for _, e := range entries {
+		if e.divider {
+			fmt.Fprintf(&b, "%s\n", e.message)
+			continue
+		}
+		fmt.Fprintf(&b, "%s [%s] %s\n", e.time, e.level, e.message)
+	}
+	return b.String()
+}
+
+// copyNotice describes the outcome of copying what to the clipboard.
+// Copies via the terminal (OSC 52) can't be confirmed, so they say so.
+func copyNotice(what string, method clipboard.Method, err error) string {
+	switch {
+	case err != nil:
+		return "剪贴板不可用，请手动选择复制" + what
+	case method == clipboard.OSC52:
+		return "已通过终端复制" + what
+	}
+	return "已复制" + what
+}
+
+// visibleLogRows returns how many log rows fit the terminal height.
+func (m RunningModel) visibleLogRows() int {
+	if m.height <= 0 {
+		return 8
+	}
+	// Reserve space for header (~3), info box (rows + 2), status line (1),
+	// AppBox chrome (4) and any banner above the view.
+	infoRows := len(m.infoFields)
+	if m.showDashboard {
+		infoRows++
+	}
+	return m.logRowsBelow(10 + m.bannerRows + infoRows + m.motdHeight() + m.mirrorsHeight() + m.visitorsHeight())
+}
+
+// logRowsBelow returns how many log rows fit once reserved rows of the
+// terminal are taken by everything else, within sensible bounds.
+func (m RunningModel) logRowsBelow(reserved int) int {
+	if m.height <= 0 {
+		return 8
+	}
+	available := m.height - reserved
+	if available < 3 {
+		available = 3
+	}
+	if available > 16 {
+		available = 16
+	}
+	return available
+}
+
+// renderLogPanel builds the log display box showing the last visibleLogs
+// rows.
+func (m RunningModel) renderLogPanel(contentWidth, visibleLogs int) string {
+	logContentWidth := logContentWidth(contentWidth)
 
 	logTitle := theme.BoxTitleStyle.Render("日志")
 
 	var lines []string
-	start := 0
-	if len(m.logEntries) > visibleLogs {
-		start = len(m.logEntries) - visibleLogs
-	}
-	for _, e := range m.logEntries[start:] {
-		lines = append(lines, m.formatLogLine(e, logContentWidth))
+	if m.wrapLogs {
+		// Walk back from the newest entry until the wrapped rows fill the
+		// budget; the oldest entry shown may be cut to its last rows.
+		for i := len(m.logEntries) - 1; i >= 0 && len(lines) < visibleLogs; i-- {
+			lines = append(m.formatLogRows(m.logEntries[i], logContentWidth), lines...)
+		}
+		if len(lines) > visibleLogs {
+			lines = lines[len(lines)-visibleLogs:]
+		}
+	} else {
+		start := 0
+		if len(m.logEntries) > visibleLogs {
+			start = len(m.logEntries) - visibleLogs
+		}
+		for _, e := range m.logEntries[start:] {
+			lines = append(lines, m.formatLogLine(e, logContentWidth))
+		}
 	}
 
 	// If no logs yet, show a placeholder.
@@ -228,9 +766,181 @@ func (m RunningModel) renderLogPanel(contentWidth int) string {
 	return theme.LogBoxStyle.Copy().Width(contentWidth).Render(logBody)
 }
 
-// formatLogLine formats a single log entry with colored level indicator.
+// logContentWidth returns the width of a log line inside the log panel.
+func logContentWidth(contentWidth int) int {
+	// LogBoxStyle adds border (2) + padding (1*2=2) = 4 chars of horizontal chrome.
+	const logChromeWidth = 4
+	w := contentWidth - logChromeWidth
+	if w < 20 {
+		w = 20
+	}
+	return w
+}
+
+// firstVisibleLog returns the index of the oldest log entry the log panel
+// shows, at least in part, in visibleLogs rows.
+func (m RunningModel) firstVisibleLog(visibleLogs int) int {
+	if !m.wrapLogs {
+		return max(len(m.logEntries)-visibleLogs, 0)
+	}
+	width := logContentWidth(m.contentWidth())
+	rows, i := 0, len(m.logEntries)
+	for i > 0 && rows < visibleLogs {
+		i--
+		rows += len(m.formatLogRows(m.logEntries[i], width))
+	}
+	return i
+}
+
+// logPrefixWidth is the width of the "HH:MM:SS [L] " prefix:
+// "HH:MM:SS" (8) + " " (1) + "[L]" (3) + " " (1) = 13 chars.
+const logPrefixWidth = 13
+
+// renderMirrors builds the box listing mirror tunnels and their status.
+func (m RunningModel) renderMirrors(contentWidth int) string {
+	lines := []string{theme.BoxTitleStyle.Render("镜像隧道")}
+	for _, mr := range m.mirrors {
+		addr := mr.RemoteAddr
+		if addr == "" {
+			addr = mr.Server
+		}
+		text := theme.LogTimeStyle.Render(mr.Text)
+		if mr.Status == StatusError {
+			text = theme.ErrorStyle.Render(mr.Text)
+		}
+		line := StatusDot(mr.Status) + " " + theme.ValueStyle.Render(addr) + "  " + text
+		lines = append(lines, truncateWidth(line, contentWidth-4))
+	}
+	return theme.LogBoxStyle.Copy().Width(contentWidth).Render(strings.Join(lines, "\n"))
+}
+
+// mirrorsHeight returns the rows taken by the mirror box (0 if none).
+func (m RunningModel) mirrorsHeight() int {
+	if len(m.mirrors) == 0 {
+		return 0
+	}
+	// Title + one row per mirror + border (2) + margin (1) + separator (1).
+	return len(m.mirrors) + 5
+}
+
+// SetDashboardURL sets the server's web dashboard, which [D] opens in a
+// browser. Empty, or anything but an http(s) URL, hides the binding.
+func (m *RunningModel) SetDashboardURL(url string) {
+	m.dashboardURL = api.CleanDashboardURL(url)
+}
+
+// openDashboard returns a tea.Cmd that opens the dashboard in the default
+// browser.
+func (m RunningModel) openDashboard() tea.Cmd {
+	url := m.dashboardURL
+	return func() tea.Msg {
+		return dashboardOpenedMsg{err: browser.Open(url)}
+	}
+}
+
+// maxMotdRows caps the server message box so a long message can't crowd
+// out the log panel.
+const maxMotdRows = 4
+
+// SetMotd sets the server's connect message shown above the connection
+// info. Empty hides the box.
+func (m *RunningModel) SetMotd(text string) {
+	m.motd = api.CleanMotd(text)
+}
+
+// motdLines wraps the server message to width, capped at maxMotdRows.
+func (m RunningModel) motdLines(width int) []string {
+	var lines []string
+	for _, line := range strings.Split(m.motd, "\n") {
+		lines = append(lines, wrapWidth(line, width)...)
+	}
+	if len(lines) > maxMotdRows {
+		lines = lines[:maxMotdRows]
+		lines[maxMotdRows-1] = truncateWidth(lines[maxMotdRows-1]+"...", width)
+	}
+	return lines
+}
+
+// renderMotd builds the box showing the server's connect message.
+func (m RunningModel) renderMotd(contentWidth int) string {
+	lines := []string{theme.BoxTitleStyle.Render("服务器公告")}
+	for _, line := range m.motdLines(contentWidth - 4) {
+		lines = append(lines, theme.ValueStyle.Render(line))
+	}
+	return theme.MotdBoxStyle.Copy().Width(contentWidth).Render(strings.Join(lines, "\n"))
+}
+
+// motdHeight returns the rows taken by the message box (0 if hidden).
+func (m RunningModel) motdHeight() int {
+	if m.motd == "" {
+		return 0
+	}
+	// Title + rows + border (2) + margin (1) + separator (1).
+	return len(m.motdLines(m.contentWidth()-4)) + 5
+}
+
+// renderVisitors builds the box listing recent visitor IPs, their
+// connection counts and when they last connected.
+func (m RunningModel) renderVisitors(contentWidth int) string {
+	lines := []string{theme.BoxTitleStyle.Render("连接来源")}
+	visitors := m.visitors()
+	if len(visitors) == 0 {
+		lines = append(lines, theme.LogTimeStyle.Render("暂无连接"))
+	}
+	if len(visitors) > maxVisitorRows {
+		visitors = visitors[:maxVisitorRows]
+	}
+	for _, v := range visitors {
+		line := theme.ValueStyle.Render(v.IP) + "  " +
+			theme.LogTimeStyle.Render(fmt.Sprintf("%d 次  最近 %s", v.Count, v.Last.Format("15:04:05")))
+		lines = append(lines, truncateWidth(line, contentWidth-4))
+	}
+	return theme.LogBoxStyle.Copy().Width(contentWidth).Render(strings.Join(lines, "\n"))
+}
+
+// visitorsHeight returns the rows taken by the visitor box (0 if hidden).
+func (m RunningModel) visitorsHeight() int {
+	if m.visitors == nil {
+		return 0
+	}
+	rows := len(m.visitors())
+	if rows < 1 {
+		rows = 1
+	}
+	if rows > maxVisitorRows {
+		rows = maxVisitorRows
+	}
+	// Title + rows + border (2) + margin (1) + separator (1).
+	return rows + 5
+}
+
+// formatLogLine formats a single log entry with colored level indicator,
+// truncating the message to fit on one row.
 func (m RunningModel) formatLogLine(e logEntry, maxWidth int) string {
+	if e.divider {
+		return theme.LogDividerStyle.Render(truncateWidth(e.message, maxWidth))
+	}
 	// Format: "HH:MM:SS [L] message"
+	return logPrefix(e) + truncateWidth(e.message, maxWidth-logPrefixWidth)
+}
+
+// formatLogRows formats a log entry soft-wrapped across as many rows as
+// needed, indenting continuation rows under the message column.
+func (m RunningModel) formatLogRows(e logEntry, maxWidth int) []string {
+	if e.divider {
+		return []string{m.formatLogLine(e, maxWidth)}
+	}
+	rows := wrapWidth(e.message, maxWidth-logPrefixWidth)
+	rows[0] = logPrefix(e) + rows[0]
+	indent := strings.Repeat(" ", logPrefixWidth)
+	for i := 1; i < len(rows); i++ {
+		rows[i] = indent + rows[i]
+	}
+	return rows
+}
+
+// logPrefix renders the timestamp and colored level indicator of a log entry.
+func logPrefix(e logEntry) string {
 	timeStr := theme.LogTimeStyle.Render(e.time)
 
 	var levelStr string
@@ -243,19 +953,7 @@ func (m RunningModel) formatLogLine(e logEntry, maxWidth int) string {
 		levelStr = theme.LogTimeStyle.Render("[" + e.level + "]")
 	}
 
-	// "HH:MM:SS" (8) + " " (1) + "[L]" (3) + " " (1) = 13 chars of prefix.
-	msgMaxWidth := maxWidth - 13
-	msg := e.message
-	if msgMaxWidth > 0 && lipgloss.Width(msg) > msgMaxWidth {
-		// Truncate the message to fit.
-		if msgMaxWidth > 3 {
-			msg = msg[:msgMaxWidth-3] + "..."
-		} else {
-			msg = msg[:msgMaxWidth]
-		}
-	}
-
-	return timeStr + " " + levelStr + " " + msg
+	return timeStr + " " + levelStr + " "
 }
 
 // Uptime returns the duration since the tunnel was started.
@@ -263,6 +961,59 @@ func (m RunningModel) Uptime() time.Duration {
 	return time.Since(m.startedAt)
 }
 
+// Summary returns the session figures for the summary view, ending with
+// the given reason.
+func (m RunningModel) Summary(reason string) SessionSummary {
+	return SessionSummary{
+		ServerName: m.serverName,
+		RemoteAddr: m.remoteAddr,
+		LocalAddr:  m.localAddr,
+		Uptime:     m.Uptime(),
+		Reconnects: m.reconnects,
+		Reason:     reason,
+	}
+}
+
+// expiryWarning is how close to expiry the remaining time turns urgent.
+const expiryWarning = 5 * time.Minute
+
+// remainingView renders the time left until expiry as of now. It is
+// redrawn on every tick, so a dot pulsing with the seconds marks it as
+// live; within expiryWarning the value blinks between the warning and
+// error colors.
+func (m RunningModel) remainingView(now time.Time) string {
+	remaining := m.expiresAt.Sub(now)
+	if remaining <= 0 {
+		return theme.ErrorStyle.Render("已过期")
+	}
+	even := now.Unix()%2 == 0
+	style := theme.ValueStyle
+	if remaining <= expiryWarning {
+		style = theme.WarningStyle
+		if even {
+			style = theme.ErrorStyle
+		}
+	}
+	pulse := lipgloss.NewStyle().Foreground(theme.ColorTextDim)
+	if even {
+		pulse = pulse.Foreground(theme.ColorSuccess)
+	}
+	return style.Render(formatRemaining(remaining)) + " " + pulse.Render(theme.GlyphDot)
+}
+
+// formatRemaining formats the time left until expiry: HH:MM:SS from an
+// hour up, and in words below, e.g. "4 分 12 秒".
+func formatRemaining(d time.Duration) string {
+	d = d.Round(time.Second)
+	switch {
+	case d >= time.Hour:
+		return formatDuration(d)
+	case d >= time.Minute:
+		return fmt.Sprintf("%d 分 %d 秒", int(d.Minutes()), int(d.Seconds())%60)
+	}
+	return fmt.Sprintf("%d 秒", int(d.Seconds()))
+}
+
 // formatDuration formats a duration as HH:MM:SS.
 func formatDuration(d time.Duration) string {
 	d = d.Round(time.Second)
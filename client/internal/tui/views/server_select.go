@@ -1,9 +1,11 @@
 package views
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"strings"
-	"sync"
+	"time"
 
 	"github.com/charmbracelet/bubbles/spinner"
 	"github.com/charmbracelet/bubbles/textinput"
@@ -18,42 +20,69 @@ import (
 type ServerSelectedMsg struct {
 	APIUrl        string
 	ServerName    string // Display name (from discovery or manual URL).
+	PublicAddr    string // Public host reported by server-info (empty for manual entry).
 	ClientVersion string // Expected client version reported by this server.
 	UpdateChannel string // Update channel (auto/dev/stable) reported by this server.
+	FrpVersion    string // frps version reported by this server, if any.
+	Motd          string // Operator message reported by this server, if any.
+	TosURL        string // Terms of service to accept before connecting, if any.
+	TosVersion    string // Version of those terms; empty if the server has none.
+	DashboardURL  string // Relay's web dashboard, if any.
+	// Latency of the probe when the server was picked automatically
+	// (--auto-server, or as the only reachable one); zero when the user
+	// chose it.
+	Latency time.Duration
+	// Only is set when the server was picked because it was the only
+	// reachable one.
+	Only bool
 }
 
-// serverEntry holds a discovered server with its status.
-type serverEntry struct {
-	apiUrl string
-	info   *api.ServerInfo
-	err    error // non-nil if the server is unreachable
+// serverListMsg is sent when the server list has been fetched. A list
+// that loaded fine but has no entries comes with empty urls and no err.
+type serverListMsg struct {
+	urls []string
+	err  error // non-nil if the list itself failed to load
 }
 
-// serversLoadedMsg is sent when the server list has been fetched and probed.
-type serversLoadedMsg struct {
-	servers []serverEntry
-	err     error // non-nil if the list itself failed to load
+// probeResultMsg delivers one server's probe result as it completes; done
+// is set once every server has reported. ch identifies the probe run.
+type probeResultMsg struct {
+	ch     <-chan api.ProbeResult
+	result api.ProbeResult
+	done   bool
 }
 
 // ServerSelectModel is the Bubble Tea model for the server selection view.
 type ServerSelectModel struct {
-	servers       []serverEntry
-	cursor        int
-	loading       bool
-	loadErr       string
-	spinner       spinner.Model
-	manualInput   textinput.Model
-	manualMode    bool // true when cursor is on the manual input row
-	width         int
-	height        int
-	serverListURL string
+	servers        []api.ProbeResult
+	cursor         int
+	loading        bool
+	loadErr        string
+	listEmpty      bool                   // the list loaded but has no servers
+	probing        bool                   // probe results are still coming in
+	probeCh        <-chan api.ProbeResult // current probe run
+	stopProbes     context.CancelFunc     // ends the probe run's deadline
+	spinner        spinner.Model
+	manualInput    textinput.Model
+	manualMode     bool // true when cursor is on the manual input row
+	width          int
+	height         int
+	serverListURL  string
+	serverListAuth string
+	staticServers  []string // from --servers; replaces the list download
+	autoPick       bool     // pick the fastest server without asking (--auto-server)
+	autoSingle     bool     // pick the only reachable server without asking
+	probeTimeout   time.Duration
+	probeOpts      []api.Option // extra client options for the probes
+	probeWorkers   int          // probes in flight at once; 0 for the default
 }
 
 // NewServerSelectModel creates a ServerSelectModel that will fetch servers
-// from the given URL.
-func NewServerSelectModel(serverListURL string) ServerSelectModel {
+// from the given URL (sending serverListAuth, if set, as its Authorization
+// header), bounding each discovery request by probeTimeout.
+func NewServerSelectModel(serverListURL, serverListAuth string, probeTimeout time.Duration) ServerSelectModel {
 	s := spinner.New()
-	s.Spinner = spinner.Dot
+	s.Spinner = theme.SpinnerType
 	s.Style = theme.SpinnerStyle
 
 	mi := textinput.New()
@@ -65,13 +94,47 @@ func NewServerSelectModel(serverListURL string) ServerSelectModel {
 	mi.PlaceholderStyle = lipgloss.NewStyle().Foreground(theme.ColorTextDim)
 
 	return ServerSelectModel{
-		loading:       true,
-		spinner:       s,
-		manualInput:   mi,
-		serverListURL: serverListURL,
+		loading:        true,
+		spinner:        s,
+		manualInput:    mi,
+		serverListURL:  serverListURL,
+		serverListAuth: serverListAuth,
+		probeTimeout:   probeTimeout,
 	}
 }
 
+// SetServers seeds the view with fixed API URLs (from --servers). They are
+// probed like list entries, but no server list is downloaded.
+func (m *ServerSelectModel) SetServers(urls []string) {
+	m.staticServers = urls
+}
+
+// SetAutoPick makes the view select the fastest reachable server as soon
+// as the probes finish (--auto-server). If none is reachable, the list is
+// shown for manual selection as usual.
+func (m *ServerSelectModel) SetAutoPick(auto bool) {
+	m.autoPick = auto
+}
+
+// SetAutoSingle makes the view select the server without asking when it
+// is the only reachable one. With several or none reachable, the list is
+// shown as usual.
+func (m *ServerSelectModel) SetAutoSingle(auto bool) {
+	m.autoSingle = auto
+}
+
+// SetProbeOptions sets extra API client options for the server probes,
+// e.g. api.WithInsecureSkipVerify.
+func (m *ServerSelectModel) SetProbeOptions(opts ...api.Option) {
+	m.probeOpts = opts
+}
+
+// SetProbeConcurrency caps how many servers are probed at once
+// (--probe-concurrency).
+func (m *ServerSelectModel) SetProbeConcurrency(n int) {
+	m.probeWorkers = n
+}
+
 // Init returns the initial commands: start spinner and fetch server list.
 func (m ServerSelectModel) Init() tea.Cmd {
 	return tea.Batch(m.spinner.Tick, m.fetchServers())
@@ -86,14 +149,14 @@ func (m ServerSelectModel) Update(msg tea.Msg) (ServerSelectModel, tea.Cmd) {
 		return m, nil
 
 	case spinner.TickMsg:
-		if m.loading {
+		if m.loading || m.probing {
 			var cmd tea.Cmd
 			m.spinner, cmd = m.spinner.Update(msg)
 			return m, cmd
 		}
 		return m, nil
 
-	case serversLoadedMsg:
+	case serverListMsg:
 		m.loading = false
 		if msg.err != nil {
 			m.loadErr = msg.err.Error()
@@ -102,12 +165,54 @@ func (m ServerSelectModel) Update(msg tea.Msg) (ServerSelectModel, tea.Cmd) {
 			m.manualInput.Focus()
 			return m, textinput.Blink
 		}
-		m.servers = msg.servers
+		if len(msg.urls) == 0 {
+			// Nothing to probe; the list view offers a refresh and
+			// manual entry.
+			m.listEmpty = true
+			m.servers = nil
+			m.cursor = 0
+			return m, nil
+		}
+		// List every server as pending and fill in results as they arrive.
+		m.servers = make([]api.ProbeResult, len(msg.urls))
+		for i, u := range msg.urls {
+			m.servers[i] = api.ProbeResult{Index: i, APIUrl: u}
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), api.ProbeDeadline(m.probeTimeout))
+		m.stopProbes = cancel
+		m.probeCh = api.StreamProbes(ctx, msg.urls, m.probeTimeout, m.probeWorkers, m.probeOpts...)
+		m.probing = true
+		return m, waitForProbe(m.probeCh)
+
+	case probeResultMsg:
+		if msg.ch != m.probeCh {
+			return m, nil
+		}
+		if !msg.done {
+			m.servers[msg.result.Index] = msg.result
+			return m, waitForProbe(m.probeCh)
+		}
+		m.probing = false
+		m.stopProbes()
+		if m.autoPick {
+			if best, ok := api.Fastest(m.servers); ok {
+				return m, func() tea.Msg { return selectedMsg(best.Info, best.Latency) }
+			}
+		}
+		if m.autoSingle && !m.manualMode {
+			if only, ok := onlyReachable(m.servers); ok {
+				return m, func() tea.Msg {
+					msg := selectedMsg(only.Info, only.Latency)
+					msg.Only = true
+					return msg
+				}
+			}
+		}
 		return m, nil
 
 	case tea.KeyMsg:
 		if msg.String() == "ctrl+c" || msg.String() == "esc" {
-			if m.manualMode && len(m.servers) > 0 {
+			if m.manualMode && (len(m.servers) > 0 || m.listEmpty) {
 				// Exit manual mode, go back to list
 				m.manualMode = false
 				m.manualInput.Blur()
@@ -149,20 +254,22 @@ func (m ServerSelectModel) handleListNavigation(msg tea.KeyMsg) (ServerSelectMod
 		if m.cursor < totalItems-1 {
 			m.cursor++
 		}
+	case "r", "R":
+		if !m.listEmpty {
+			return m, nil
+		}
+		m.listEmpty = false
+		m.loading = true
+		return m, tea.Batch(m.spinner.Tick, m.fetchServers())
 	case "enter":
 		if m.cursor < len(m.servers) {
 			entry := m.servers[m.cursor]
-			if entry.err != nil {
-				// Can't select an offline server
+			if entry.Err != nil || entry.Info == nil {
+				// Can't select an offline or not yet probed server
 				return m, nil
 			}
-			name := entry.info.Name
-			apiUrl := entry.apiUrl
-			clientVersion := entry.info.ClientVersion
-			updateChannel := entry.info.UpdateChannel
-			return m, func() tea.Msg {
-				return ServerSelectedMsg{APIUrl: apiUrl, ServerName: name, ClientVersion: clientVersion, UpdateChannel: updateChannel}
-			}
+			info := entry.Info
+			return m, func() tea.Msg { return selectedMsg(info, 0) }
 		}
 		// Manual input option selected
 		m.manualMode = true
@@ -194,11 +301,42 @@ func (m ServerSelectModel) handleManualInput(msg tea.KeyMsg) (ServerSelectModel,
 	return m, cmd
 }
 
+// onlyReachable returns the single reachable server among results, and
+// false if none or several are reachable.
+func onlyReachable(results []api.ProbeResult) (api.ProbeResult, bool) {
+	var only api.ProbeResult
+	n := 0
+	for _, r := range results {
+		if r.Err == nil && r.Info != nil {
+			only = r
+			n++
+		}
+	}
+	return only, n == 1
+}
+
+// selectedMsg builds the selection message for a probed server.
+func selectedMsg(info *api.ServerInfo, latency time.Duration) ServerSelectedMsg {
+	return ServerSelectedMsg{
+		APIUrl:        info.APIUrl,
+		ServerName:    info.Name,
+		PublicAddr:    info.PublicAddr,
+		ClientVersion: info.ClientVersion,
+		UpdateChannel: info.UpdateChannel,
+		FrpVersion:    info.FrpVersion,
+		Motd:          info.Motd,
+		TosURL:        info.TosURL,
+		TosVersion:    info.TosVersion,
+		DashboardURL:  info.DashboardURL,
+		Latency:       latency,
+	}
+}
+
 // View renders the server selection view.
 func (m ServerSelectModel) View() string {
 	var b strings.Builder
 
-	b.WriteString(theme.BrandText())
+	b.WriteString(theme.BrandText(m.width))
 	b.WriteString("\n\n")
 
 	b.WriteString(theme.InputLabelStyle.Render("选择服务器:"))
@@ -207,11 +345,15 @@ func (m ServerSelectModel) View() string {
 	if m.loading {
 		b.WriteString("\n")
 		b.WriteString(m.spinner.View())
-		b.WriteString(" 正在获取服务器列表...")
+		if m.autoPick {
+			b.WriteString(" 正在测速并选择最快的服务器...")
+		} else {
+			b.WriteString(" 正在获取服务器列表...")
+		}
 		b.WriteString("\n")
 	} else if m.loadErr != "" && len(m.servers) == 0 {
 		b.WriteString("\n")
-		b.WriteString(theme.ErrorStyle.Render("  ✗ 获取服务器列表失败: " + m.loadErr))
+		b.WriteString(theme.ErrorStyle.Render("  " + theme.GlyphCross + " 获取服务器列表失败: " + m.loadErr))
 		b.WriteString("\n\n")
 		b.WriteString(theme.InputLabelStyle.Render("手动输入服务器地址:"))
 		b.WriteString("\n")
@@ -223,26 +365,51 @@ func (m ServerSelectModel) View() string {
 		b.WriteString(theme.FocusedInputStyle.Render(m.manualInput.View()))
 	} else {
 		b.WriteString("\n")
+		if m.listEmpty {
+			b.WriteString(theme.WarningStyle.Render("  暂无可用服务器，可稍后刷新或手动输入地址"))
+			b.WriteString("\n")
+		}
 		for i, entry := range m.servers {
 			selected := i == m.cursor
 
+			dim := lipgloss.NewStyle().Foreground(theme.ColorTextDim)
+			textWidth := m.serverTextWidth()
+
 			var line string
-			if entry.err != nil {
-				// Offline server
-				dot := lipgloss.NewStyle().Foreground(theme.ColorError).Bold(true).Render("●")
-				name := lipgloss.NewStyle().Foreground(theme.ColorTextDim).Render(entry.apiUrl + " (离线)")
+			switch {
+			case entry.Err != nil:
+				// Offline server, or one that didn't answer in time
+				state := " (离线)"
+				switch {
+				case errors.Is(entry.Err, api.ErrProbeTimeout):
+					state = " (超时)"
+				case errors.Is(entry.Err, api.ErrNotProbed):
+					state = " (未测速)"
+				case errors.Is(entry.Err, api.ErrNotJSON):
+					state = " (非 API 地址)"
+				}
+				dot := theme.DotError
+				name := dim.Render(truncateWidth(entry.APIUrl+state, textWidth))
 				line = fmt.Sprintf("  %s %s", dot, name)
-			} else {
-				dot := lipgloss.NewStyle().Foreground(theme.ColorSuccess).Bold(true).Render("●")
-				name := entry.info.Name
-				desc := lipgloss.NewStyle().Foreground(theme.ColorTextDim).Render(
-					fmt.Sprintf(" (%s) %s", entry.info.PublicAddr, entry.info.Description),
-				)
-				line = fmt.Sprintf("  %s %s%s", dot, name, desc)
+			case entry.Info == nil:
+				// Still being probed
+				name := dim.Render(truncateWidth(entry.APIUrl+" (测速中...)", textWidth))
+				line = fmt.Sprintf("  %s %s", m.spinner.View(), name)
+			default:
+				// Truncate name and description together, then style the
+				// part of the description that survived.
+				dot := theme.DotConnected
+				name := entry.Info.Name
+				full := name + fmt.Sprintf(" (%s) %s", entry.Info.PublicAddr, entry.Info.Description)
+				text := truncateWidth(full, textWidth)
+				if strings.HasPrefix(text, name) {
+					text = name + dim.Render(text[len(name):])
+				}
+				line = fmt.Sprintf("  %s %s", dot, text)
 			}
 
 			if selected {
-				line = lipgloss.NewStyle().Foreground(theme.ColorPrimary).Bold(true).Render("▸") + line[1:]
+				line = lipgloss.NewStyle().Foreground(theme.ColorPrimary).Bold(true).Render(theme.GlyphCursor) + line[1:]
 			}
 
 			b.WriteString(line)
@@ -250,10 +417,10 @@ func (m ServerSelectModel) View() string {
 		}
 
 		// Manual input option
-		manualLine := "  ✎ 手动输入地址..."
+		manualLine := "  " + theme.GlyphPencil + " 手动输入地址..."
 		if m.cursor == len(m.servers) {
-			manualLine = lipgloss.NewStyle().Foreground(theme.ColorPrimary).Bold(true).Render("▸") +
-				" ✎ 手动输入地址..."
+			manualLine = lipgloss.NewStyle().Foreground(theme.ColorPrimary).Bold(true).Render(theme.GlyphCursor) +
+				" " + theme.GlyphPencil + " 手动输入地址..."
 		}
 		b.WriteString(manualLine)
 	}
@@ -263,50 +430,61 @@ func (m ServerSelectModel) View() string {
 	if m.manualMode {
 		help := theme.HelpStyle.Render("[Enter] 确认  [Esc] 返回")
 		b.WriteString(help)
+	} else if m.probing && m.autoPick {
+		b.WriteString(theme.HelpStyle.Render("正在测速并选择最快的服务器..."))
+	} else if m.listEmpty {
+		b.WriteString(theme.HelpStyle.Render("[Enter] 手动输入  [R] 刷新  [Esc] 退出"))
 	} else if !m.loading {
-		help := theme.HelpStyle.Render("[↑/↓] 选择  [Enter] 确认  [Esc] 退出")
+		help := theme.HelpStyle.Render("[" + theme.GlyphUpDown + "] 选择  [Enter] 确认  [Esc] 退出")
 		b.WriteString(help)
 	}
 
+	// Narrow the box on small terminals so no line wraps past the edge.
+	box := theme.AppBoxStyle
 	content := b.String()
-	return theme.AppBoxStyle.Render(content)
+	return box.Copy().Width(appBoxContentWidth(m.width) + box.GetHorizontalPadding()).Render(content)
 }
 
-// fetchServers returns a tea.Cmd that fetches the server list and probes each server.
+// serverTextWidth returns the display cells available for a server's name
+// and description inside the app box.
+func (m ServerSelectModel) serverTextWidth() int {
+	// The cursor/indent, status dot and spaces take 4 cells.
+	return max(appBoxContentWidth(m.width)-4, 10)
+}
+
+// fetchServers returns a tea.Cmd that fetches the server list. The servers
+// are probed once it arrives.
 func (m *ServerSelectModel) fetchServers() tea.Cmd {
 	url := m.serverListURL
+	auth := m.serverListAuth
+	timeout := m.probeTimeout
+	static := m.staticServers
 	return func() tea.Msg {
-		entries, err := api.FetchServerList(url)
-		if err != nil {
-			return serversLoadedMsg{err: err}
-		}
-
-		if len(entries) == 0 {
-			return serversLoadedMsg{err: fmt.Errorf("服务器列表为空")}
+		var entries []api.ServerListEntry
+		if len(static) > 0 {
+			for _, u := range static {
+				entries = append(entries, api.ServerListEntry{APIUrl: u})
+			}
+		} else {
+			var err error
+			entries, err = api.FetchServerList(url, auth, timeout)
+			if err != nil {
+				return serverListMsg{err: err}
+			}
 		}
 
-		// Probe each server concurrently
-		results := make([]serverEntry, len(entries))
-		var wg sync.WaitGroup
-
+		urls := make([]string, len(entries))
 		for i, entry := range entries {
-			wg.Add(1)
-			go func(idx int, apiUrl string) {
-				defer wg.Done()
-				client := api.NewAPIClient(apiUrl)
-				info, err := client.FetchServerInfo()
-				results[idx] = serverEntry{
-					apiUrl: apiUrl,
-					info:   info,
-					err:    err,
-				}
-				if info != nil {
-					results[idx].info.APIUrl = apiUrl
-				}
-			}(i, entry.APIUrl)
+			urls[i] = entry.APIUrl
 		}
+		return serverListMsg{urls: urls}
+	}
+}
 
-		wg.Wait()
-		return serversLoadedMsg{servers: results}
+// waitForProbe returns a tea.Cmd that waits for the next probe result.
+func waitForProbe(ch <-chan api.ProbeResult) tea.Cmd {
+	return func() tea.Msg {
+		r, ok := <-ch
+		return probeResultMsg{ch: ch, result: r, done: !ok}
 	}
 }
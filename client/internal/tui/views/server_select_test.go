@@ -0,0 +1,63 @@
+package views
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+
+	"github.com/AerNos/firefrp-client/internal/api"
+	"github.com/AerNos/firefrp-client/internal/tui/theme"
+)
+
+func TestServerSelectTruncatesLongDescription(t *testing.T) {
+	info := &api.ServerInfo{
+		Name:        "华东节点",
+		PublicAddr:  "mc.example.com",
+		Description: strings.Repeat("这是一个非常长的中文服务器描述，用于测试截断", 4),
+	}
+	tests := []struct {
+		name  string
+		ascii bool
+		width int
+	}{
+		{"40 columns", false, 40},
+		{"80 columns", false, 80},
+		// theme.SetASCII cannot be undone, so the ASCII cases run last.
+		{"ascii 40 columns", true, 40},
+		{"ascii 80 columns", true, 80},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.ascii {
+				theme.SetASCII(true)
+			}
+			m := NewServerSelectModel("", "", time.Second)
+			m.loading = false
+			m.servers = []api.ProbeResult{{APIUrl: "https://relay.example.com", Info: info}}
+			m, _ = m.Update(tea.WindowSizeMsg{Width: tt.width, Height: 40})
+
+			var entry string
+			for _, line := range strings.Split(m.View(), "\n") {
+				if w := lipgloss.Width(line); w > tt.width {
+					t.Errorf("line is %d cells wide, want at most %d: %q", w, tt.width, line)
+				}
+				if strings.Contains(line, info.Name) {
+					entry = line
+				}
+			}
+			if entry == "" {
+				t.Fatal("server entry not rendered")
+			}
+			text := strings.TrimSpace(strings.Trim(strings.TrimSpace(entry), "│|"))
+			if !strings.HasSuffix(text, "...") {
+				t.Errorf("truncated entry %q does not end with an ellipsis", text)
+			}
+			if strings.Contains(entry, info.Description) {
+				t.Errorf("entry %q shows the full description", entry)
+			}
+		})
+	}
+}
@@ -0,0 +1,131 @@
+package views
+
+import (
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+
+	"github.com/AerNos/firefrp-client/internal/clipboard"
+	"github.com/AerNos/firefrp-client/internal/qr"
+	"github.com/AerNos/firefrp-client/internal/tui/theme"
+)
+
+// addressCopiedMsg reports the outcome of copying the remote address.
+type addressCopiedMsg struct {
+	method clipboard.Method
+	err    error
+}
+
+// qrQuietZone is the light margin around the QR code, in modules.
+const qrQuietZone = 2
+
+// shareInstruction tells the recipient what to do with the address.
+const shareInstruction = "将此地址填入游戏的服务器地址"
+
+// qrStyle pins the QR colors so it scans regardless of the terminal theme.
+var qrStyle = lipgloss.NewStyle().
+	Foreground(lipgloss.Color("#FFFFFF")).
+	Background(lipgloss.Color("#000000"))
+
+// updateShare handles keys while the share panel is open.
+func (m RunningModel) updateShare(msg tea.KeyMsg) (RunningModel, tea.Cmd) {
+	switch msg.String() {
+	case "q", "ctrl+c":
+		return m, tea.Quit
+	case "h", "esc":
+		m.sharing = false
+	case "y", "enter":
+		return m, m.copyAddress()
+	}
+	return m, nil
+}
+
+// copyAddress returns a tea.Cmd that copies the remote address to the
+// clipboard.
+func (m RunningModel) copyAddress() tea.Cmd {
+	addr := m.remoteAddr
+	return func() tea.Msg {
+		method, err := clipboard.Write(addr)
+		return addressCopiedMsg{method: method, err: err}
+	}
+}
+
+// shareView renders the share panel: the remote address, a short
+// instruction and, when it fits the terminal, a QR code of the address.
+func (m RunningModel) shareView() string {
+	var b strings.Builder
+
+	b.WriteString(theme.BrandText(m.width))
+	b.WriteString("\n\n")
+
+	info := strings.Join([]string{
+		theme.LabelStyle.Render("地址:") + " " + theme.SuccessStyle.Render(m.remoteAddr),
+		theme.LabelStyle.Render("说明:") + " " + theme.ValueStyle.Render(shareInstruction),
+	}, "\n")
+	b.WriteString(theme.BoxStyle.Render(theme.BoxTitleStyle.Render("分享隧道") + "\n" + info))
+	b.WriteString("\n")
+
+	code, err := qr.Encode(m.remoteAddr)
+	switch {
+	case err != nil:
+		b.WriteString(theme.HelpStyle.Render("  地址过长，无法生成二维码"))
+	case theme.ASCII:
+		b.WriteString(theme.HelpStyle.Render("  ASCII 模式下不显示二维码"))
+	case !m.qrFits(code):
+		b.WriteString(theme.HelpStyle.Render("  窗口过小，已隐藏二维码"))
+	default:
+		b.WriteString(renderQR(code))
+	}
+	b.WriteString("\n")
+
+	statusLine := ""
+	if m.notice != "" && time.Now().Before(m.noticeEnd) {
+		statusLine = theme.SuccessStyle.Render(m.notice) + "  "
+	}
+	b.WriteString("  " + statusLine + theme.HelpStyle.Render("[Y] 复制地址  [H] 返回  [Q] 断开并退出"))
+
+	return theme.AppBoxStyle.Render(b.String())
+}
+
+// qrFits reports whether the QR code fits the terminal next to the rest of
+// the share panel. An unknown terminal size is assumed to fit.
+func (m RunningModel) qrFits(code *qr.Code) bool {
+	side := code.Size + 2*qrQuietZone
+	// AppBoxStyle chrome is 8 columns; header, address box, help line and
+	// box chrome take about 12 rows.
+	if m.width > 0 && m.width < side+8 {
+		return false
+	}
+	if m.height > 0 && m.height < (side+1)/2+12 {
+		return false
+	}
+	return true
+}
+
+// renderQR draws the code with half-block characters, two modules per
+// text row, light modules in the foreground color.
+func renderQR(code *qr.Code) string {
+	side := code.Size + 2*qrQuietZone
+	var rows []string
+	for y := 0; y < side; y += 2 {
+		var row strings.Builder
+		for x := 0; x < side; x++ {
+			top := !code.Dark(x-qrQuietZone, y-qrQuietZone)
+			bottom := y+1 >= side || !code.Dark(x-qrQuietZone, y+1-qrQuietZone)
+			switch {
+			case top && bottom:
+				row.WriteString("█")
+			case top:
+				row.WriteString("▀")
+			case bottom:
+				row.WriteString("▄")
+			default:
+				row.WriteString(" ")
+			}
+		}
+		rows = append(rows, "  "+qrStyle.Render(row.String()))
+	}
+	return strings.Join(rows, "\n")
+}
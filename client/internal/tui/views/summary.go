@@ -0,0 +1,75 @@
+package views
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/AerNos/firefrp-client/internal/tui/theme"
+)
+
+// SessionSummary holds the figures shown when a tunnel session ends.
+type SessionSummary struct {
+	ServerName string
+	RemoteAddr string
+	LocalAddr  string
+	Uptime     time.Duration
+	Reconnects int
+	Reason     string
+}
+
+// SummaryModel is the Bubble Tea model for the short "session ended" view
+// shown before the client quits. Any key quits immediately.
+type SummaryModel struct {
+	summary SessionSummary
+	width   int
+}
+
+// NewSummaryModel creates a SummaryModel for the given session.
+func NewSummaryModel(s SessionSummary) SummaryModel {
+	return SummaryModel{summary: s}
+}
+
+// Init implements tea.Model; the summary has nothing to start.
+func (m SummaryModel) Init() tea.Cmd {
+	return nil
+}
+
+// Update quits on any key press.
+func (m SummaryModel) Update(msg tea.Msg) (SummaryModel, tea.Cmd) {
+	if msg, ok := msg.(tea.WindowSizeMsg); ok {
+		m.width = msg.Width
+		return m, nil
+	}
+	if _, ok := msg.(tea.KeyMsg); ok {
+		return m, tea.Quit
+	}
+	return m, nil
+}
+
+// View renders the session summary.
+func (m SummaryModel) View() string {
+	s := m.summary
+	var b strings.Builder
+
+	b.WriteString(theme.BrandText(m.width))
+	b.WriteString("\n\n")
+	b.WriteString("  " + theme.DotReconnecting + " " + theme.WarningStyle.Render("隧道已断开"))
+	b.WriteString("\n")
+
+	info := strings.Join([]string{
+		theme.LabelStyle.Render("服务器:") + "  " + theme.ValueStyle.Render(s.ServerName),
+		theme.LabelStyle.Render("远程地址:") + " " + theme.ValueStyle.Render(s.RemoteAddr),
+		theme.LabelStyle.Render("本地映射:") + " " + theme.ValueStyle.Render(s.LocalAddr),
+		theme.LabelStyle.Render("运行时长:") + " " + theme.ValueStyle.Render(formatDuration(s.Uptime)),
+		theme.LabelStyle.Render("重连次数:") + " " + theme.ValueStyle.Render(fmt.Sprintf("%d", s.Reconnects)),
+		theme.LabelStyle.Render("结束原因:") + " " + theme.ValueStyle.Render(s.Reason),
+	}, "\n")
+	b.WriteString(theme.BoxStyle.Render(theme.BoxTitleStyle.Render("会话摘要") + "\n" + info))
+	b.WriteString("\n")
+	b.WriteString("  " + theme.HelpStyle.Render("即将退出，按任意键立即退出"))
+
+	return theme.AppBoxStyle.Render(b.String())
+}
@@ -0,0 +1,72 @@
+package views
+
+import (
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// truncateWidth shortens s to at most width display cells, appending "..."
+// when it had to cut. It is rune-aware, so CJK text is measured and cut
+// on character boundaries.
+func truncateWidth(s string, width int) string {
+	if width <= 0 || lipgloss.Width(s) <= width {
+		return s
+	}
+	tail := "..."
+	if width <= len(tail) {
+		tail = ""
+	}
+	limit := width - len(tail)
+
+	var b strings.Builder
+	used := 0
+	for _, r := range s {
+		w := lipgloss.Width(string(r))
+		if used+w > limit {
+			break
+		}
+		b.WriteRune(r)
+		used += w
+	}
+	return b.String() + tail
+}
+
+// wrapMessage renders prefix followed by text wrapped to width display
+// cells, indenting continuation rows under the start of the text so long
+// errors stay readable inside a fixed-width box.
+func wrapMessage(prefix, text string, width int) string {
+	indent := lipgloss.Width(prefix)
+	rows := wrapWidth(text, width-indent)
+	pad := strings.Repeat(" ", indent)
+	for i := 1; i < len(rows); i++ {
+		rows[i] = pad + rows[i]
+	}
+	return prefix + strings.Join(rows, "\n")
+}
+
+// wrapWidth splits s into rows of at most width display cells. It breaks
+// on character boundaries (not words), which suits log lines and URLs.
+func wrapWidth(s string, width int) []string {
+	if width <= 0 || lipgloss.Width(s) <= width {
+		return []string{s}
+	}
+
+	var rows []string
+	var b strings.Builder
+	used := 0
+	for _, r := range s {
+		w := lipgloss.Width(string(r))
+		if used+w > width && used > 0 {
+			rows = append(rows, b.String())
+			b.Reset()
+			used = 0
+		}
+		b.WriteRune(r)
+		used += w
+	}
+	if b.Len() > 0 {
+		rows = append(rows, b.String())
+	}
+	return rows
+}
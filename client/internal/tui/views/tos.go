@@ -0,0 +1,74 @@
+package views
+
+import (
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/AerNos/firefrp-client/internal/tui/theme"
+)
+
+// TOSAcceptedMsg is emitted when the user accepts the server's terms of
+// service. Declining emits CancelConnectMsg.
+type TOSAcceptedMsg struct{}
+
+// TOSModel is the Bubble Tea model for the view asking the user to accept
+// a server's terms of service before the first connection. Acceptance
+// needs an explicit "y"; Enter alone does nothing.
+type TOSModel struct {
+	serverName string
+	url        string
+	version    string
+	width      int
+}
+
+// NewTOSModel creates a TOSModel for the terms at url, in the given
+// version, of the named server.
+func NewTOSModel(serverName, url, version string) TOSModel {
+	return TOSModel{serverName: serverName, url: url, version: version}
+}
+
+// Init implements tea.Model; the view has nothing to start.
+func (m TOSModel) Init() tea.Cmd {
+	return nil
+}
+
+// Update handles messages for the terms view.
+func (m TOSModel) Update(msg tea.Msg) (TOSModel, tea.Cmd) {
+	if msg, ok := msg.(tea.WindowSizeMsg); ok {
+		m.width = msg.Width
+		return m, nil
+	}
+	if msg, ok := msg.(tea.KeyMsg); ok {
+		switch msg.String() {
+		case "ctrl+c":
+			return m, tea.Quit
+		case "y", "Y":
+			return m, func() tea.Msg { return TOSAcceptedMsg{} }
+		case "esc", "n", "N":
+			return m, func() tea.Msg { return CancelConnectMsg{} }
+		}
+	}
+	return m, nil
+}
+
+// View renders the terms notice and the accept/decline help.
+func (m TOSModel) View() string {
+	var b strings.Builder
+
+	b.WriteString(theme.BrandText(m.width))
+	b.WriteString("\n\n")
+	b.WriteString("  " + theme.DotReconnecting + " " + theme.WarningStyle.Render("服务条款"))
+	b.WriteString("\n")
+
+	info := theme.ValueStyle.Render(wrapMessage("", m.serverName+" 要求在连接前同意其服务条款，请阅读后确认。", appBoxContentWidth(m.width)))
+	if m.url != "" {
+		info += "\n\n" + theme.LabelStyle.Render("条款地址:") + " " + theme.ValueStyle.Render(m.url)
+	}
+	info += "\n" + theme.LabelStyle.Render("版本:") + " " + theme.ValueStyle.Render(m.version)
+	b.WriteString(theme.BoxStyle.Render(info))
+	b.WriteString("\n")
+	b.WriteString(theme.HelpStyle.Render("[Y] 同意并连接  [Esc] 拒绝"))
+
+	return theme.AppBoxStyle.Render(b.String())
+}
@@ -1,6 +1,7 @@
 package views
 
 import (
+	"fmt"
 	"strings"
 
 	"github.com/charmbracelet/bubbles/spinner"
@@ -17,20 +18,30 @@ type UpdateErrorMsg struct {
 	Err error
 }
 
+// UpdateProgressMsg reports download progress for the update in flight.
+type UpdateProgressMsg struct {
+	Downloaded int64 // bytes downloaded so far
+	Total      int64 // expected size in bytes, or -1 if unknown
+	Attempt    int   // 1-based download attempt
+	Retrying   bool  // true when the previous attempt was interrupted
+}
+
 // UpdatingModel is the Bubble Tea model for the "updating" spinner view.
 type UpdatingModel struct {
-	spinner spinner.Model
-	version string
-	done    bool
-	errMsg  string
-	width   int
-	height  int
+	spinner  spinner.Model
+	version  string
+	reason   string // why the update happens, shown above the progress
+	done     bool
+	errMsg   string
+	progress UpdateProgressMsg
+	width    int
+	height   int
 }
 
 // NewUpdatingModel creates an UpdatingModel for the given target version.
 func NewUpdatingModel(version string) UpdatingModel {
 	s := spinner.New()
-	s.Spinner = spinner.Dot
+	s.Spinner = theme.SpinnerType
 	s.Style = theme.SpinnerStyle
 
 	return UpdatingModel{
@@ -39,6 +50,12 @@ func NewUpdatingModel(version string) UpdatingModel {
 	}
 }
 
+// SetReason sets the explanation shown above the progress, e.g. that the
+// selected server requires this version.
+func (m *UpdatingModel) SetReason(text string) {
+	m.reason = text
+}
+
 // Init starts the spinner animation.
 func (m UpdatingModel) Init() tea.Cmd {
 	return m.spinner.Tick
@@ -56,6 +73,10 @@ func (m UpdatingModel) Update(msg tea.Msg) (UpdatingModel, tea.Cmd) {
 		m.done = true
 		return m, nil
 
+	case UpdateProgressMsg:
+		m.progress = msg
+		return m, nil
+
 	case UpdateErrorMsg:
 		m.errMsg = msg.Err.Error()
 		return m, nil
@@ -73,18 +94,60 @@ func (m UpdatingModel) Update(msg tea.Msg) (UpdatingModel, tea.Cmd) {
 func (m UpdatingModel) View() string {
 	var b strings.Builder
 
-	b.WriteString(theme.BrandText())
+	b.WriteString(theme.BrandText(m.width))
 	b.WriteString("\n\n")
 
+	if m.reason != "" {
+		b.WriteString("  " + theme.WarningStyle.Render(m.reason))
+		b.WriteString("\n\n")
+	}
+
 	if m.errMsg != "" {
-		b.WriteString("  " + theme.ErrorStyle.Render("✗ 更新失败: "+m.errMsg))
+		b.WriteString("  " + theme.ErrorStyle.Render(theme.GlyphCross+" 更新失败: "+m.errMsg))
 	} else if m.done {
-		b.WriteString("  " + theme.SuccessStyle.Render("✓ 更新完成，正在重启..."))
+		b.WriteString("  " + theme.SuccessStyle.Render(theme.GlyphCheck+" 更新完成，正在重启..."))
 	} else {
 		b.WriteString("  " + m.spinner.View() + " 正在更新到 " + m.version + " ...")
+		if line := m.progressLine(); line != "" {
+			b.WriteString("\n  " + line)
+		}
 	}
 
 	b.WriteString("\n")
 	content := b.String()
 	return theme.AppBoxStyle.Render(content)
 }
+
+// progressLine renders the download size and retry state, or "" before any
+// progress has been reported.
+func (m UpdatingModel) progressLine() string {
+	p := m.progress
+	if p.Attempt == 0 {
+		return ""
+	}
+	size := formatBytes(p.Downloaded)
+	if p.Total > 0 {
+		size += " / " + formatBytes(p.Total)
+	}
+	line := theme.LogTimeStyle.Render(size)
+	if p.Attempt > 1 {
+		retry := fmt.Sprintf("第 %d 次重试", p.Attempt-1)
+		if p.Retrying {
+			retry = fmt.Sprintf("下载中断，%s...", retry)
+		}
+		line += "  " + theme.WarningStyle.Render(retry)
+	}
+	return line
+}
+
+// formatBytes formats a byte count as a short human-readable string.
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	if n < unit*unit {
+		return fmt.Sprintf("%.1f KB", float64(n)/unit)
+	}
+	return fmt.Sprintf("%.1f MB", float64(n)/(unit*unit))
+}
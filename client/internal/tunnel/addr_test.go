@@ -0,0 +1,62 @@
+package tunnel
+
+import "testing"
+
+func TestJoinHostPort(t *testing.T) {
+	tests := []struct {
+		host string
+		port int
+		want string
+	}{
+		{"127.0.0.1", 25565, "127.0.0.1:25565"},
+		{"frps.example.com", 7000, "frps.example.com:7000"},
+		{"::1", 25565, "[::1]:25565"},
+		{"2001:db8::1", 30001, "[2001:db8::1]:30001"},
+		{"fe80::1%eth0", 80, "[fe80::1%eth0]:80"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.want, func(t *testing.T) {
+			if got := JoinHostPort(tt.host, tt.port); got != tt.want {
+				t.Errorf("JoinHostPort(%q, %d) = %q, want %q", tt.host, tt.port, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTunnelConfigAddrs(t *testing.T) {
+	tests := []struct {
+		name       string
+		cfg        TunnelConfig
+		wantRemote string
+		wantLocal  string
+	}{
+		{
+			name:       "ipv4",
+			cfg:        TunnelConfig{ServerAddr: "203.0.113.7", RemotePort: 30001, LocalIP: "127.0.0.1", LocalPort: 25565},
+			wantRemote: "203.0.113.7:30001",
+			wantLocal:  "127.0.0.1:25565",
+		},
+		{
+			name:       "ipv6",
+			cfg:        TunnelConfig{ServerAddr: "2001:db8::7", RemotePort: 30001, LocalIP: "::1", LocalPort: 25565},
+			wantRemote: "[2001:db8::7]:30001",
+			wantLocal:  "[::1]:25565",
+		},
+		{
+			name:       "public address preferred",
+			cfg:        TunnelConfig{ServerAddr: "10.0.0.1", PublicAddr: "2001:db8::8", RemotePort: 30002, LocalIP: "127.0.0.1", LocalPort: 8080},
+			wantRemote: "[2001:db8::8]:30002",
+			wantLocal:  "127.0.0.1:8080",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.cfg.RemoteAddr(); got != tt.wantRemote {
+				t.Errorf("RemoteAddr() = %q, want %q", got, tt.wantRemote)
+			}
+			if got := tt.cfg.LocalAddr(); got != tt.wantLocal {
+				t.Errorf("LocalAddr() = %q, want %q", got, tt.wantLocal)
+			}
+		})
+	}
+}
@@ -0,0 +1,42 @@
+package tunnel
+
+import (
+	"encoding/json"
+	"net/url"
+
+	v1 "github.com/fatedier/frp/pkg/config/v1"
+)
+
+// auditMask replaces secrets in FrpConfigJSON output.
+const auditMask = "(redacted)"
+
+// FrpConfigJSON returns the frpc configuration StartTunnel derives from c
+// as indented JSON, so --audit can show it before connecting. The frps
+// token, OIDC client secret, access key and tunnel proxy password are
+// masked unless showSecrets is set. The proxy's local address is the
+// configured one; at run time it may point at the local traffic relay
+// instead, and failover may pick another frps endpoint.
+func (c TunnelConfig) FrpConfigJSON(showSecrets bool) string {
+	common := buildCommonConfig(c)
+	proxy := buildTCPProxyConfig(c)
+	if !showSecrets {
+		if common.Auth.Token != "" {
+			common.Auth.Token = auditMask
+		}
+		if common.Auth.OIDC.ClientSecret != "" {
+			common.Auth.OIDC.ClientSecret = auditMask
+		}
+		common.Metadatas[MetaAccessKey] = auditMask
+		if u, err := url.Parse(common.Transport.ProxyURL); err == nil {
+			common.Transport.ProxyURL = u.Redacted()
+		}
+	}
+	b, err := json.MarshalIndent(struct {
+		Common  *v1.ClientCommonConfig `json:"common"`
+		Proxies []*v1.TCPProxyConfig   `json:"proxies"`
+	}{common, []*v1.TCPProxyConfig{proxy}}, "", "  ")
+	if err != nil {
+		return err.Error()
+	}
+	return string(b)
+}
@@ -0,0 +1,70 @@
+package tunnel
+
+import (
+	"fmt"
+	"strings"
+
+	v1 "github.com/fatedier/frp/pkg/config/v1"
+)
+
+// Auth methods frps may require, as sent in auth_method by the server.
+const (
+	AuthMethodToken = "token"
+	AuthMethodOIDC  = "oidc"
+)
+
+// OIDCConfig holds the client-credentials settings frpc uses to obtain an
+// OIDC token when frps authenticates with OIDC instead of a shared token.
+type OIDCConfig struct {
+	ClientID         string
+	ClientSecret     string
+	Audience         string
+	Scope            string
+	TokenEndpointURL string
+}
+
+// validateAuth checks that the settings the configured auth method needs
+// are present. An empty method means token.
+func (c TunnelConfig) validateAuth() error {
+	switch c.AuthMethod {
+	case "", AuthMethodToken:
+		return nil
+	case AuthMethodOIDC:
+		if c.OIDC == nil {
+			return fmt.Errorf("oidc auth is missing its settings")
+		}
+		var missing []string
+		if c.OIDC.ClientID == "" {
+			missing = append(missing, "client_id")
+		}
+		if c.OIDC.ClientSecret == "" {
+			missing = append(missing, "client_secret")
+		}
+		if c.OIDC.TokenEndpointURL == "" {
+			missing = append(missing, "token_endpoint_url")
+		}
+		if len(missing) > 0 {
+			return fmt.Errorf("oidc auth is missing %s", strings.Join(missing, ", "))
+		}
+		return nil
+	default:
+		return fmt.Errorf("unsupported auth method %q", c.AuthMethod)
+	}
+}
+
+// applyAuth sets the frpc auth section for the configured method.
+func (c TunnelConfig) applyAuth(auth *v1.AuthClientConfig) {
+	if c.AuthMethod == AuthMethodOIDC && c.OIDC != nil {
+		auth.Method = v1.AuthMethodOIDC
+		auth.OIDC = v1.AuthOIDCClientConfig{
+			ClientID:         c.OIDC.ClientID,
+			ClientSecret:     c.OIDC.ClientSecret,
+			Audience:         c.OIDC.Audience,
+			Scope:            c.OIDC.Scope,
+			TokenEndpointURL: c.OIDC.TokenEndpointURL,
+		}
+		return
+	}
+	auth.Method = v1.AuthMethodToken
+	auth.Token = c.Token
+}
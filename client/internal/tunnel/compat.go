@@ -0,0 +1,39 @@
+package tunnel
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/fatedier/frp/pkg/util/version"
+)
+
+// FrpVersion returns the version of the embedded frp client.
+func FrpVersion() string {
+	return version.Full()
+}
+
+// CheckFrpVersion compares the frps version a server advertises with the
+// embedded frp client and returns a warning if they differ in major or
+// minor version, where login and transport defaults may have changed. An
+// empty or unparseable server version yields no warning.
+func CheckFrpVersion(serverVersion string) string {
+	server, ok := majorMinor(serverVersion)
+	if !ok {
+		return ""
+	}
+	client, ok := majorMinor(FrpVersion())
+	if !ok || server == client {
+		return ""
+	}
+	return fmt.Sprintf("服务器 frp 版本可能不兼容 (服务器 %s，客户端 %s)",
+		strings.TrimPrefix(serverVersion, "v"), FrpVersion())
+}
+
+// majorMinor extracts "major.minor" from a version such as "v0.67.0".
+func majorMinor(v string) (string, bool) {
+	parts := strings.SplitN(strings.TrimPrefix(strings.TrimSpace(v), "v"), ".", 3)
+	if len(parts) < 2 || parts[0] == "" || parts[1] == "" {
+		return "", false
+	}
+	return parts[0] + "." + parts[1], true
+}
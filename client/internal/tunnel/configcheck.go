@@ -0,0 +1,75 @@
+package tunnel
+
+import (
+	"fmt"
+	"strings"
+
+	v1 "github.com/fatedier/frp/pkg/config/v1"
+	"github.com/fatedier/frp/pkg/config/v1/validation"
+)
+
+// ConfigError is returned by StartTunnel when the frpc configuration built
+// from the server's response is invalid. Message says what to fix; the
+// underlying frp error is kept for logs.
+type ConfigError struct {
+	Message string
+	Err     error
+}
+
+func (e *ConfigError) Error() string {
+	return fmt.Sprintf("%s: %v", e.Message, e.Err)
+}
+
+// Unwrap returns the underlying frp error.
+func (e *ConfigError) Unwrap() error {
+	return e.Err
+}
+
+// checkFrpConfig fills in frp's defaults and runs frpc's own validation
+// over the common and proxy configs, so mistakes surface as a ConfigError
+// with an actionable message instead of an opaque service failure.
+func checkFrpConfig(common *v1.ClientCommonConfig, proxy v1.ProxyConfigurer) *ConfigError {
+	base := proxy.GetBaseConfig()
+	switch {
+	case common.ServerAddr == "":
+		return &ConfigError{Message: "服务器未返回 frps 地址", Err: fmt.Errorf("serverAddr is empty")}
+	case common.ServerPort < 1 || common.ServerPort > 65535:
+		return &ConfigError{Message: fmt.Sprintf("服务器返回的 frps 端口无效 (%d)", common.ServerPort), Err: fmt.Errorf("serverPort %d out of range", common.ServerPort)}
+	case base.LocalPort < 1 || base.LocalPort > 65535:
+		return &ConfigError{Message: fmt.Sprintf("本地端口无效 (%d)", base.LocalPort), Err: fmt.Errorf("localPort %d out of range", base.LocalPort)}
+	}
+
+	if err := common.Complete(); err != nil {
+		return &ConfigError{Message: "frpc 配置无效", Err: err}
+	}
+	proxy.Complete("")
+
+	if _, err := validation.NewConfigValidator(nil).ValidateClientCommonConfig(common); err != nil {
+		return &ConfigError{Message: describeConfigError(err), Err: err}
+	}
+	if err := validation.ValidateProxyConfigurerForClient(proxy); err != nil {
+		return &ConfigError{Message: describeConfigError(err), Err: err}
+	}
+	return nil
+}
+
+// describeConfigError maps common frp validation failures to a localized
+// message saying what is wrong.
+func describeConfigError(err error) string {
+	msg := err.Error()
+	switch {
+	case strings.Contains(msg, "name should not be empty"):
+		return "服务器未返回代理名称"
+	case strings.Contains(msg, "localPort"):
+		return "本地端口无效"
+	case strings.Contains(msg, "proxy protocol version"):
+		return "不支持的 PROXY 协议版本"
+	case strings.Contains(msg, "auth"):
+		return "服务器返回的 frps 认证参数无效"
+	case strings.Contains(msg, "transport.protocol"):
+		return "不支持的 frps 传输协议"
+	case strings.Contains(msg, "annotation"):
+		return "代理附加信息无效"
+	}
+	return "frpc 配置无效"
+}
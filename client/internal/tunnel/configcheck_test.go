@@ -0,0 +1,85 @@
+package tunnel
+
+import (
+	"errors"
+	"testing"
+)
+
+func validTunnelConfig() TunnelConfig {
+	return TunnelConfig{
+		ServerAddr: "frps.example.com",
+		ServerPort: 7000,
+		Token:      "secret",
+		AccessKey:  "ff-key",
+		ProxyName:  "ff-1-mc",
+		LocalIP:    "127.0.0.1",
+		LocalPort:  25565,
+		RemotePort: 30001,
+	}
+}
+
+func TestCheckFrpConfig(t *testing.T) {
+	tests := []struct {
+		name    string
+		modify  func(c *TunnelConfig)
+		wantMsg string // empty: valid
+	}{
+		{"valid", func(c *TunnelConfig) {}, ""},
+		{"no server address", func(c *TunnelConfig) { c.ServerAddr = "" }, "服务器未返回 frps 地址"},
+		{"server port zero", func(c *TunnelConfig) { c.ServerPort = 0 }, "服务器返回的 frps 端口无效 (0)"},
+		{"server port too large", func(c *TunnelConfig) { c.ServerPort = 70000 }, "服务器返回的 frps 端口无效 (70000)"},
+		{"local port zero", func(c *TunnelConfig) { c.LocalPort = 0 }, "本地端口无效 (0)"},
+		{"local port too large", func(c *TunnelConfig) { c.LocalPort = 65536 }, "本地端口无效 (65536)"},
+		{"no proxy name", func(c *TunnelConfig) { c.ProxyName = "" }, "服务器未返回代理名称"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := validTunnelConfig()
+			tt.modify(&cfg)
+			cerr := checkFrpConfig(buildCommonConfig(cfg), buildTCPProxyConfig(cfg))
+			switch {
+			case tt.wantMsg == "" && cerr != nil:
+				t.Fatalf("checkFrpConfig() = %v, want nil", cerr)
+			case tt.wantMsg != "" && cerr == nil:
+				t.Fatalf("checkFrpConfig() = nil, want %q", tt.wantMsg)
+			case cerr != nil && cerr.Message != tt.wantMsg:
+				t.Errorf("checkFrpConfig() message = %q, want %q", cerr.Message, tt.wantMsg)
+			}
+			if cerr != nil && cerr.Unwrap() == nil {
+				t.Errorf("checkFrpConfig() dropped the underlying error")
+			}
+		})
+	}
+}
+
+func TestCheckFrpConfigTransportProtocol(t *testing.T) {
+	cfg := validTunnelConfig()
+	common := buildCommonConfig(cfg)
+	common.Transport.Protocol = "pigeon"
+	cerr := checkFrpConfig(common, buildTCPProxyConfig(cfg))
+	if cerr == nil || cerr.Message != "不支持的 frps 传输协议" {
+		t.Fatalf("checkFrpConfig() = %v, want the transport protocol message", cerr)
+	}
+}
+
+func TestDescribeConfigError(t *testing.T) {
+	tests := []struct {
+		err  string
+		want string
+	}{
+		{"name should not be empty", "服务器未返回代理名称"},
+		{"localPort: invalid", "本地端口无效"},
+		{"proxy protocol version is invalid", "不支持的 PROXY 协议版本"},
+		{"invalid auth method", "服务器返回的 frps 认证参数无效"},
+		{"invalid transport.protocol, optional values are [tcp kcp]", "不支持的 frps 传输协议"},
+		{"annotation key is too long", "代理附加信息无效"},
+		{"something else entirely", "frpc 配置无效"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.err, func(t *testing.T) {
+			if got := describeConfigError(errors.New(tt.err)); got != tt.want {
+				t.Errorf("describeConfigError(%q) = %q, want %q", tt.err, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,44 @@
+package tunnel
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// WriteEndpointFile atomically writes the tunnel's public address and
+// expiry to path, for scripts that poll a known file (--write-endpoint-file).
+// The file holds two lines: "host:port" and the expiry in RFC 3339 (empty
+// if unknown). It is replaced via a rename, so readers never see a
+// partially written file.
+func WriteEndpointFile(path, addr string, expiresAt time.Time) error {
+	expires := ""
+	if !expiresAt.IsZero() {
+		expires = expiresAt.Format(time.RFC3339)
+	}
+	content := addr + "\n" + expires + "\n"
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return fmt.Errorf("failed to create endpoint file: %w", err)
+	}
+	defer os.Remove(tmp.Name()) // no-op once renamed
+	if _, err := tmp.WriteString(content); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write endpoint file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write endpoint file: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to replace endpoint file: %w", err)
+	}
+	return nil
+}
+
+// RemoveEndpointFile deletes the endpoint file on shutdown. A missing file
+// is not an error.
+func RemoveEndpointFile(path string) {
+	os.Remove(path)
+}
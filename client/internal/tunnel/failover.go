@@ -0,0 +1,67 @@
+package tunnel
+
+import (
+	"context"
+	"net"
+	"strconv"
+	"time"
+)
+
+// endpointProbeTimeout bounds the quick dial used to check whether an frps
+// endpoint accepts connections when the server offers several.
+const endpointProbeTimeout = 3 * time.Second
+
+// failoverAttempts is how many reconnects to an endpoint may fail before a
+// tunnel with fallback endpoints moves on to the next one.
+const failoverAttempts = 3
+
+// Endpoint returns the frps address the config points at, as "host:port".
+func (c TunnelConfig) Endpoint() string {
+	return JoinHostPort(c.ServerAddr, c.ServerPort)
+}
+
+// UseEndpoint points the config at the given "host:port" frps endpoint,
+// e.g. the one reported in StatusUpdate.Endpoint. Malformed values are
+// ignored.
+func (c *TunnelConfig) UseEndpoint(endpoint string) {
+	host, portStr, err := net.SplitHostPort(endpoint)
+	if err != nil {
+		return
+	}
+	port, err := strconv.Atoi(portStr)
+	if err != nil {
+		return
+	}
+	c.ServerAddr = host
+	c.ServerPort = port
+}
+
+// selectEndpoint dials the candidates in order and returns the first one
+// that accepts a TCP connection. If none does, the first is returned and
+// frpc's own retry loop takes over.
+func selectEndpoint(ctx context.Context, candidates []string) string {
+	dialer := net.Dialer{Timeout: endpointProbeTimeout}
+	for _, endpoint := range candidates {
+		conn, err := dialer.DialContext(ctx, "tcp", endpoint)
+		if err == nil {
+			conn.Close()
+			return endpoint
+		}
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	return candidates[0]
+}
+
+// nextEndpoints rotates candidates so that the one after current comes
+// first and current comes last, for picking again after current failed.
+func nextEndpoints(candidates []string, current string) []string {
+	for i, endpoint := range candidates {
+		if endpoint == current {
+			next := append([]string{}, candidates[i+1:]...)
+			return append(next, candidates[:i+1]...)
+		}
+	}
+	return candidates
+}
@@ -4,15 +4,15 @@
 package tunnel
 
 import (
-	"bytes"
 	"context"
 	"fmt"
 	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/fatedier/frp/client"
 	v1 "github.com/fatedier/frp/pkg/config/v1"
-	frplog "github.com/fatedier/frp/pkg/util/log"
-	goliblog "github.com/fatedier/golib/log"
 	"github.com/samber/lo"
 )
 
@@ -20,12 +20,12 @@ import (
 type Status int
 
 const (
-	StatusConnecting    Status = iota // Attempting to connect to frps
-	StatusConnected                   // Successfully connected and tunnel is active
-	StatusReconnecting                // Connection lost, attempting to reconnect
-	StatusRejected                    // Server rejected the connection (e.g. invalid key)
-	StatusError                       // An error occurred
-	StatusClosed                      // Tunnel has been closed
+	StatusConnecting   Status = iota // Attempting to connect to frps
+	StatusConnected                  // Successfully connected and tunnel is active
+	StatusReconnecting               // Connection lost, attempting to reconnect
+	StatusRejected                   // Server rejected the connection (e.g. invalid key)
+	StatusError                      // An error occurred
+	StatusClosed                     // Tunnel has been closed
 )
 
 // String returns a human-readable description of the status.
@@ -53,48 +53,74 @@ type StatusUpdate struct {
 	Status  Status
 	Message string
 	Error   error
+	// Final is set on the last update StartTunnel sends before returning.
+	// Consumers can treat the channel closing after a final update as a
+	// normal end of the tunnel rather than a failure.
+	Final bool
+	// Attempt is the 1-based reconnect attempt for StatusReconnecting.
+	Attempt int
+	// Endpoint, for StatusConnected, is the frps "host:port" the tunnel
+	// is connected through.
+	Endpoint string
 }
 
+// finalStatusTimeout bounds how long StartTunnel waits for room in the
+// status channel to deliver its final update.
+const finalStatusTimeout = time.Second
+
 // LogEntry represents a parsed frpc log line.
 type LogEntry struct {
 	Time    string // HH:MM:SS
 	Level   string // I, W, E, D, T
 	Message string // Log message text (source file reference stripped)
+	Source  string // frp source reference, e.g. "client/service.go:295"; empty if none
+}
+
+// Text returns the message for display, prefixed with its source
+// reference when withSource is set and the entry has one.
+func (e LogEntry) Text(withSource bool) string {
+	if withSource && e.Source != "" {
+		return "[" + e.Source + "] " + e.Message
+	}
+	return e.Message
 }
 
-// logWriter implements io.Writer to capture frpc log output.
-// It buffers incoming bytes, splits on newlines, parses each line,
-// sends LogEntry values to logCh, and detects connection status
-// changes from log content (since frpc has no event callback API).
+// logWriter captures the frpc log output of one tunnel (as routed by
+// logMux). It parses each line, sends LogEntry values to logCh, and
+// detects connection status changes from log content (since frpc has no
+// event callback API).
 type logWriter struct {
 	ch        chan<- LogEntry
 	statusCh  chan<- StatusUpdate
-	buf       bytes.Buffer
 	connected bool // whether we've ever successfully connected
+	attempts  int  // reconnect attempts since the connection was lost
+	endpoint  string
+	// failover, if set, is called once the reconnect attempts reach
+	// failoverAttempts, to move the tunnel to another endpoint.
+	failover func()
+
+	mu         sync.Mutex  // guards the fields above against loginTimer
+	loginTimer *time.Timer // armed between login and "start proxy success"
 }
 
-func (w *logWriter) Write(p []byte) (n int, err error) {
-	n = len(p)
-	w.buf.Write(p)
-	for {
-		line, err := w.buf.ReadString('\n')
-		if err != nil {
-			// Incomplete line — put it back for next Write call.
-			w.buf.WriteString(line)
-			break
-		}
-		line = strings.TrimRight(line, "\r\n")
-		if entry, ok := parseLogLine(line); ok {
-			suppressed := w.detectStatus(entry.Message)
-			if !suppressed {
-				select {
-				case w.ch <- entry:
-				default:
-				}
+// loginFallbackDelay is how long after "login to server success" the
+// tunnel is reported connected anyway if frpc never logs "start proxy
+// success" (an edge case seen with some frps versions).
+const loginFallbackDelay = 10 * time.Second
+
+// handleLine processes a single raw frpc log line.
+func (w *logWriter) handleLine(line string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if entry, ok := parseLogLine(line); ok {
+		suppressed := w.detectStatus(entry.Message)
+		if !suppressed {
+			select {
+			case w.ch <- entry:
+			default:
 			}
 		}
 	}
-	return n, nil
 }
 
 // detectStatus inspects a parsed log message for frpc connection events
@@ -103,35 +129,66 @@ func (w *logWriter) Write(p []byte) (n int, err error) {
 // display (to avoid redundant lines already represented by the status
 // indicator). The frpc library logs:
 //
+//   - "try to connect to server..." → dialing + login (or reconnection)
+//   - "login to server success"     → login ok, proxies being registered;
+//     reported as connected after loginFallbackDelay if nothing follows
+//   - "proxy added"                 → proxy registration sent to frps
 //   - "start proxy success"         → proxy is active (fully connected)
-//   - "login to server success"     → login ok (connection established)
-//   - "try to connect to server..." → reconnection attempt
 //   - "connect to server error: ..."→ connection failed
+//
+// Before the first successful connection, each step is reported as a
+// StatusConnecting update so the UI can show granular progress.
 func (w *logWriter) detectStatus(msg string) bool {
 	switch {
 	case strings.Contains(msg, "start proxy success"):
-		w.connected = true
-		sendStatus(w.statusCh, StatusUpdate{
-			Status:  StatusConnected,
-			Message: "隧道已建立",
-		})
+		w.stopLoginTimer()
+		w.markConnected("隧道已建立")
 		return true
 	case strings.Contains(msg, "login to server success"):
-		// Login succeeded but proxy may not be ready yet; mark connected
-		// in case "start proxy success" is not logged (edge case).
 		if !w.connected {
-			w.connected = true
 			sendStatus(w.statusCh, StatusUpdate{
-				Status:  StatusConnected,
-				Message: "已登录服务器",
+				Status:  StatusConnecting,
+				Message: "登录成功，注册代理中...",
 			})
 		}
+		w.stopLoginTimer()
+		var t *time.Timer
+		t = time.AfterFunc(loginFallbackDelay, func() {
+			w.mu.Lock()
+			defer w.mu.Unlock()
+			if w.loginTimer == t {
+				w.loginTimer = nil
+				w.markConnected("已登录服务器")
+			}
+		})
+		w.loginTimer = t
 		return true
+	case strings.Contains(msg, "proxy added"):
+		if !w.connected {
+			sendStatus(w.statusCh, StatusUpdate{
+				Status:  StatusConnecting,
+				Message: "等待服务器开放端口...",
+			})
+		}
+		return false
 	case strings.Contains(msg, "try to connect to server"):
+		w.stopLoginTimer()
 		if w.connected {
+			w.attempts++
+			if w.failover != nil && w.attempts > failoverAttempts {
+				w.failover()
+				w.failover = nil
+				return false
+			}
 			sendStatus(w.statusCh, StatusUpdate{
 				Status:  StatusReconnecting,
 				Message: "正在重连服务器...",
+				Attempt: w.attempts,
+			})
+		} else {
+			sendStatus(w.statusCh, StatusUpdate{
+				Status:  StatusConnecting,
+				Message: "连接并登录服务器中...",
 			})
 		}
 		return false
@@ -140,6 +197,22 @@ func (w *logWriter) detectStatus(msg string) bool {
 			sendStatus(w.statusCh, StatusUpdate{
 				Status:  StatusReconnecting,
 				Message: "连接服务器失败，正在重试...",
+				Attempt: w.attempts,
+			})
+		} else {
+			sendStatus(w.statusCh, StatusUpdate{
+				Status:  StatusConnecting,
+				Message: "连接服务器失败，正在重试...",
+			})
+		}
+		return false
+	case strings.Contains(msg, "already exists"):
+		// frps still holds a proxy of this name, e.g. from a session that
+		// hasn't been released yet; frpc keeps retrying.
+		if !w.connected {
+			sendStatus(w.statusCh, StatusUpdate{
+				Status:  StatusConnecting,
+				Message: "代理名称冲突，服务器上仍有同名代理，等待释放...",
 			})
 		}
 		return false
@@ -159,12 +232,41 @@ func (w *logWriter) detectStatus(msg string) bool {
 	return false
 }
 
+// markConnected records that the tunnel is up and reports it.
+func (w *logWriter) markConnected(msg string) {
+	w.connected = true
+	w.attempts = 0
+	sendStatus(w.statusCh, StatusUpdate{
+		Status:   StatusConnected,
+		Message:  msg,
+		Endpoint: w.endpoint,
+	})
+}
+
+// stopLoginTimer disarms the login fallback, if armed.
+func (w *logWriter) stopLoginTimer() {
+	if w.loginTimer != nil {
+		w.loginTimer.Stop()
+		w.loginTimer = nil
+	}
+}
+
+// close disarms the login fallback once the service has stopped and
+// reports whether the tunnel had connected.
+func (w *logWriter) close() (connected bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.stopLoginTimer()
+	return w.connected
+}
+
 // parseLogLine parses a frpc log line of the form:
 //
 //	"YYYY-MM-DD HH:MM:SS.mmm [L] [source/file.go:line] message"
 //
 // It extracts the time (HH:MM:SS), level letter, and message (with
-// the date, milliseconds, and source reference stripped).
+// the date, milliseconds, and source reference stripped). The source
+// reference is kept separately in Source.
 func parseLogLine(line string) (LogEntry, bool) {
 	// Minimal length: "YYYY-MM-DD HH:MM:SS.mmm [X] msg" = 32 chars
 	if len(line) < 32 {
@@ -185,9 +287,11 @@ func parseLogLine(line string) (LogEntry, bool) {
 	// Skip past "[L] "
 	msg := rest[4:]
 
-	// Strip the optional "[source/file.go:line] " prefix from the message.
+	// Split off the optional "[source/file.go:line] " prefix.
+	var source string
 	if len(msg) > 0 && msg[0] == '[' {
 		if idx := strings.Index(msg, "] "); idx != -1 {
+			source = msg[1:idx]
 			msg = msg[idx+2:]
 		}
 	}
@@ -196,6 +300,7 @@ func parseLogLine(line string) (LogEntry, bool) {
 		Time:    timePart,
 		Level:   level,
 		Message: msg,
+		Source:  source,
 	}, true
 }
 
@@ -205,8 +310,20 @@ type TunnelConfig struct {
 	ServerAddr string
 	// ServerPort is the frps server bind port.
 	ServerPort int
+	// Fallbacks lists further frps "host:port" endpoints of the same server,
+	// in preference order. When set, StartTunnel connects to the first of
+	// ServerAddr:ServerPort and the fallbacks that accepts connections.
+	Fallbacks []string
+	// PublicAddr is the public hostname users should share to reach the
+	// tunnel. It may differ from ServerAddr (e.g. frps behind a load balancer).
+	PublicAddr string
 	// Token is the frps authentication token.
 	Token string
+	// AuthMethod is how frpc authenticates with frps: AuthMethodToken
+	// (the default when empty) or AuthMethodOIDC, which uses OIDC.
+	AuthMethod string
+	// OIDC holds the OIDC settings; required when AuthMethod is oidc.
+	OIDC *OIDCConfig
 	// AccessKey is placed into frpc metadata for server-side plugin validation.
 	AccessKey string
 	// ProxyName is the unique name assigned to this proxy by the management server.
@@ -217,6 +334,44 @@ type TunnelConfig struct {
 	LocalPort int
 	// RemotePort is the public port allocated on the frps server.
 	RemotePort int
+	// Metadata holds extra hints for the frps server plugin (see
+	// ClientMetadata). AccessKey always overrides any access_key entry.
+	Metadata map[string]string
+	// IdleTimeout, when positive, shuts the tunnel down once no traffic has
+	// flowed through it for this long (see ErrIdleTimeout).
+	IdleTimeout time.Duration
+	// Traffic, when set, receives the byte count of forwarded traffic.
+	Traffic *TrafficCounter
+	// Sources, when set, records the remote IPs of visitors. frps passes
+	// them to frpc, which forwards them with the PROXY protocol.
+	Sources *ConnSources
+	// ProxyURL, when set, is an http, socks5 or ntlm proxy frpc dials
+	// frps through. The frps address is then left to the proxy to
+	// resolve, and fallback endpoints aren't probed.
+	ProxyURL string
+	// Target, when set, is the local address traffic is forwarded to in
+	// place of LocalIP and LocalPort, and can be changed while the tunnel
+	// runs (see LocalTarget).
+	Target *LocalTarget
+}
+
+// PublicHost returns the host users should share to reach the tunnel,
+// falling back to ServerAddr when no public address was provided.
+func (c TunnelConfig) PublicHost() string {
+	if c.PublicAddr != "" {
+		return c.PublicAddr
+	}
+	return c.ServerAddr
+}
+
+// RemoteAddr returns the public "host:port" users connect to.
+func (c TunnelConfig) RemoteAddr() string {
+	return JoinHostPort(c.PublicHost(), c.RemotePort)
+}
+
+// LocalAddr returns the local "host:port" traffic is forwarded to.
+func (c TunnelConfig) LocalAddr() string {
+	return JoinHostPort(c.LocalIP, c.LocalPort)
 }
 
 // StartTunnel creates and runs an embedded frp client service.
@@ -230,11 +385,36 @@ type TunnelConfig struct {
 // retrying the connection if the initial login fails (e.g. due to transient
 // network issues). The frps server-side plugin uses the access_key metadata
 // to validate the client on Login.
+//
+// When the config has Fallbacks, the endpoints are dialed in order first
+// and the tunnel uses the first one reachable; the choice is reported in
+// the StatusConnected update's Endpoint. If reconnecting to it later fails
+// failoverAttempts times in a row, the selection runs again starting from
+// the next endpoint.
 func StartTunnel(ctx context.Context, cfg TunnelConfig, statusCh chan<- StatusUpdate, logCh chan<- LogEntry) error {
+	if err := cfg.validateAuth(); err != nil {
+		sendFinalStatus(statusCh, StatusUpdate{
+			Status:  StatusError,
+			Message: "服务器返回的 frps 认证配置无效",
+			Error:   fmt.Errorf("invalid frps auth settings: %w", err),
+		})
+		return fmt.Errorf("invalid frps auth settings: %w", err)
+	}
+
+	var candidates []string
+	if len(cfg.Fallbacks) > 0 && cfg.ProxyURL == "" {
+		sendStatus(statusCh, StatusUpdate{
+			Status:  StatusConnecting,
+			Message: "正在选择可用的 frps 节点...",
+		})
+		candidates = append([]string{cfg.Endpoint()}, cfg.Fallbacks...)
+		cfg.UseEndpoint(selectEndpoint(ctx, candidates))
+	}
+
 	// Send initial connecting status.
 	sendStatus(statusCh, StatusUpdate{
 		Status:  StatusConnecting,
-		Message: fmt.Sprintf("Connecting to %s:%d...", cfg.ServerAddr, cfg.ServerPort),
+		Message: fmt.Sprintf("正在连接 %s...", cfg.Endpoint()),
 	})
 
 	// Build the frp client common configuration.
@@ -243,51 +423,158 @@ func StartTunnel(ctx context.Context, cfg TunnelConfig, statusCh chan<- StatusUp
 	// Build the TCP proxy configuration.
 	proxyCfg := buildTCPProxyConfig(cfg)
 
-	// Redirect the frpc global logger to our logWriter so that log output
-	// is captured as structured entries instead of going to os.Stdout,
-	// which would corrupt the Bubble Tea alt screen. The logWriter also
-	// detects connection status changes from log content and sends
-	// StatusUpdate messages, since frpc has no event callback API.
-	frplog.Logger = frplog.Logger.WithOptions(goliblog.WithOutput(&logWriter{
-		ch:       logCh,
-		statusCh: statusCh,
-	}))
-
-	// Create the frp client service.
-	svc, err := client.NewService(client.ServiceOptions{
-		Common:    commonCfg,
-		ProxyCfgs: []v1.ProxyConfigurer{proxyCfg},
-	})
-	if err != nil {
-		sendStatus(statusCh, StatusUpdate{
+	// Catch configuration mistakes with a message the user can act on.
+	if cerr := checkFrpConfig(commonCfg, proxyCfg); cerr != nil {
+		sendFinalStatus(statusCh, StatusUpdate{
 			Status:  StatusError,
-			Message: "Failed to create frp service",
-			Error:   fmt.Errorf("failed to create frp service: %w", err),
+			Message: cerr.Message,
+			Error:   cerr.Err,
 		})
-		return fmt.Errorf("failed to create frp service: %w", err)
+		return cerr
 	}
 
-	// Notify that the service has been created and is now attempting to connect.
-	sendStatus(statusCh, StatusUpdate{
-		Status:  StatusConnecting,
-		Message: fmt.Sprintf("Tunnel service started, proxy=%s, remote port=%d", cfg.ProxyName, cfg.RemotePort),
-	})
+	// Fail fast on a name that doesn't resolve instead of retrying forever.
+	// Through a proxy the name is resolved on the far side, if at all.
+	if cfg.ProxyURL == "" {
+		if err := resolveServerAddr(ctx, cfg.ServerAddr); err != nil {
+			sendFinalStatus(statusCh, StatusUpdate{
+				Status:  StatusError,
+				Message: fmt.Sprintf("无法解析服务器地址 %s，请检查网络或 DNS 设置", cfg.ServerAddr),
+				Error:   err,
+			})
+			return err
+		}
+	}
 
-	// Run the service. This blocks until ctx is cancelled or an error occurs.
-	// With LoginFailExit=false, the service will retry connections internally.
-	err = svc.Run(ctx)
+	// When traffic is counted, sources are recorded, an idle timeout is
+	// set or the local target may change, route traffic through a counting
+	// relay; stop the service once it has been quiet for too long.
+	runCtx, cancelRun := context.WithCancel(ctx)
+	defer cancelRun()
+	var idle atomic.Bool
+	if cfg.Traffic != nil || cfg.IdleTimeout > 0 || cfg.Sources != nil || cfg.Target != nil {
+		counter := cfg.Traffic
+		if counter == nil {
+			counter = &TrafficCounter{}
+		}
+		target := cfg.Target
+		if target == nil {
+			target = &LocalTarget{}
+		}
+		if target.Addr() == "" {
+			target.Set(cfg.LocalIP, cfg.LocalPort)
+		}
+		relay, err := newTrafficRelay(target, counter, cfg.Sources)
+		if err != nil {
+			sendFinalStatus(statusCh, StatusUpdate{
+				Status:  StatusError,
+				Message: "Failed to start traffic relay",
+				Error:   fmt.Errorf("failed to start traffic relay: %w", err),
+			})
+			return fmt.Errorf("failed to start traffic relay: %w", err)
+		}
+		proxyCfg.LocalIP, proxyCfg.LocalPort = relay.addr()
+		go relay.serve(runCtx)
+		if cfg.IdleTimeout > 0 {
+			go watchIdle(runCtx, relay, cfg.IdleTimeout, func() {
+				idle.Store(true)
+				cancelRun()
+			})
+		}
+	}
+
+	// Run the frp client service. When the connection to one of several
+	// endpoints keeps failing, the service is stopped and started again on
+	// the next reachable endpoint.
+	var (
+		err       error
+		connected bool
+	)
+	for {
+		var failover atomic.Bool
+		w := &logWriter{
+			ch:        logCh,
+			statusCh:  statusCh,
+			endpoint:  cfg.Endpoint(),
+			connected: connected,
+		}
+		svcCtx, cancelSvc := context.WithCancel(runCtx)
+		if len(candidates) > 1 {
+			w.failover = func() {
+				failover.Store(true)
+				cancelSvc()
+			}
+		}
+
+		// Capture this tunnel's frpc log output. The logWriter also detects
+		// connection status changes from log content and sends StatusUpdate
+		// messages, since frpc has no event callback API. Several tunnels
+		// may run at once, so output is routed by a per-tunnel log tag.
+		svcCtx, unregister := registerLogWriter(svcCtx, tunnelTag(), w)
+
+		// Create the frp client service.
+		var svc *client.Service
+		svc, err = client.NewService(client.ServiceOptions{
+			Common:    commonCfg,
+			ProxyCfgs: []v1.ProxyConfigurer{proxyCfg},
+		})
+		if err != nil {
+			unregister()
+			cancelSvc()
+			sendFinalStatus(statusCh, StatusUpdate{
+				Status:  StatusError,
+				Message: "frpc 服务创建失败: " + err.Error(),
+				Error:   fmt.Errorf("failed to create frp service: %w", err),
+			})
+			return fmt.Errorf("failed to create frp service: %w", err)
+		}
+
+		// Notify that the service has been created and is now attempting to connect.
+		sendStatus(statusCh, StatusUpdate{
+			Status:  StatusConnecting,
+			Message: fmt.Sprintf("隧道服务已启动 (代理 %s, 远程端口 %d)", cfg.ProxyName, cfg.RemotePort),
+		})
+
+		// Run the service. This blocks until ctx is cancelled or an error
+		// occurs. With LoginFailExit=false, the service will retry
+		// connections internally.
+		err = svc.Run(svcCtx)
+		unregister()
+		cancelSvc()
+		connected = w.close()
+		if !failover.Load() || runCtx.Err() != nil {
+			break
+		}
+		candidates = nextEndpoints(candidates, cfg.Endpoint())
+		cfg.UseEndpoint(selectEndpoint(runCtx, candidates))
+		commonCfg.ServerAddr, commonCfg.ServerPort = cfg.ServerAddr, cfg.ServerPort
+		msg := fmt.Sprintf("重连失败，切换到 frps 节点 %s...", cfg.Endpoint())
+		select {
+		case logCh <- LogEntry{Time: time.Now().Format("15:04:05"), Level: "W", Message: msg}:
+		default:
+		}
+		sendStatus(statusCh, StatusUpdate{Status: StatusReconnecting, Message: msg})
+	}
 
 	// When we reach here, the service has stopped.
+	if idle.Load() {
+		sendFinalStatus(statusCh, StatusUpdate{
+			Status:  StatusClosed,
+			Message: "空闲超时，已断开",
+			Error:   ErrIdleTimeout,
+		})
+		return nil
+	}
 	if err != nil {
 		// Check if the context was cancelled (graceful shutdown).
 		if ctx.Err() != nil {
-			sendStatus(statusCh, StatusUpdate{
+			sendFinalStatus(statusCh, StatusUpdate{
 				Status:  StatusClosed,
 				Message: "Tunnel closed",
 			})
 			return nil
 		}
-		sendStatus(statusCh, StatusUpdate{
+		sendFinalStatus(statusCh, StatusUpdate{
 			Status:  StatusError,
 			Message: "Tunnel service exited with error",
 			Error:   err,
@@ -295,7 +582,7 @@ func StartTunnel(ctx context.Context, cfg TunnelConfig, statusCh chan<- StatusUp
 		return fmt.Errorf("frp service error: %w", err)
 	}
 
-	sendStatus(statusCh, StatusUpdate{
+	sendFinalStatus(statusCh, StatusUpdate{
 		Status:  StatusClosed,
 		Message: "Tunnel closed",
 	})
@@ -307,16 +594,19 @@ func buildCommonConfig(cfg TunnelConfig) *v1.ClientCommonConfig {
 	commonCfg := &v1.ClientCommonConfig{}
 	commonCfg.ServerAddr = cfg.ServerAddr
 	commonCfg.ServerPort = cfg.ServerPort
+	commonCfg.Transport.ProxyURL = cfg.ProxyURL
 
-	// Authentication using token method.
-	commonCfg.Auth.Method = v1.AuthMethodToken
-	commonCfg.Auth.Token = cfg.Token
+	// Authenticate with the token, or with OIDC if the server asks for it.
+	cfg.applyAuth(&commonCfg.Auth)
 
 	// Embed the access key in metadata so the frps server-side plugin
-	// can validate the client on Login.
-	commonCfg.Metadatas = map[string]string{
-		"access_key": cfg.AccessKey,
+	// can validate the client on Login. Client hints ride along; the
+	// access key is set last so it can't be overridden.
+	commonCfg.Metadatas = make(map[string]string, len(cfg.Metadata)+1)
+	for k, v := range cfg.Metadata {
+		commonCfg.Metadatas[k] = v
 	}
+	commonCfg.Metadatas[MetaAccessKey] = cfg.AccessKey
 
 	// Disable LoginFailExit so the client keeps retrying on connection failure.
 	// This allows automatic reconnection when the server is temporarily unavailable.
@@ -337,6 +627,10 @@ func buildTCPProxyConfig(cfg TunnelConfig) *v1.TCPProxyConfig {
 	proxyCfg.LocalIP = cfg.LocalIP
 	proxyCfg.LocalPort = cfg.LocalPort
 	proxyCfg.RemotePort = cfg.RemotePort
+	if cfg.Sources != nil {
+		// The traffic relay reads and strips this header.
+		proxyCfg.Transport.ProxyProtocolVersion = "v1"
+	}
 	return proxyCfg
 }
 
@@ -349,3 +643,14 @@ func sendStatus(ch chan<- StatusUpdate, update StatusUpdate) {
 		// Channel full, drop the update to avoid blocking the tunnel goroutine.
 	}
 }
+
+// sendFinalStatus delivers the terminal status update with Final set. Unlike
+// sendStatus it waits briefly for room in the channel, so the consumer can
+// rely on seeing it before the channel is closed.
+func sendFinalStatus(ch chan<- StatusUpdate, update StatusUpdate) {
+	update.Final = true
+	select {
+	case ch <- update:
+	case <-time.After(finalStatusTimeout):
+	}
+}
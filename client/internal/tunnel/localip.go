@@ -0,0 +1,80 @@
+package tunnel
+
+import (
+	"net"
+	"time"
+)
+
+// DefaultLocalIP is the local address tunnels forward to unless --local-ip
+// says otherwise.
+const DefaultLocalIP = "127.0.0.1"
+
+// localProbeTimeout bounds each dial ResolveLocalIP makes.
+const localProbeTimeout = time.Second
+
+// ResolveLocalIP checks whether something accepts connections on ip:port
+// and, if not and ip is DefaultLocalIP, tries the machine's LAN addresses
+// instead (primary first). This helps on Windows/WSL setups where the
+// service listens on a virtual interface that loopback doesn't reach. It
+// returns the address to use and whether it differs from ip; a custom ip,
+// or no reachable alternative, is returned unchanged.
+func ResolveLocalIP(ip string, port int) (string, bool) {
+	if ip != DefaultLocalIP || localReachable(ip, port) {
+		return ip, false
+	}
+	for _, candidate := range lanIPs() {
+		if localReachable(candidate, port) {
+			return candidate, true
+		}
+	}
+	return ip, false
+}
+
+// localReachable reports whether a TCP connection to ip:port succeeds.
+func localReachable(ip string, port int) bool {
+	conn, err := net.DialTimeout("tcp", JoinHostPort(ip, port), localProbeTimeout)
+	if err != nil {
+		return false
+	}
+	conn.Close()
+	return true
+}
+
+// lanIPs lists the machine's non-loopback IPv4 addresses, starting with
+// the one outbound traffic uses.
+func lanIPs() []string {
+	var ips []string
+	seen := make(map[string]bool)
+	add := func(ip net.IP) {
+		if ip4 := ip.To4(); ip4 != nil && !ip4.IsLoopback() && !ip4.IsLinkLocalUnicast() && !seen[ip4.String()] {
+			seen[ip4.String()] = true
+			ips = append(ips, ip4.String())
+		}
+	}
+
+	if ip := defaultRouteIP(); ip != nil {
+		add(ip)
+	}
+	addrs, err := net.InterfaceAddrs()
+	if err != nil {
+		return ips
+	}
+	for _, a := range addrs {
+		if ipNet, ok := a.(*net.IPNet); ok {
+			add(ipNet.IP)
+		}
+	}
+	return ips
+}
+
+// defaultRouteIP returns the source address of the default route, or nil
+// if there is none. Connecting a UDP socket sends nothing but makes the OS
+// pick the address.
+func defaultRouteIP() net.IP {
+	conn, err := net.Dial("udp", "192.0.2.1:9")
+	if err != nil {
+		return nil
+	}
+	defer conn.Close()
+	return conn.LocalAddr().(*net.UDPAddr).IP
+}
@@ -0,0 +1,93 @@
+package tunnel
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	frplog "github.com/fatedier/frp/pkg/util/log"
+	"github.com/fatedier/frp/pkg/util/xlog"
+	goliblog "github.com/fatedier/golib/log"
+)
+
+// frpc has a single global logger, so concurrent tunnels would otherwise
+// see each other's log lines (and status events). Each tunnel's context
+// carries an xlog prefix naming it; logMux receives all frpc output, finds
+// the tag in each line, strips it, and hands the line to that tunnel's
+// logWriter. Untagged lines (process-wide frpc messages) go to every tunnel.
+type logMux struct {
+	mu      sync.Mutex
+	buf     bytes.Buffer
+	writers map[string]*logWriter
+}
+
+var (
+	mux        = &logMux{writers: make(map[string]*logWriter)}
+	muxInstall sync.Once
+	tunnelSeq  atomic.Int64
+)
+
+// tunnelTag returns a unique log prefix value for a new tunnel.
+func tunnelTag() string {
+	return fmt.Sprintf("ff-tunnel-%d", tunnelSeq.Add(1))
+}
+
+// registerLogWriter routes frpc log lines tagged with tag to w and returns
+// a context that makes frpc tag its logs accordingly. The returned function
+// unregisters the writer.
+func registerLogWriter(ctx context.Context, tag string, w *logWriter) (context.Context, func()) {
+	// Redirect the frpc global logger to the mux so that log output is
+	// captured as structured entries instead of going to os.Stdout, which
+	// would corrupt the Bubble Tea alt screen.
+	muxInstall.Do(func() {
+		frplog.Logger = frplog.Logger.WithOptions(goliblog.WithOutput(mux))
+	})
+
+	mux.mu.Lock()
+	mux.writers[tag] = w
+	mux.mu.Unlock()
+
+	xl := xlog.New().AddPrefix(xlog.LogPrefix{Name: "tunnel", Value: tag, Priority: 1})
+	return xlog.NewContext(ctx, xl), func() {
+		mux.mu.Lock()
+		delete(mux.writers, tag)
+		mux.mu.Unlock()
+	}
+}
+
+// Write buffers incoming bytes, splits on newlines and dispatches each
+// complete line.
+func (m *logMux) Write(p []byte) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.buf.Write(p)
+	for {
+		line, err := m.buf.ReadString('\n')
+		if err != nil {
+			// Incomplete line — put it back for next Write call.
+			m.buf.WriteString(line)
+			break
+		}
+		m.dispatch(strings.TrimRight(line, "\r\n"))
+	}
+	return len(p), nil
+}
+
+// dispatch delivers one line to the tunnel it is tagged for, or to all
+// tunnels if it carries no known tag. Callers hold m.mu.
+func (m *logMux) dispatch(line string) {
+	for tag, w := range m.writers {
+		marker := "[" + tag + "] "
+		if idx := strings.Index(line, marker); idx != -1 {
+			w.handleLine(line[:idx] + line[idx+len(marker):])
+			return
+		}
+	}
+	for _, w := range m.writers {
+		w.handleLine(line)
+	}
+}
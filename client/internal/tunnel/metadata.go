@@ -0,0 +1,38 @@
+package tunnel
+
+import "runtime"
+
+// Metadata keys sent to the frps server plugin with every login. They are
+// hints for logging and quotas only; access_key is the sole credential and
+// always wins over anything else in the map.
+const (
+	MetaAccessKey     = "access_key"
+	MetaClientVersion = "client_version"
+	MetaOS            = "os"
+	MetaArch          = "arch"
+	MetaLabel         = "label" // user-chosen session label (--label)
+)
+
+// ClientMetadata builds the metadata hints for a tunnel: client version,
+// OS and architecture are always included, label when non-empty. Entries in
+// overrides replace or add fields; an empty override value removes the
+// field. access_key is not part of the result (see buildCommonConfig).
+func ClientMetadata(version, label string, overrides map[string]string) map[string]string {
+	meta := map[string]string{
+		MetaClientVersion: version,
+		MetaOS:            runtime.GOOS,
+		MetaArch:          runtime.GOARCH,
+	}
+	if label != "" {
+		meta[MetaLabel] = label
+	}
+	for k, v := range overrides {
+		if v == "" {
+			delete(meta, k)
+		} else {
+			meta[k] = v
+		}
+	}
+	delete(meta, MetaAccessKey)
+	return meta
+}
@@ -0,0 +1,38 @@
+package tunnel
+
+import (
+	"net"
+	"sort"
+	"strings"
+)
+
+// NetworkFingerprint summarises the machine's network configuration: the
+// addresses of all up, non-loopback interfaces plus the source address of
+// the default route. It changes when Wi-Fi is swapped for Ethernet, a VPN
+// comes up or goes down, or DHCP hands out a new address, so comparing
+// successive fingerprints detects a network change without netlink. It
+// returns "" if the interfaces can't be listed.
+func NetworkFingerprint() string {
+	ifaces, err := net.Interfaces()
+	if err != nil {
+		return ""
+	}
+	var parts []string
+	for _, iface := range ifaces {
+		if iface.Flags&net.FlagUp == 0 || iface.Flags&net.FlagLoopback != 0 {
+			continue
+		}
+		addrs, err := iface.Addrs()
+		if err != nil {
+			continue
+		}
+		for _, a := range addrs {
+			parts = append(parts, iface.Name+"="+a.String())
+		}
+	}
+	sort.Strings(parts)
+	if ip := defaultRouteIP(); ip != nil {
+		parts = append(parts, "route="+ip.String())
+	}
+	return strings.Join(parts, ",")
+}
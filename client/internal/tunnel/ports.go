@@ -0,0 +1,38 @@
+package tunnel
+
+import (
+	"fmt"
+	"runtime"
+)
+
+// privilegedPortMax is the highest port that needs elevated rights to
+// listen on (outside Windows).
+const privilegedPortMax = 1023
+
+// udpOnlyPorts lists well-known ports whose services speak UDP. Tunnels
+// only forward TCP, so pointing one at these ports is almost always a
+// mistake.
+var udpOnlyPorts = map[int]string{
+	53:    "DNS",
+	123:   "NTP",
+	2456:  "Valheim",
+	19132: "Minecraft 基岩版",
+	34197: "Factorio",
+}
+
+// Warnings returns advisory notes about implausible port choices, such as
+// a privileged local port or a port normally used by a UDP-only service.
+// They never stop the tunnel from starting.
+func (c TunnelConfig) Warnings() []string {
+	var warnings []string
+	if c.LocalPort > 0 && c.LocalPort <= privilegedPortMax && runtime.GOOS != "windows" {
+		warnings = append(warnings, fmt.Sprintf("本地端口 %d 是特权端口，本地服务可能需要管理员权限才能监听", c.LocalPort))
+	}
+	if name, ok := udpOnlyPorts[c.LocalPort]; ok {
+		warnings = append(warnings, fmt.Sprintf("本地端口 %d 通常用于 %s (UDP)，但隧道仅转发 TCP", c.LocalPort, name))
+	}
+	if c.RemotePort > 0 && c.RemotePort <= privilegedPortMax {
+		warnings = append(warnings, fmt.Sprintf("服务器分配的远程端口 %d 是特权端口，部分网络可能无法访问", c.RemotePort))
+	}
+	return warnings
+}
@@ -0,0 +1,49 @@
+package tunnel
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"time"
+)
+
+// Resolution of the frps address before frpc starts: frpc would otherwise
+// retry an unresolvable name forever with an opaque "connect to server
+// error". Temporary DNS failures get a few quick retries.
+const (
+	resolveAttempts   = 3
+	resolveTimeout    = 5 * time.Second
+	resolveRetryDelay = time.Second
+)
+
+// resolveServerAddr checks that host resolves. IP literals pass without a
+// lookup. It returns nil if ctx is cancelled, leaving the shutdown to the
+// caller.
+func resolveServerAddr(ctx context.Context, host string) error {
+	if net.ParseIP(host) != nil {
+		return nil
+	}
+	var err error
+	for attempt := 1; attempt <= resolveAttempts; attempt++ {
+		lookupCtx, cancel := context.WithTimeout(ctx, resolveTimeout)
+		_, err = net.DefaultResolver.LookupHost(lookupCtx, host)
+		cancel()
+		if err == nil || ctx.Err() != nil {
+			return nil
+		}
+		var dnsErr *net.DNSError
+		if errors.As(err, &dnsErr) && dnsErr.IsNotFound {
+			// The name doesn't exist: retrying won't help.
+			break
+		}
+		if attempt < resolveAttempts {
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-time.After(resolveRetryDelay):
+			}
+		}
+	}
+	return fmt.Errorf("resolve %s: %w", host, err)
+}
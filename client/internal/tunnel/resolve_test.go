@@ -0,0 +1,32 @@
+package tunnel
+
+import (
+	"context"
+	"testing"
+)
+
+func TestResolveServerAddr(t *testing.T) {
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	tests := []struct {
+		name    string
+		ctx     context.Context
+		host    string
+		wantErr bool
+	}{
+		{"ipv4 literal", context.Background(), "203.0.113.7", false},
+		{"ipv6 literal", context.Background(), "2001:db8::1", false},
+		{"localhost", context.Background(), "localhost", false},
+		{"unresolvable", context.Background(), "frps.firefrp.invalid", true},
+		{"cancelled", cancelled, "frps.firefrp.invalid", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := resolveServerAddr(tt.ctx, tt.host)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("resolveServerAddr(%q) = %v, wantErr %v", tt.host, err, tt.wantErr)
+			}
+		})
+	}
+}
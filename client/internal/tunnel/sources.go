@@ -0,0 +1,127 @@
+package tunnel
+
+import (
+	"bufio"
+	"errors"
+	"net"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// maxSources bounds how many distinct source IPs ConnSources remembers;
+// the least recently seen is forgotten first.
+const maxSources = 32
+
+// proxyHeaderTimeout bounds the wait for frpc's PROXY header on a work
+// connection. frpc writes it before any payload, so it arrives at once if
+// frps reported a source address at all.
+const proxyHeaderTimeout = time.Second
+
+// proxyHeaderMax is the longest PROXY protocol v1 line (107 bytes per spec).
+const proxyHeaderMax = 107
+
+// errProxyHeaderTooLong is returned by readProxyHeader for a PROXY line
+// with no line end within proxyHeaderMax bytes.
+var errProxyHeaderTooLong = errors.New("PROXY protocol header too long")
+
+// SourceStat summarises the connections seen from one remote IP.
+type SourceStat struct {
+	IP    string
+	Count int
+	Last  time.Time
+}
+
+// ConnSources records the remote IPs of connections made through a tunnel.
+// frpc only learns a visitor's address from frps, and passes it on with the
+// PROXY protocol; the traffic relay reads and strips that header. A value
+// may be shared across tunnel restarts, like TrafficCounter.
+type ConnSources struct {
+	mu    sync.Mutex
+	stats map[string]*SourceStat
+}
+
+// Record counts a connection from ip.
+func (s *ConnSources) Record(ip string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.stats == nil {
+		s.stats = make(map[string]*SourceStat)
+	}
+	st, ok := s.stats[ip]
+	if !ok {
+		if len(s.stats) >= maxSources {
+			s.evictOldest()
+		}
+		st = &SourceStat{IP: ip}
+		s.stats[ip] = st
+	}
+	st.Count++
+	st.Last = time.Now()
+}
+
+// evictOldest forgets the least recently seen IP. s.mu must be held.
+func (s *ConnSources) evictOldest() {
+	var oldest *SourceStat
+	for _, st := range s.stats {
+		if oldest == nil || st.Last.Before(oldest.Last) {
+			oldest = st
+		}
+	}
+	if oldest != nil {
+		delete(s.stats, oldest.IP)
+	}
+}
+
+// Recent returns the recorded sources, most recently seen first.
+func (s *ConnSources) Recent() []SourceStat {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := make([]SourceStat, 0, len(s.stats))
+	for _, st := range s.stats {
+		out = append(out, *st)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Last.After(out[j].Last) })
+	return out
+}
+
+// readProxyHeader consumes a PROXY protocol v1 header from br, if conn
+// starts with one, and returns the source IP it names. Without a header
+// (frps sent no source address) nothing is consumed and ip is empty. A
+// header longer than the spec allows is an error, and the connection
+// should be dropped.
+func readProxyHeader(conn net.Conn, br *bufio.Reader) (ip string, err error) {
+	conn.SetReadDeadline(time.Now().Add(proxyHeaderTimeout))
+	defer conn.SetReadDeadline(time.Time{})
+
+	prefix, err := br.Peek(6)
+	if err != nil || string(prefix) != "PROXY " {
+		// Server-first protocols send nothing until greeted; that's fine.
+		var netErr net.Error
+		if errors.As(err, &netErr) && netErr.Timeout() {
+			err = nil
+		}
+		return "", err
+	}
+	var line []byte
+	for {
+		b, err := br.ReadByte()
+		if err != nil {
+			return "", err
+		}
+		line = append(line, b)
+		if b == '\n' {
+			break
+		}
+		if len(line) >= proxyHeaderMax {
+			return "", errProxyHeaderTooLong
+		}
+	}
+	// "PROXY TCP4 <src> <dst> <srcport> <dstport>\r\n" or "PROXY UNKNOWN ...".
+	fields := strings.Fields(string(line))
+	if len(fields) >= 3 && (fields[1] == "TCP4" || fields[1] == "TCP6") {
+		return fields[2], nil
+	}
+	return "", nil
+}
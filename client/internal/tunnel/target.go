@@ -0,0 +1,34 @@
+package tunnel
+
+import "sync/atomic"
+
+// LocalTarget is the local address a tunnel forwards to, changeable while
+// the tunnel runs. The traffic relay dials it afresh for every connection,
+// so after Set new visitors reach the new address without frpc
+// reconnecting and the remote port stays the same; connections already
+// open keep their old target. A value may be shared across tunnel
+// restarts, like TrafficCounter: the first tunnel to use it sets it from
+// its LocalIP and LocalPort, and later ones follow it.
+type LocalTarget struct {
+	addr atomic.Pointer[string]
+}
+
+// Set points the target at ip:port.
+func (t *LocalTarget) Set(ip string, port int) {
+	addr := JoinHostPort(ip, port)
+	t.addr.Store(&addr)
+}
+
+// Addr returns the current "host:port", or "" before the first Set.
+func (t *LocalTarget) Addr() string {
+	if p := t.addr.Load(); p != nil {
+		return *p
+	}
+	return ""
+}
+
+// LocalReachable reports whether something accepts TCP connections on
+// ip:port, waiting at most about a second.
+func LocalReachable(ip string, port int) bool {
+	return localReachable(ip, port)
+}
@@ -0,0 +1,156 @@
+package tunnel
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"net"
+	"strconv"
+	"sync/atomic"
+	"time"
+)
+
+// ErrIdleTimeout is set as the Error of the final StatusClosed update when
+// the tunnel shut itself down because no traffic flowed for IdleTimeout.
+var ErrIdleTimeout = errors.New("idle timeout")
+
+// idleCheckInterval is how often the idle watcher samples the byte counter.
+const idleCheckInterval = time.Second
+
+// TrafficCounter accumulates the bytes a tunnel forwards in both
+// directions. A counter may be shared across tunnel restarts to keep
+// session-wide totals.
+type TrafficCounter struct {
+	n atomic.Int64
+}
+
+// Bytes returns the total bytes counted so far.
+func (c *TrafficCounter) Bytes() int64 {
+	return c.n.Load()
+}
+
+// trafficRelay sits between frpc and the local service and counts the
+// bytes it forwards in both directions. frpc exposes no traffic counters
+// of its own, so the proxy is pointed at the relay instead. With sources
+// set, it also strips the PROXY header frpc then sends and records the
+// visitor's IP.
+type trafficRelay struct {
+	ln      net.Listener
+	target  *LocalTarget
+	counter *TrafficCounter
+	sources *ConnSources
+}
+
+// newTrafficRelay listens on an ephemeral loopback port and forwards each
+// accepted connection to target's current address, counting into counter and, if sources is
+// non-nil, recording source IPs into it.
+func newTrafficRelay(target *LocalTarget, counter *TrafficCounter, sources *ConnSources) (*trafficRelay, error) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+	return &trafficRelay{ln: ln, target: target, counter: counter, sources: sources}, nil
+}
+
+// addr returns the host and port frpc should forward to.
+func (r *trafficRelay) addr() (string, int) {
+	a := r.ln.Addr().(*net.TCPAddr)
+	return a.IP.String(), a.Port
+}
+
+// Bytes returns the total bytes counted so far.
+func (r *trafficRelay) Bytes() int64 {
+	return r.counter.Bytes()
+}
+
+// serve accepts connections until ctx is cancelled.
+func (r *trafficRelay) serve(ctx context.Context) {
+	go func() {
+		<-ctx.Done()
+		r.ln.Close()
+	}()
+	for {
+		conn, err := r.ln.Accept()
+		if err != nil {
+			return
+		}
+		go r.handle(conn)
+	}
+}
+
+// handle pipes one frpc work connection to the local service.
+func (r *trafficRelay) handle(conn net.Conn) {
+	defer conn.Close()
+	var in io.Reader = conn
+	if r.sources != nil {
+		br := bufio.NewReader(conn)
+		ip, err := readProxyHeader(conn, br)
+		if err != nil {
+			return
+		}
+		if ip != "" {
+			r.sources.Record(ip)
+		}
+		in = br
+	}
+	local, err := net.Dial("tcp", r.target.Addr())
+	if err != nil {
+		return
+	}
+	defer local.Close()
+
+	// Return once either direction ends; the deferred closes stop the other.
+	done := make(chan struct{}, 2)
+	go func() {
+		io.Copy(countingWriter{local, &r.counter.n}, in)
+		done <- struct{}{}
+	}()
+	go func() {
+		io.Copy(countingWriter{conn, &r.counter.n}, local)
+		done <- struct{}{}
+	}()
+	<-done
+}
+
+// countingWriter adds the number of bytes written to n.
+type countingWriter struct {
+	w io.Writer
+	n *atomic.Int64
+}
+
+func (c countingWriter) Write(p []byte) (int, error) {
+	written, err := c.w.Write(p)
+	c.n.Add(int64(written))
+	return written, err
+}
+
+// watchIdle calls onIdle once the relay's byte count has not changed for
+// timeout. It returns early if ctx is cancelled.
+func watchIdle(ctx context.Context, r *trafficRelay, timeout time.Duration, onIdle func()) {
+	ticker := time.NewTicker(idleCheckInterval)
+	defer ticker.Stop()
+
+	last := r.Bytes()
+	lastActive := time.Now()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			if b := r.Bytes(); b != last {
+				last = b
+				lastActive = now
+			} else if now.Sub(lastActive) >= timeout {
+				onIdle()
+				return
+			}
+		}
+	}
+}
+
+// JoinHostPort formats host and port as "host:port" for net.Dial and for
+// display, bracketing IPv6 literals ("[::1]:25565").
+func JoinHostPort(host string, port int) string {
+	return net.JoinHostPort(host, strconv.Itoa(port))
+}
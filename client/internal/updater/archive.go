@@ -0,0 +1,174 @@
+package updater
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// maxBinarySize bounds how much is extracted from an archive, so a corrupt
+// or hostile asset can't fill the disk.
+const maxBinarySize = 512 << 20
+
+// Leading bytes identifying the archive formats forks ship binaries in.
+var (
+	gzipMagic = []byte{0x1f, 0x8b}
+	zipMagic  = []byte("PK\x03\x04")
+)
+
+// unpackArchive replaces the downloaded file at filePath with the binary
+// it contains when the download is a .tar.gz or .zip archive, as detected
+// from its leading bytes. Anything else is taken to be the binary itself
+// and left alone. The archive must hold exactly one executable.
+func unpackArchive(filePath string) error {
+	f, err := os.Open(filePath)
+	if err != nil {
+		return fmt.Errorf("failed to open download: %w", err)
+	}
+	defer f.Close()
+
+	head := make([]byte, len(zipMagic))
+	n, _ := io.ReadFull(f, head)
+	head = head[:n]
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return fmt.Errorf("failed to read download: %w", err)
+	}
+
+	var bin io.ReadCloser
+	switch {
+	case bytes.HasPrefix(head, gzipMagic):
+		bin, err = binaryFromTarGz(f)
+	case bytes.HasPrefix(head, zipMagic):
+		info, statErr := f.Stat()
+		if statErr != nil {
+			return fmt.Errorf("failed to read download: %w", statErr)
+		}
+		bin, err = binaryFromZip(f, info.Size())
+	default:
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	out, err := os.CreateTemp(filepath.Dir(filePath), "firefrp-extract-*")
+	if err != nil {
+		return fmt.Errorf("failed to create temp file: %w", err)
+	}
+	outPath := out.Name()
+	written, err := io.Copy(out, io.LimitReader(bin, maxBinarySize+1))
+	bin.Close()
+	if err == nil && written > maxBinarySize {
+		err = fmt.Errorf("binary in archive exceeds %d MB", maxBinarySize>>20)
+	}
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(outPath)
+		return fmt.Errorf("failed to extract binary: %w", err)
+	}
+
+	// Windows can't rename over a file that is still open.
+	f.Close()
+	if err := os.Rename(outPath, filePath); err != nil {
+		os.Remove(outPath)
+		return fmt.Errorf("failed to replace download with extracted binary: %w", err)
+	}
+	return nil
+}
+
+// binaryFromTarGz returns the contents of the single executable in a
+// gzip-compressed tar archive.
+func binaryFromTarGz(r io.Reader) (io.ReadCloser, error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read gzip archive: %w", err)
+	}
+	// A tar stream can't be rewound, so buffer the match while checking
+	// that no second executable follows.
+	var found *bytes.Buffer
+	var names []string
+	tr := tar.NewReader(gz)
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read tar archive: %w", err)
+		}
+		if hdr.Typeflag != tar.TypeReg || !isExecutable(hdr.Name, hdr.FileInfo().Mode()) {
+			continue
+		}
+		names = append(names, hdr.Name)
+		if len(names) > 1 {
+			continue
+		}
+		found = &bytes.Buffer{}
+		n, err := io.Copy(found, io.LimitReader(tr, maxBinarySize+1))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s from archive: %w", hdr.Name, err)
+		}
+		if n > maxBinarySize {
+			return nil, fmt.Errorf("binary in archive exceeds %d MB", maxBinarySize>>20)
+		}
+	}
+	if err := checkSingle(names); err != nil {
+		return nil, err
+	}
+	return io.NopCloser(found), nil
+}
+
+// binaryFromZip returns a reader for the single executable in a zip archive.
+func binaryFromZip(r io.ReaderAt, size int64) (io.ReadCloser, error) {
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read zip archive: %w", err)
+	}
+	var match *zip.File
+	var names []string
+	for _, zf := range zr.File {
+		if !zf.Mode().IsRegular() || !isExecutable(zf.Name, zf.Mode()) {
+			continue
+		}
+		names = append(names, zf.Name)
+		match = zf
+	}
+	if err := checkSingle(names); err != nil {
+		return nil, err
+	}
+	rc, err := match.Open()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s from archive: %w", match.Name, err)
+	}
+	return rc, nil
+}
+
+// isExecutable reports whether an archive entry looks like a program: it
+// has an execute bit, or is a .exe (zip files made on Windows carry no
+// Unix permissions).
+func isExecutable(name string, mode fs.FileMode) bool {
+	return mode&0o111 != 0 || strings.EqualFold(path.Ext(name), ".exe")
+}
+
+// checkSingle ensures an archive held exactly one executable.
+func checkSingle(names []string) error {
+	switch len(names) {
+	case 1:
+		return nil
+	case 0:
+		return fmt.Errorf("no executable found in update archive")
+	default:
+		return fmt.Errorf("update archive contains %d executables (%s), expected one", len(names), strings.Join(names, ", "))
+	}
+}
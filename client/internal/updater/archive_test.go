@@ -0,0 +1,159 @@
+package updater
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// archiveEntry is one file to put in a test archive.
+type archiveEntry struct {
+	name string
+	mode fs.FileMode
+	body string
+}
+
+func makeTarGz(t *testing.T, entries []archiveEntry) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	for _, e := range entries {
+		hdr := &tar.Header{Name: e.name, Mode: int64(e.mode), Size: int64(len(e.body)), Typeflag: tar.TypeReg}
+		if err := tw.WriteHeader(hdr); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write([]byte(e.body)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func makeZip(t *testing.T, entries []archiveEntry) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, e := range entries {
+		hdr := &zip.FileHeader{Name: e.name, Method: zip.Deflate}
+		if e.mode != 0 {
+			hdr.SetMode(e.mode)
+		}
+		w, err := zw.CreateHeader(hdr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write([]byte(e.body)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestUnpackArchive(t *testing.T) {
+	binary := "\x7fELF fake firefrp binary"
+	readme := archiveEntry{"README.md", 0o644, "# FireFrp"}
+	tests := []struct {
+		name    string
+		data    func(t *testing.T) []byte
+		want    string // file contents afterwards
+		wantErr string
+	}{
+		{
+			name: "plain binary left alone",
+			data: func(t *testing.T) []byte { return []byte(binary) },
+			want: binary,
+		},
+		{
+			name: "tar.gz",
+			data: func(t *testing.T) []byte {
+				return makeTarGz(t, []archiveEntry{readme, {"firefrp/firefrp", 0o755, binary}})
+			},
+			want: binary,
+		},
+		{
+			name: "tar.gz without executable",
+			data: func(t *testing.T) []byte {
+				return makeTarGz(t, []archiveEntry{readme, {"firefrp", 0o644, binary}})
+			},
+			wantErr: "no executable",
+		},
+		{
+			name: "tar.gz with two executables",
+			data: func(t *testing.T) []byte {
+				return makeTarGz(t, []archiveEntry{{"firefrp", 0o755, binary}, {"install.sh", 0o755, "#!/bin/sh"}})
+			},
+			wantErr: "contains 2 executables",
+		},
+		{
+			name: "zip with unix mode",
+			data: func(t *testing.T) []byte {
+				return makeZip(t, []archiveEntry{readme, {"firefrp", 0o755, binary}})
+			},
+			want: binary,
+		},
+		{
+			name: "zip with windows exe",
+			data: func(t *testing.T) []byte {
+				return makeZip(t, []archiveEntry{{"LICENSE", 0, "MIT"}, {"firefrp.EXE", 0, binary}})
+			},
+			want: binary,
+		},
+		{
+			name: "zip with two executables",
+			data: func(t *testing.T) []byte {
+				return makeZip(t, []archiveEntry{{"firefrp.exe", 0, binary}, {"frpc.exe", 0, binary}})
+			},
+			wantErr: "contains 2 executables",
+		},
+		{
+			name:    "corrupt gzip",
+			data:    func(t *testing.T) []byte { return []byte{0x1f, 0x8b, 0, 1, 2} },
+			wantErr: "gzip",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "download")
+			if err := os.WriteFile(path, tt.data(t), 0o600); err != nil {
+				t.Fatal(err)
+			}
+			err := unpackArchive(path)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("unpackArchive() = %v, want error containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unpackArchive() = %v", err)
+			}
+			got, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("file contents = %q, want %q", got, tt.want)
+			}
+			leftovers, _ := filepath.Glob(filepath.Join(filepath.Dir(path), "firefrp-extract-*"))
+			if len(leftovers) > 0 {
+				t.Errorf("temp files left behind: %v", leftovers)
+			}
+		})
+	}
+}
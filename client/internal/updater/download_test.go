@@ -0,0 +1,273 @@
+package updater
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+// tempDownload returns an empty temp file standing in for a download.
+func tempDownload(t *testing.T) *os.File {
+	t.Helper()
+	f, err := os.Create(filepath.Join(t.TempDir(), "download"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { f.Close() })
+	return f
+}
+
+func TestDownloadAttemptStatus(t *testing.T) {
+	tests := []struct {
+		name         string
+		status       int
+		wantRetry    bool
+		wantNotFound bool
+	}{
+		{"not found", http.StatusNotFound, false, true},
+		{"gone", http.StatusGone, false, false},
+		{"forbidden", http.StatusForbidden, false, false},
+		{"bad gateway", http.StatusBadGateway, true, false},
+		{"unavailable", http.StatusServiceUnavailable, true, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+			}))
+			defer srv.Close()
+
+			retry, err := downloadAttempt(srv.Client(), srv.URL+"/firefrp-linux-amd64", tempDownload(t), 1, nil)
+			if err == nil {
+				t.Fatal("downloadAttempt() = nil, want an error")
+			}
+			if retry != tt.wantRetry {
+				t.Errorf("retry = %v, want %v", retry, tt.wantRetry)
+			}
+			if got := errors.Is(err, ErrReleaseNotFound); got != tt.wantNotFound {
+				t.Errorf("errors.Is(err, ErrReleaseNotFound) = %v, want %v (err %v)", got, tt.wantNotFound, err)
+			}
+		})
+	}
+}
+
+func TestDownloadNotFoundIsNotRetried(t *testing.T) {
+	requests := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		http.NotFound(w, r)
+	}))
+	defer srv.Close()
+
+	err := download(srv.Client(), srv.URL+"/firefrp-linux-amd64", tempDownload(t), nil)
+	if !errors.Is(err, ErrReleaseNotFound) {
+		t.Fatalf("download() = %v, want ErrReleaseNotFound", err)
+	}
+	if requests != 1 {
+		t.Errorf("server saw %d requests, want 1", requests)
+	}
+}
+
+func TestParseContentRange(t *testing.T) {
+	tests := []struct {
+		in        string
+		wantStart int64
+		wantTotal int64
+		wantOK    bool
+	}{
+		{"bytes 100-199/200", 100, 200, true},
+		{"bytes 0-99/*", 0, -1, true},
+		{"bytes 0-0/1", 0, 1, true},
+		{"", 0, -1, false},
+		{"bytes */200", 0, -1, false},
+		{"bytes 100-199", 0, -1, false},
+		{"items 0-9/10", 0, -1, false},
+		{"bytes x-9/10", 0, -1, false},
+		{"bytes 0-9/ten", 0, -1, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			start, total, ok := parseContentRange(tt.in)
+			if start != tt.wantStart || total != tt.wantTotal || ok != tt.wantOK {
+				t.Errorf("parseContentRange(%q) = %d, %d, %v; want %d, %d, %v",
+					tt.in, start, total, ok, tt.wantStart, tt.wantTotal, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestDownloadAttemptResume(t *testing.T) {
+	const asset = "0123456789abcdefghij"
+	tests := []struct {
+		name       string
+		have       string // already on disk
+		respond    func(w http.ResponseWriter)
+		wantRetry  bool
+		wantErr    bool
+		wantOnDisk string
+	}{
+		{
+			name: "range honoured",
+			have: asset[:8],
+			respond: func(w http.ResponseWriter) {
+				w.Header().Set("Content-Range", "bytes 8-19/20")
+				w.WriteHeader(http.StatusPartialContent)
+				w.Write([]byte(asset[8:]))
+			},
+			wantOnDisk: asset,
+		},
+		{
+			name: "range ignored",
+			have: asset[:8],
+			respond: func(w http.ResponseWriter) {
+				w.Write([]byte(asset))
+			},
+			wantOnDisk: asset,
+		},
+		{
+			name: "206 from zero on a fresh download",
+			respond: func(w http.ResponseWriter) {
+				w.Header().Set("Content-Range", "bytes 0-19/20")
+				w.WriteHeader(http.StatusPartialContent)
+				w.Write([]byte(asset))
+			},
+			wantOnDisk: asset,
+		},
+		{
+			name: "206 from zero when resuming",
+			have: asset[:8],
+			respond: func(w http.ResponseWriter) {
+				w.Header().Set("Content-Range", "bytes 0-19/20")
+				w.WriteHeader(http.StatusPartialContent)
+				w.Write([]byte(asset))
+			},
+			wantOnDisk: asset,
+		},
+		{
+			name: "206 at another offset",
+			have: asset[:8],
+			respond: func(w http.ResponseWriter) {
+				w.Header().Set("Content-Range", "bytes 4-19/20")
+				w.WriteHeader(http.StatusPartialContent)
+				w.Write([]byte(asset[4:]))
+			},
+			wantRetry: true, wantErr: true,
+			wantOnDisk: "",
+		},
+		{
+			name: "206 without Content-Range",
+			have: asset[:8],
+			respond: func(w http.ResponseWriter) {
+				w.WriteHeader(http.StatusPartialContent)
+				w.Write([]byte(asset[8:]))
+			},
+			wantRetry: true, wantErr: true,
+			wantOnDisk: "",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/octet-stream")
+				tt.respond(w)
+			}))
+			defer srv.Close()
+
+			f := tempDownload(t)
+			if _, err := f.WriteString(tt.have); err != nil {
+				t.Fatal(err)
+			}
+			retry, err := downloadAttempt(srv.Client(), srv.URL, f, 2, nil)
+			if (err != nil) != tt.wantErr || retry != tt.wantRetry {
+				t.Fatalf("downloadAttempt() = %v, %v; want retry %v, error %v", retry, err, tt.wantRetry, tt.wantErr)
+			}
+			got, err := os.ReadFile(f.Name())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tt.wantOnDisk {
+				t.Errorf("file contents = %q, want %q", got, tt.wantOnDisk)
+			}
+		})
+	}
+}
+
+func TestDownloadResumesAfterDrop(t *testing.T) {
+	defer func(d time.Duration) { downloadRetryDelay = d }(downloadRetryDelay)
+	downloadRetryDelay = time.Millisecond
+
+	asset := bytes.Repeat([]byte("0123456789abcdef"), 4096)
+	const dropAt = 20000
+
+	var (
+		mu     sync.Mutex
+		ranges []string
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		ranges = append(ranges, r.Header.Get("Range"))
+		n := len(ranges)
+		mu.Unlock()
+
+		w.Header().Set("Content-Type", "application/octet-stream")
+		switch n {
+		case 1:
+			// Send the first dropAt bytes of the full asset, then cut the
+			// connection.
+			w.Header().Set("Content-Length", strconv.Itoa(len(asset)))
+			w.Write(asset[:dropAt])
+			w.(http.Flusher).Flush()
+			conn, _, err := w.(http.Hijacker).Hijack()
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			conn.Close()
+		case 2:
+			// Answer the resume with a range that doesn't line up.
+			w.Header().Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", dropAt-100, len(asset)-1, len(asset)))
+			w.WriteHeader(http.StatusPartialContent)
+			w.Write(asset[dropAt-100:])
+		default:
+			http.ServeContent(w, r, "firefrp", time.Time{}, bytes.NewReader(asset))
+		}
+	}))
+	defer srv.Close()
+
+	var attempts []int
+	f := tempDownload(t)
+	err := download(srv.Client(), srv.URL+"/firefrp-linux-amd64", f, func(p Progress) {
+		if p.Err != nil {
+			attempts = append(attempts, p.Attempt)
+		}
+	})
+	if err != nil {
+		t.Fatalf("download() = %v", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	wantRanges := []string{"", fmt.Sprintf("bytes=%d-", dropAt), ""}
+	if !slices.Equal(ranges, wantRanges) {
+		t.Errorf("Range headers = %q, want %q", ranges, wantRanges)
+	}
+	if !slices.Equal(attempts, []int{2, 3}) {
+		t.Errorf("retries announced for attempts %v, want [2 3]", attempts)
+	}
+	got, err := os.ReadFile(f.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, asset) {
+		t.Errorf("downloaded %d bytes that differ from the %d-byte asset", len(got), len(asset))
+	}
+}
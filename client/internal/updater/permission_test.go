@@ -0,0 +1,110 @@
+package updater
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"syscall"
+	"testing"
+)
+
+func TestIsPermission(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"fs.ErrPermission", fs.ErrPermission, true},
+		{"EACCES", &fs.PathError{Op: "open", Path: "/usr/bin/firefrp", Err: syscall.EACCES}, true},
+		{"EPERM", &os.LinkError{Op: "rename", Old: "a", New: "b", Err: syscall.EPERM}, true},
+		{"EROFS", &fs.PathError{Op: "open", Path: "/usr/bin/firefrp", Err: syscall.EROFS}, true},
+		{"wrapped", fmt.Errorf("replace executable: %w", &fs.PathError{Op: "open", Err: syscall.EACCES}), true},
+		{"not exist", &fs.PathError{Op: "open", Err: syscall.ENOENT}, false},
+		{"other", errors.New("disk on fire"), false},
+		{"nil", nil, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isPermission(tt.err); got != tt.want {
+				t.Errorf("isPermission(%v) = %v, want %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPermissionError(t *testing.T) {
+	cause := &fs.PathError{Op: "open", Path: "/usr/bin/firefrp", Err: syscall.EACCES}
+	err := fmt.Errorf("update failed: %w", &PermissionError{Path: "/usr/bin/firefrp", Err: cause})
+
+	if !errors.Is(err, ErrPermission) {
+		t.Error("errors.Is(err, ErrPermission) = false, want true")
+	}
+	if !errors.Is(err, fs.ErrPermission) {
+		t.Error("errors.Is(err, fs.ErrPermission) = false, want true via Unwrap")
+	}
+	var perr *PermissionError
+	if !errors.As(err, &perr) || perr.Path != "/usr/bin/firefrp" {
+		t.Errorf("errors.As(err, *PermissionError) = %v, want Path /usr/bin/firefrp", perr)
+	}
+	if errors.Is(errors.New("other"), ErrPermission) {
+		t.Error("an unrelated error matched ErrPermission")
+	}
+}
+
+func TestReadOnlyInstallDir(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("directory permission bits don't apply on Windows")
+	}
+	if os.Geteuid() == 0 {
+		t.Skip("root can write to read-only directories")
+	}
+
+	base := t.TempDir()
+	dir := filepath.Join(base, "bin")
+	if err := os.Mkdir(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	exePath := filepath.Join(dir, "firefrp")
+	if err := os.WriteFile(exePath, []byte("old binary"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chmod(dir, 0o555); err != nil {
+		t.Fatal(err)
+	}
+	// Let t.TempDir clean up.
+	t.Cleanup(func() { os.Chmod(dir, 0o755) })
+
+	checkPermission := func(t *testing.T, err error) {
+		t.Helper()
+		var perr *PermissionError
+		if !errors.As(err, &perr) {
+			t.Fatalf("err = %v (%T), want a *PermissionError", err, err)
+		}
+		if !errors.Is(err, ErrPermission) {
+			t.Error("errors.Is(err, ErrPermission) = false, want true")
+		}
+		got, readErr := os.ReadFile(exePath)
+		if readErr != nil || string(got) != "old binary" {
+			t.Errorf("original binary = %q, %v; want it untouched", got, readErr)
+		}
+	}
+
+	t.Run("create temp file", func(t *testing.T) {
+		f, err := createUpdateFile(dir)
+		if f != nil {
+			f.Close()
+		}
+		checkPermission(t, err)
+	})
+
+	t.Run("swap", func(t *testing.T) {
+		tmpPath := filepath.Join(base, "firefrp-update-1")
+		if err := os.WriteFile(tmpPath, []byte("new binary"), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		checkPermission(t, replaceExecutable(tmpPath, exePath))
+	})
+}
@@ -0,0 +1,52 @@
+package updater
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestCheckAPIResponse(t *testing.T) {
+	tests := []struct {
+		name        string
+		status      int
+		remaining   string
+		reset       string
+		wantErr     bool
+		wantLimited bool
+		wantReset   time.Time
+	}{
+		{"ok", http.StatusOK, "", "", false, false, time.Time{}},
+		{"ok with no requests left", http.StatusOK, "0", "1792152000", false, false, time.Time{}},
+		{"403 rate limited", http.StatusForbidden, "0", "1792152000", true, true, time.Unix(1792152000, 0)},
+		{"429 rate limited", http.StatusTooManyRequests, "0", "1792152000", true, true, time.Unix(1792152000, 0)},
+		{"rate limited without reset", http.StatusForbidden, "0", "", true, true, time.Time{}},
+		{"rate limited with bad reset", http.StatusForbidden, "0", "soon", true, true, time.Time{}},
+		{"403 with requests left", http.StatusForbidden, "42", "1792152000", true, false, time.Time{}},
+		{"403 without headers", http.StatusForbidden, "", "", true, false, time.Time{}},
+		{"server error", http.StatusBadGateway, "0", "", true, false, time.Time{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := &http.Response{StatusCode: tt.status, Header: http.Header{}}
+			if tt.remaining != "" {
+				resp.Header.Set("X-RateLimit-Remaining", tt.remaining)
+			}
+			if tt.reset != "" {
+				resp.Header.Set("X-RateLimit-Reset", tt.reset)
+			}
+			err := checkAPIResponse(resp)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("checkAPIResponse() = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got := errors.Is(err, ErrRateLimited); got != tt.wantLimited {
+				t.Fatalf("errors.Is(err, ErrRateLimited) = %v, want %v (err %v)", got, tt.wantLimited, err)
+			}
+			var rl *RateLimitedError
+			if errors.As(err, &rl) && !rl.Reset.Equal(tt.wantReset) {
+				t.Errorf("Reset = %v, want %v", rl.Reset, tt.wantReset)
+			}
+		})
+	}
+}
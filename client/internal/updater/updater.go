@@ -5,15 +5,20 @@ package updater
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"io/fs"
 	"net/http"
 	"os"
 	"path/filepath"
 	"runtime"
 	"strconv"
 	"strings"
+	"syscall"
 	"time"
+
+	"github.com/AerNos/firefrp-client/internal/httputil"
 )
 
 const githubRepo = "lieyanc/FireFrp"
@@ -44,6 +49,31 @@ func IsDevVersion(v string) bool {
 	return strings.HasPrefix(v, "dev-")
 }
 
+// Update channels a client can follow.
+const (
+	ChannelDev    = "dev"
+	ChannelStable = "stable"
+)
+
+// ResolveChannel returns the update channel (ChannelDev or ChannelStable)
+// in effect for a server's channel setting ("dev", "stable", "auto" or
+// empty). Auto follows the server's client version when known, else
+// whether the current build is a dev build.
+func ResolveChannel(channel, serverVersion, currentVersion string) string {
+	switch channel {
+	case ChannelDev, ChannelStable:
+		return channel
+	}
+	v := serverVersion
+	if v == "" || v == "unknown" {
+		v = currentVersion
+	}
+	if IsDevVersion(v) {
+		return ChannelDev
+	}
+	return ChannelStable
+}
+
 // parseDevBuildNumber extracts the build number from a dev version string.
 // e.g. "dev-10-20260220-715650a" → 10. Returns -1 if parsing fails.
 func parseDevBuildNumber(v string) int {
@@ -62,15 +92,29 @@ func parseDevBuildNumber(v string) int {
 	return n
 }
 
-// assetName returns the expected binary asset name for the current platform.
-func assetName() string {
-	os := runtime.GOOS
-	arch := runtime.GOARCH
-	name := fmt.Sprintf("firefrp-%s-%s", os, arch)
-	if os == "windows" {
-		name += ".exe"
+// DefaultAssetTemplate is the release asset naming scheme of the official
+// builds, e.g. "firefrp-linux-amd64" or "firefrp-windows-amd64.exe".
+const DefaultAssetTemplate = "firefrp-{os}-{arch}{ext}"
+
+// assetName expands an asset name template for the current platform and
+// the given release tag. Placeholders: {os}, {arch}, {tag}, {version} (the
+// tag without a leading "v") and {ext} (".exe" on Windows, else empty).
+// An empty template means DefaultAssetTemplate.
+func assetName(template, tag string) string {
+	if template == "" {
+		template = DefaultAssetTemplate
+	}
+	ext := ""
+	if runtime.GOOS == "windows" {
+		ext = ".exe"
 	}
-	return name
+	return strings.NewReplacer(
+		"{os}", runtime.GOOS,
+		"{arch}", runtime.GOARCH,
+		"{tag}", tag,
+		"{version}", strings.TrimPrefix(tag, "v"),
+		"{ext}", ext,
+	).Replace(template)
 }
 
 // CheckUpdate compares the server-reported version with the current version
@@ -85,18 +129,7 @@ func CheckUpdate(serverVersion, currentVersion, channel string) (*UpdateInfo, er
 		return &UpdateInfo{Available: false}, nil
 	}
 
-	isDev := false
-	switch channel {
-	case "dev":
-		isDev = true
-	case "stable":
-		isDev = false
-	default:
-		// "auto" or empty: detect from server version string.
-		isDev = IsDevVersion(serverVersion)
-	}
-
-	if !isDev {
+	if ResolveChannel(channel, serverVersion, currentVersion) == ChannelStable {
 		// Release version: force sync with server version.
 		if serverVersion == currentVersion {
 			return &UpdateInfo{Available: false}, nil
@@ -143,23 +176,19 @@ func fetchLatestPrerelease() (*release, error) {
 	url := fmt.Sprintf("https://api.github.com/repos/%s/releases", githubRepo)
 	client := &http.Client{Timeout: 15 * time.Second}
 
-	req, err := http.NewRequest(http.MethodGet, url, nil)
+	req, err := newAPIRequest(url)
 	if err != nil {
 		return nil, err
 	}
-	req.Header.Set("Accept", "application/vnd.github.v3+json")
-	if token := os.Getenv("GITHUB_TOKEN"); token != "" {
-		req.Header.Set("Authorization", "token "+token)
-	}
 
-	resp, err := client.Do(req)
+	resp, err := httputil.DoWithRetry(client, req, httputil.DefaultPolicy)
 	if err != nil {
 		return nil, err
 	}
 	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("GitHub API returned HTTP %d", resp.StatusCode)
+	if err := checkAPIResponse(resp); err != nil {
+		return nil, err
 	}
 
 	var releases []release
@@ -176,10 +205,116 @@ func fetchLatestPrerelease() (*release, error) {
 	return nil, nil
 }
 
+// CheckReachable reports whether the GitHub releases API used for updates
+// can be reached within timeout.
+func CheckReachable(timeout time.Duration) error {
+	url := fmt.Sprintf("https://api.github.com/repos/%s/releases?per_page=1", githubRepo)
+	client := &http.Client{Timeout: timeout}
+
+	req, err := newAPIRequest(url)
+	if err != nil {
+		return err
+	}
+
+	resp, err := httputil.DoWithRetry(client, req, httputil.DefaultPolicy)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+
+	return checkAPIResponse(resp)
+}
+
+// ErrReleaseNotFound is returned by DoUpdate when the release asset does
+// not exist (HTTP 404), e.g. the server names a version that was never
+// published or has been yanked. Retrying cannot help.
+var ErrReleaseNotFound = errors.New("release asset not found")
+
+// ErrRateLimited matches any RateLimitedError via errors.Is.
+var ErrRateLimited = errors.New("GitHub API rate limited")
+
+// RateLimitedError is returned when GitHub refuses an API call because the
+// rate limit is used up (60 requests per hour without a token).
+type RateLimitedError struct {
+	// Reset is when GitHub restores the quota; zero if not reported.
+	Reset time.Time
+}
+
+func (e *RateLimitedError) Error() string {
+	if e.Reset.IsZero() {
+		return "GitHub 请求受限，请稍后重试"
+	}
+	return fmt.Sprintf("GitHub 请求受限，请稍后重试 (%s 后恢复)", e.Reset.Local().Format("15:04"))
+}
+
+// Is reports whether target is ErrRateLimited.
+func (e *RateLimitedError) Is(target error) bool {
+	return target == ErrRateLimited
+}
+
+// newAPIRequest builds a GET request to the GitHub API. Setting
+// $GITHUB_TOKEN authenticates it, which raises the rate limit.
+func newAPIRequest(url string) (*http.Request, error) {
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/vnd.github.v3+json")
+	if token := os.Getenv("GITHUB_TOKEN"); token != "" {
+		req.Header.Set("Authorization", "token "+token)
+	}
+	return req, nil
+}
+
+// checkAPIResponse turns a non-200 GitHub API response into an error,
+// telling rate limiting (403 or 429 with no requests remaining) apart from
+// other failures.
+func checkAPIResponse(resp *http.Response) error {
+	if resp.StatusCode == http.StatusOK {
+		return nil
+	}
+	if (resp.StatusCode == http.StatusForbidden || resp.StatusCode == http.StatusTooManyRequests) &&
+		resp.Header.Get("X-RateLimit-Remaining") == "0" {
+		e := &RateLimitedError{}
+		if secs, err := strconv.ParseInt(resp.Header.Get("X-RateLimit-Reset"), 10, 64); err == nil {
+			e.Reset = time.Unix(secs, 0)
+		}
+		return e
+	}
+	return fmt.Errorf("GitHub API returned HTTP %d", resp.StatusCode)
+}
+
+// Progress describes the state of an in-flight update download.
+type Progress struct {
+	Downloaded int64 // bytes written to the temp file so far
+	Total      int64 // expected total size in bytes, or -1 if unknown
+	Attempt    int   // 1-based attempt number
+	Err        error // non-nil when this notification announces a retry
+}
+
+// ProgressFunc receives download progress notifications. It is called from
+// the downloading goroutine and must not block.
+type ProgressFunc func(Progress)
+
+// MaxDownloadAttempts bounds how many times a dropped download is tried.
+const MaxDownloadAttempts = 3
+
+// downloadRetryDelay is the base backoff between download attempts.
+var downloadRetryDelay = 2 * time.Second
+
+// ReleasePageURL returns the GitHub page for the given release tag, where
+// users can download the binary by hand.
+func ReleasePageURL(tag string) string {
+	return fmt.Sprintf("https://github.com/%s/releases/tag/%s", githubRepo, tag)
+}
+
 // DoUpdate downloads the binary for the given release tag and replaces
-// the current executable.
-func DoUpdate(tag string) error {
-	name := assetName()
+// the current executable. The asset is named by assetTemplate (see
+// assetName; empty for the official scheme). Interrupted downloads are
+// resumed with HTTP range requests; onProgress (may be nil) is notified of
+// progress and retries.
+func DoUpdate(tag, assetTemplate string, onProgress ProgressFunc) error {
+	name := assetName(assetTemplate, tag)
 	downloadURL := fmt.Sprintf(
 		"https://github.com/%s/releases/download/%s/%s",
 		githubRepo, tag, name,
@@ -195,10 +330,9 @@ func DoUpdate(tag string) error {
 		return fmt.Errorf("failed to resolve executable path: %w", err)
 	}
 
-	dir := filepath.Dir(exePath)
-	tmpFile, err := os.CreateTemp(dir, "firefrp-update-*")
+	tmpFile, err := createUpdateFile(filepath.Dir(exePath))
 	if err != nil {
-		return fmt.Errorf("failed to create temp file: %w", err)
+		return err
 	}
 	tmpPath := tmpFile.Name()
 
@@ -209,32 +343,21 @@ func DoUpdate(tag string) error {
 		}
 	}()
 
-	client := &http.Client{Timeout: 120 * time.Second}
-	req, reqErr := http.NewRequest(http.MethodGet, downloadURL, nil)
-	if reqErr != nil {
-		tmpFile.Close()
-		return fmt.Errorf("failed to create download request: %w", reqErr)
-	}
-	if token := os.Getenv("GITHUB_TOKEN"); token != "" {
-		req.Header.Set("Authorization", "token "+token)
-	}
-	resp, err := client.Do(req)
-	if err != nil {
+	client := &http.Client{Timeout: 120 * time.Second, CheckRedirect: checkRedirect}
+	if err = download(client, downloadURL, tmpFile, onProgress); err != nil {
 		tmpFile.Close()
-		return fmt.Errorf("failed to download update: %w", err)
+		return err
 	}
-	defer resp.Body.Close()
+	tmpFile.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		tmpFile.Close()
-		return fmt.Errorf("download returned HTTP %d", resp.StatusCode)
+	// Forks may ship the binary inside an archive.
+	if err = unpackArchive(tmpPath); err != nil {
+		return err
 	}
-
-	if _, err = io.Copy(tmpFile, resp.Body); err != nil {
-		tmpFile.Close()
-		return fmt.Errorf("failed to write update: %w", err)
+	// Don't swap in an error page or a truncated file.
+	if err = verifyBinary(tmpPath); err != nil {
+		return err
 	}
-	tmpFile.Close()
 
 	// Make executable (non-Windows).
 	if runtime.GOOS != "windows" {
@@ -244,22 +367,275 @@ func DoUpdate(tag string) error {
 	}
 
 	// Replace the current binary.
+	if err = replaceExecutable(tmpPath, exePath); err != nil {
+		return err
+	}
+	return nil
+}
+
+// createUpdateFile creates the temp file the update is downloaded to in
+// dir, the executable's directory, so the final rename stays on one file
+// system.
+func createUpdateFile(dir string) (*os.File, error) {
+	f, err := os.CreateTemp(dir, "firefrp-update-*")
+	if err != nil {
+		if isPermission(err) {
+			return nil, &PermissionError{Path: dir, Err: err}
+		}
+		return nil, fmt.Errorf("failed to create temp file: %w", err)
+	}
+	return f, nil
+}
+
+// replaceExecutable swaps the downloaded binary at tmpPath in for exePath.
+// On failure the original executable is left in place.
+func replaceExecutable(tmpPath, exePath string) error {
 	if runtime.GOOS == "windows" {
 		// Windows can't overwrite a running exe; rename the old one first.
 		oldPath := exePath + ".old"
 		os.Remove(oldPath) // remove any previous .old file
-		if err = os.Rename(exePath, oldPath); err != nil {
+		if err := os.Rename(exePath, oldPath); err != nil {
+			if isPermission(err) {
+				return &PermissionError{Path: exePath, Err: err}
+			}
 			return fmt.Errorf("failed to rename old binary: %w", err)
 		}
+		if err := os.Rename(tmpPath, exePath); err != nil {
+			// Put the original back so the client still starts.
+			if restoreErr := os.Rename(oldPath, exePath); restoreErr != nil {
+				return fmt.Errorf("failed to replace binary: %w (restoring %s also failed: %v)", err, oldPath, restoreErr)
+			}
+			if isPermission(err) {
+				return &PermissionError{Path: exePath, Err: err}
+			}
+			return fmt.Errorf("failed to replace binary: %w", err)
+		}
+		return nil
 	}
 
-	if err = os.Rename(tmpPath, exePath); err != nil {
+	if err := os.Rename(tmpPath, exePath); err != nil {
+		if isPermission(err) {
+			return &PermissionError{Path: exePath, Err: err}
+		}
 		return fmt.Errorf("failed to replace binary: %w", err)
 	}
 
 	return nil
 }
 
+// ErrPermission matches any PermissionError via errors.Is.
+var ErrPermission = errors.New("no permission to replace the executable")
+
+// PermissionError is returned by DoUpdate when the executable can't be
+// replaced for lack of permission, typically because it is installed in a
+// system directory. Such installs should be updated by the package manager
+// or with administrator rights.
+type PermissionError struct {
+	Path string // the executable or its directory
+	Err  error
+}
+
+func (e *PermissionError) Error() string {
+	return fmt.Sprintf("无权限更新，请使用包管理器或以管理员身份运行 (%s)", e.Path)
+}
+
+// Unwrap returns the underlying file system error.
+func (e *PermissionError) Unwrap() error {
+	return e.Err
+}
+
+// Is reports whether target is ErrPermission.
+func (e *PermissionError) Is(target error) bool {
+	return target == ErrPermission
+}
+
+// isPermission reports whether err means the file system refused the
+// change: EACCES/EPERM, or a read-only file system.
+func isPermission(err error) bool {
+	return errors.Is(err, fs.ErrPermission) || errors.Is(err, syscall.EROFS)
+}
+
+// download fetches url into f, retrying transient failures. Each retry
+// resumes from the current size of f with a Range request; if the server
+// does not honour ranges, or answers a different one, the file is
+// truncated and downloaded in full.
+func download(client *http.Client, url string, f *os.File, onProgress ProgressFunc) error {
+	var lastErr error
+	for attempt := 1; attempt <= MaxDownloadAttempts; attempt++ {
+		if attempt > 1 {
+			offset, _ := f.Seek(0, io.SeekEnd)
+			if onProgress != nil {
+				onProgress(Progress{Downloaded: offset, Total: -1, Attempt: attempt, Err: lastErr})
+			}
+			time.Sleep(downloadRetryDelay * time.Duration(attempt-1))
+		}
+
+		retry, err := downloadAttempt(client, url, f, attempt, onProgress)
+		if err == nil {
+			return nil
+		}
+		if !retry {
+			return err
+		}
+		lastErr = err
+	}
+	return fmt.Errorf("download failed after %d attempts: %w", MaxDownloadAttempts, lastErr)
+}
+
+// downloadAttempt performs a single GET (ranged if f already holds data).
+// It reports whether a failure is transient and worth retrying.
+func downloadAttempt(client *http.Client, url string, f *os.File, attempt int, onProgress ProgressFunc) (retry bool, err error) {
+	offset, err := f.Seek(0, io.SeekEnd)
+	if err != nil {
+		return false, fmt.Errorf("failed to seek temp file: %w", err)
+	}
+
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return false, fmt.Errorf("failed to create download request: %w", err)
+	}
+	if token := os.Getenv("GITHUB_TOKEN"); token != "" {
+		req.Header.Set("Authorization", "token "+token)
+	}
+	if offset > 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+	}
+
+	// download retries and resumes on its own, so try once here.
+	resp, err := httputil.DoWithRetry(client, req, httputil.NoRetry)
+	if err != nil {
+		return !errors.Is(err, errTooManyRedirects), fmt.Errorf("failed to download update: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusOK || resp.StatusCode == http.StatusPartialContent {
+		if err := checkContentType(resp.Header.Get("Content-Type")); err != nil {
+			return false, err
+		}
+	}
+
+	total := int64(-1)
+	switch {
+	case resp.StatusCode == http.StatusPartialContent:
+		// Some servers answer 206 even to a plain GET; either way the
+		// range must start where our file ends, or the bytes won't line
+		// up. A range from zero replaces the file; any other mismatch
+		// starts over with a fresh request.
+		cr := resp.Header.Get("Content-Range")
+		start, size, ok := parseContentRange(cr)
+		if !ok || start != offset {
+			if err := resetFile(f); err != nil {
+				return false, err
+			}
+			if !ok || start != 0 {
+				return true, fmt.Errorf("download resumed at the wrong offset (Content-Range %q, have %d bytes)", cr, offset)
+			}
+			offset = 0
+		}
+		total = size
+	case resp.StatusCode == http.StatusOK:
+		// Fresh download, or the server ignored our Range header:
+		// discard whatever we had and start over.
+		if offset > 0 {
+			if err := resetFile(f); err != nil {
+				return false, err
+			}
+			offset = 0
+		}
+		if resp.ContentLength >= 0 {
+			total = resp.ContentLength
+		}
+	case resp.StatusCode == http.StatusRequestedRangeNotSatisfiable:
+		// Our partial file doesn't line up with the remote asset.
+		if err := resetFile(f); err != nil {
+			return false, err
+		}
+		return true, fmt.Errorf("download returned HTTP %d", resp.StatusCode)
+	case resp.StatusCode >= 500:
+		return true, fmt.Errorf("download returned HTTP %d", resp.StatusCode)
+	case resp.StatusCode == http.StatusNotFound:
+		return false, fmt.Errorf("download returned HTTP %d: %w", resp.StatusCode, ErrReleaseNotFound)
+	default:
+		return false, fmt.Errorf("download returned HTTP %d", resp.StatusCode)
+	}
+
+	pw := &progressWriter{
+		w:          f,
+		progress:   Progress{Downloaded: offset, Total: total, Attempt: attempt},
+		onProgress: onProgress,
+	}
+	if _, err := io.Copy(pw, resp.Body); err != nil {
+		if pw.writeErr != nil {
+			return false, fmt.Errorf("failed to write update: %w", pw.writeErr)
+		}
+		return true, fmt.Errorf("download interrupted: %w", err)
+	}
+	return false, nil
+}
+
+// progressWriter forwards writes to w and reports cumulative progress.
+// It records write-side errors separately so they aren't mistaken for
+// transient network failures.
+type progressWriter struct {
+	w          io.Writer
+	progress   Progress
+	onProgress ProgressFunc
+	writeErr   error
+}
+
+func (pw *progressWriter) Write(p []byte) (int, error) {
+	n, err := pw.w.Write(p)
+	pw.progress.Downloaded += int64(n)
+	if err != nil {
+		pw.writeErr = err
+		return n, err
+	}
+	if pw.onProgress != nil {
+		pw.onProgress(pw.progress)
+	}
+	return n, nil
+}
+
+// resetFile truncates f and rewinds it to the start.
+func resetFile(f *os.File) error {
+	if err := f.Truncate(0); err != nil {
+		return fmt.Errorf("failed to truncate temp file: %w", err)
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return fmt.Errorf("failed to seek temp file: %w", err)
+	}
+	return nil
+}
+
+// parseContentRange extracts the first byte position and the complete
+// length from a "bytes start-end/total" Content-Range header. total is -1
+// when the header gives it as "*"; ok is false if the header is malformed.
+func parseContentRange(h string) (start, total int64, ok bool) {
+	rng, ok := strings.CutPrefix(h, "bytes ")
+	if !ok {
+		return 0, -1, false
+	}
+	rng, size, ok := strings.Cut(rng, "/")
+	if !ok {
+		return 0, -1, false
+	}
+	first, _, ok := strings.Cut(rng, "-")
+	if !ok {
+		return 0, -1, false
+	}
+	start, err := strconv.ParseInt(first, 10, 64)
+	if err != nil || start < 0 {
+		return 0, -1, false
+	}
+	total = -1
+	if size != "*" {
+		if total, err = strconv.ParseInt(size, 10, 64); err != nil {
+			return 0, -1, false
+		}
+	}
+	return start, total, true
+}
+
 // Relaunch re-executes the current binary with the same arguments.
 // On Unix, this replaces the current process. On Windows, it starts a
 // new process and exits.
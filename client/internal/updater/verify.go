@@ -0,0 +1,100 @@
+package updater
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"runtime"
+	"strings"
+)
+
+// minBinarySize is the smallest file accepted as a client binary. Real
+// builds embed frp and are many megabytes; anything this small is an error
+// page or a truncated download.
+const minBinarySize = 1 << 20
+
+// maxRedirects bounds how many redirects a download follows (GitHub sends
+// one, to its CDN).
+const maxRedirects = 10
+
+// errTooManyRedirects is returned for redirect loops, which retrying won't fix.
+var errTooManyRedirects = errors.New("too many redirects")
+
+// checkRedirect stops downloads caught in a redirect loop.
+func checkRedirect(req *http.Request, via []*http.Request) error {
+	if len(via) >= maxRedirects {
+		return errTooManyRedirects
+	}
+	return nil
+}
+
+// checkContentType rejects responses that declare a web page or API error
+// body instead of a binary, such as a mirror's HTML error page served with
+// status 200. A missing or unparseable Content-Type is allowed.
+func checkContentType(header string) error {
+	if header == "" {
+		return nil
+	}
+	mediaType, _, err := mime.ParseMediaType(header)
+	if err != nil {
+		return nil
+	}
+	if strings.HasPrefix(mediaType, "text/") || mediaType == "application/json" {
+		return fmt.Errorf("download returned %s instead of a binary", mediaType)
+	}
+	return nil
+}
+
+// verifyBinary checks that the file at filePath plausibly is an executable
+// for this platform before it replaces the running one: large enough, and
+// starting with the platform's executable header.
+func verifyBinary(filePath string) error {
+	f, err := os.Open(filePath)
+	if err != nil {
+		return fmt.Errorf("failed to open download: %w", err)
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return fmt.Errorf("failed to read download: %w", err)
+	}
+	if info.Size() < minBinarySize {
+		return fmt.Errorf("downloaded file is too small to be a binary (%d bytes)", info.Size())
+	}
+
+	head := make([]byte, 512)
+	n, _ := io.ReadFull(f, head)
+	head = head[:n]
+	if !hasExecutableHeader(head) {
+		return fmt.Errorf("downloaded file is not a %s executable (looks like %s)",
+			runtime.GOOS, http.DetectContentType(head))
+	}
+	return nil
+}
+
+// hasExecutableHeader reports whether head starts like an executable for
+// the current OS: PE on Windows, Mach-O on macOS, ELF elsewhere.
+func hasExecutableHeader(head []byte) bool {
+	switch runtime.GOOS {
+	case "windows":
+		return bytes.HasPrefix(head, []byte("MZ"))
+	case "darwin":
+		for _, magic := range [][]byte{
+			{0xcf, 0xfa, 0xed, 0xfe}, // 64-bit
+			{0xce, 0xfa, 0xed, 0xfe}, // 32-bit
+			{0xca, 0xfe, 0xba, 0xbe}, // universal
+		} {
+			if bytes.HasPrefix(head, magic) {
+				return true
+			}
+		}
+		return false
+	default:
+		return bytes.HasPrefix(head, []byte("\x7fELF"))
+	}
+}
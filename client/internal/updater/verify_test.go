@@ -0,0 +1,116 @@
+package updater
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestCheckContentType(t *testing.T) {
+	tests := []struct {
+		header  string
+		wantErr bool
+	}{
+		{"", false},
+		{"application/octet-stream", false},
+		{"application/x-msdownload", false},
+		{"application/gzip", false},
+		{"not a media type;;", false},
+		{"text/html; charset=utf-8", true},
+		{"TEXT/PLAIN", true},
+		{"application/json", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.header, func(t *testing.T) {
+			if err := checkContentType(tt.header); (err != nil) != tt.wantErr {
+				t.Errorf("checkContentType(%q) error = %v, wantErr %v", tt.header, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// executableHeader returns the executable magic for the current OS.
+func executableHeader() []byte {
+	switch runtime.GOOS {
+	case "windows":
+		return []byte("MZ")
+	case "darwin":
+		return []byte{0xcf, 0xfa, 0xed, 0xfe}
+	default:
+		return []byte("\x7fELF")
+	}
+}
+
+func TestVerifyBinary(t *testing.T) {
+	pad := func(head []byte, size int) []byte {
+		return append(head, make([]byte, size-len(head))...)
+	}
+	tests := []struct {
+		name    string
+		data    []byte
+		wantErr string // empty: valid
+	}{
+		{"executable", pad(executableHeader(), minBinarySize), ""},
+		{"too small", pad(executableHeader(), minBinarySize-1), "too small"},
+		{"html page", pad([]byte("<!DOCTYPE html><html>"), minBinarySize), "not a " + runtime.GOOS + " executable"},
+		{"zeros", make([]byte, minBinarySize), "not a " + runtime.GOOS + " executable"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "firefrp")
+			if err := os.WriteFile(path, tt.data, 0o755); err != nil {
+				t.Fatal(err)
+			}
+			err := verifyBinary(path)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("verifyBinary() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("verifyBinary() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+
+	if err := verifyBinary(filepath.Join(t.TempDir(), "missing")); err == nil {
+		t.Error("verifyBinary() on a missing file succeeded")
+	}
+}
+
+func TestHasExecutableHeader(t *testing.T) {
+	if !hasExecutableHeader(executableHeader()) {
+		t.Errorf("hasExecutableHeader(%q) = false on %s", executableHeader(), runtime.GOOS)
+	}
+	for _, head := range [][]byte{nil, []byte("#!/bin/sh"), []byte("PK\x03\x04"), []byte{0x1f, 0x8b}} {
+		if hasExecutableHeader(head) {
+			t.Errorf("hasExecutableHeader(%q) = true", head)
+		}
+	}
+	if runtime.GOOS != "windows" && hasExecutableHeader([]byte("MZ")) {
+		t.Error("a PE header was accepted outside Windows")
+	}
+	if runtime.GOOS == "linux" && hasExecutableHeader([]byte{0xcf, 0xfa, 0xed, 0xfe}) {
+		t.Error("a Mach-O header was accepted on Linux")
+	}
+}
+
+func TestCheckRedirectLoop(t *testing.T) {
+	var srv *httptest.Server
+	srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, srv.URL+r.URL.Path+"x", http.StatusFound)
+	}))
+	defer srv.Close()
+
+	client := &http.Client{CheckRedirect: checkRedirect}
+	_, err := client.Get(srv.URL + "/a")
+	if !errors.Is(err, errTooManyRedirects) {
+		t.Fatalf("Get() error = %v, want %v", err, errTooManyRedirects)
+	}
+}
@@ -95,7 +95,7 @@ const KEY_PATTERN = /^[a-zA-Z0-9\-_]+$/;
  * Validate an access key and return frps connection parameters.
  *
  * Request body: { "key": "ff-xxxx..." }
- * Success response: { "ok": true, "data": { frps_addr, frps_port, remote_port, token, proxy_name, expires_at } }
+ * Success response: { "ok": true, "data": { frps_addr, frps_port, remote_port, token, proxy_name, expires_at, server_time } }
  * Error response: { "ok": false, "error": { "code": "KEY_NOT_FOUND", "message": "..." } }
  */
 router.post('/api/v1/validate', (req: Request, res: Response) => {
@@ -105,6 +105,10 @@ router.post('/api/v1/validate', (req: Request, res: Response) => {
     const rateLimited = checkRateLimit(clientIp);
     if (rateLimited) {
       log.warn({ ip: clientIp, window: rateLimited }, 'Rate limit exceeded on /api/v1/validate');
+      const bucket = (rateLimited === 'minute' ? rateLimitMinute : rateLimitHour).get(clientIp);
+      if (bucket) {
+        res.set('Retry-After', String(Math.max(1, Math.ceil((bucket.resetAt - Date.now()) / 1000))));
+      }
       res.status(429).json({
         ok: false,
         error: { code: 'RATE_LIMITED', message: 'Too many requests. Please try again later.' },
@@ -174,10 +178,12 @@ router.post('/api/v1/validate', (req: Request, res: Response) => {
           ? req.hostname  // Use the request hostname if frps binds to all interfaces
           : config.frps.bindAddr,
         frps_port: config.frps.bindPort,
+        public_addr: config.server.publicAddr,
         remote_port: ak.remotePort,
         token: config.frps.authToken,
         proxy_name: ak.proxyName,
         expires_at: ak.expiresAt,
+        server_time: new Date().toISOString(),
       },
     });
   } catch (err) {
@@ -203,6 +209,7 @@ router.get('/api/v1/server-info', (_req: Request, res: Response) => {
       description: config.server.description,
       client_version: getVersion(),
       update_channel: config.updates.channel,
+      frp_version: config.frpVersion,
     },
   });
 });